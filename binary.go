@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/format/gitattributes"
+)
+
+// binarySniffLen is how much of a file is searched for a NUL byte, as git does
+const binarySniffLen = 8000
+
+// BinaryInfo describes the two versions of a binary file, which is summarized
+// instead of diffed line by line
+type BinaryInfo struct {
+	Old, New BinaryBlob
+}
+
+// BinaryBlob is one version of a binary file
+type BinaryBlob struct {
+	Exists bool
+	Size   int
+	Hash   string // git blob hash of the content
+	MIME   string // media type guessed from the leading bytes
+	// Dimensions of a PNG, JPEG or GIF image, 0 for other files
+	Width, Height int
+	content       []byte       // the encoded image when it can be previewed
+	thumbnail     *image.NRGBA // the image scaled down for the inline preview
+}
+
+// isBinaryContent reports whether content looks binary: git treats a file as
+// binary when a NUL byte appears near its start
+func isBinaryContent(content []byte) bool {
+	return bytes.IndexByte(content[:min(len(content), binarySniffLen)], 0) >= 0
+}
+
+// newBinaryBlob describes one version of a binary file. It runs in the
+// commands that load diffs, so images are decoded and scaled down there
+// rather than while the diff is drawn.
+func newBinaryBlob(content []byte, exists bool) BinaryBlob {
+	if !exists {
+		return BinaryBlob{}
+	}
+	blob := BinaryBlob{
+		Exists: true,
+		Size:   len(content),
+		Hash:   plumbing.ComputeHash(plumbing.BlobObject, content).String(),
+		MIME:   guessMIMEType(content),
+	}
+	if previewableImageTypes[blob.MIME] {
+		// The header is read first, so a huge image is not decoded. A
+		// corrupt image is summarized like any other binary file.
+		if config, _, err := image.DecodeConfig(bytes.NewReader(content)); err == nil {
+			blob.Width, blob.Height = config.Width, config.Height
+			if config.Width*config.Height <= maxPreviewPixels {
+				if img, _, err := image.Decode(bytes.NewReader(content)); err == nil {
+					blob.content = content
+					blob.thumbnail = newThumbnail(img)
+				}
+			}
+		}
+	}
+	return blob
+}
+
+// isImage reports whether the blob is an image that can be previewed
+func (b BinaryBlob) isImage() bool {
+	return b.thumbnail != nil
+}
+
+// newBinaryFileDiff summarizes a binary file. It has no hunks or line counts.
+func newBinaryFileDiff(path string, changeType ChangeType, oldContent, newContent []byte, oldExists, newExists bool) *FileDiff {
+	return &FileDiff{
+		Path:       path,
+		ChangeType: changeType,
+		Binary: &BinaryInfo{
+			Old: newBinaryBlob(oldContent, oldExists),
+			New: newBinaryBlob(newContent, newExists),
+		},
+	}
+}
+
+// magicTypes are formats common in repositories that http.DetectContentType
+// does not know
+var magicTypes = []struct {
+	magic string
+	mime  string
+}{
+	{"\x7fELF", "application/x-elf"},
+	{"\xcf\xfa\xed\xfe", "application/x-mach-binary"},
+	{"\xca\xfe\xba\xbe", "application/java-vm"},
+	{"SQLite format 3\x00", "application/vnd.sqlite3"},
+	{"\x28\xb5\x2f\xfd", "application/zstd"},
+	{"\xfd7zXZ\x00", "application/x-xz"},
+	{"7z\xbc\xaf\x27\x1c", "application/x-7z-compressed"},
+	{"BZh", "application/x-bzip2"},
+}
+
+// guessMIMEType guesses the media type of content from its magic bytes
+func guessMIMEType(content []byte) string {
+	for _, magic := range magicTypes {
+		if bytes.HasPrefix(content, []byte(magic.magic)) {
+			return magic.mime
+		}
+	}
+	mime, _, _ := strings.Cut(http.DetectContentType(content), ";")
+	return mime
+}
+
+// formatByteSize formats a size in bytes with a binary unit: 512 B, 1.5 KiB
+func formatByteSize(size int) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	value, exp := float64(size)/unit, 0
+	for value >= unit && exp < 3 {
+		value /= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", value, "KMGT"[exp])
+}
+
+// binarySummaryLines describes both versions of a binary file, one line each
+func binarySummaryLines(info *BinaryInfo) []string {
+	lines := []string{"Binary file"}
+	for _, side := range []struct {
+		label string
+		blob  BinaryBlob
+	}{{"old", info.Old}, {"new", info.New}} {
+		if !side.blob.Exists {
+			lines = append(lines, fmt.Sprintf("  %s: none", side.label))
+			continue
+		}
+		mime := side.blob.MIME
+		if side.blob.Width > 0 {
+			mime += fmt.Sprintf(" %d×%d", side.blob.Width, side.blob.Height)
+		}
+		lines = append(lines, fmt.Sprintf("  %s: %s  %s  %s", side.label, formatByteSize(side.blob.Size), mime, shortHash(side.blob.Hash)))
+	}
+	if info.Old.Exists && info.New.Exists {
+		if delta := info.New.Size - info.Old.Size; delta != 0 {
+			sign := "+"
+			if delta < 0 {
+				sign, delta = "-", -delta
+			}
+			lines[0] += fmt.Sprintf(" (%s%s)", sign, formatByteSize(delta))
+		}
+	}
+	return lines
+}
+
+// shortHash abbreviates a hash the way git log --oneline does
+func shortHash(hash string) string {
+	return hash[:min(len(hash), 7)]
+}
+
+// binaryAttributes looks up the gitattributes that mark a file binary, "-diff"
+// or "binary", reading the .gitattributes of each directory once
+type binaryAttributes struct {
+	fs       billy.Filesystem
+	patterns map[string][]gitattributes.MatchAttribute
+}
+
+// newBinaryAttributes reads .gitattributes files from fs, the worktree. A nil
+// fs marks no file binary.
+func newBinaryAttributes(fs billy.Filesystem) *binaryAttributes {
+	return &binaryAttributes{fs: fs, patterns: make(map[string][]gitattributes.MatchAttribute)}
+}
+
+// worktreeBinaryAttributes reads the gitattributes of the worktree; a bare
+// repository has none
+func (gs *GitService) worktreeBinaryAttributes() *binaryAttributes {
+	worktree, err := gs.repo.Worktree()
+	if err != nil {
+		return newBinaryAttributes(nil)
+	}
+	return newBinaryAttributes(worktree.Filesystem)
+}
+
+// isBinary reports whether the attributes of path turn off its text diff
+func (a *binaryAttributes) isBinary(filePath string) bool {
+	if a == nil || a.fs == nil {
+		return false
+	}
+
+	parts := strings.Split(filePath, "/")
+	var stack []gitattributes.MatchAttribute
+	for depth := range parts {
+		// Cloned: the patterns keep their directory, and reading appends to it
+		stack = append(stack, a.dirPatterns(slices.Clone(parts[:depth]))...)
+	}
+
+	// Deeper files and later lines take precedence
+	for i := len(stack) - 1; i >= 0; i-- {
+		if stack[i].Pattern == nil || !stack[i].Pattern.Match(parts) {
+			continue
+		}
+		attrs := stack[i].Attributes
+		for j := len(attrs) - 1; j >= 0; j-- {
+			switch attrs[j].Name() {
+			case "diff":
+				return attrs[j].IsUnset()
+			case "binary":
+				if attrs[j].IsSet() {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// dirPatterns returns the patterns of the .gitattributes file in dir
+func (a *binaryAttributes) dirPatterns(dir []string) []gitattributes.MatchAttribute {
+	key := path.Join(dir...)
+	if patterns, ok := a.patterns[key]; ok {
+		return patterns
+	}
+	// A missing or malformed file contributes no patterns
+	patterns, _ := gitattributes.ReadAttributesFile(a.fs, dir, ".gitattributes", len(dir) == 0)
+	a.patterns[key] = patterns
+	return patterns
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+const pngHeader = "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"
+
+func TestBinaryDetection(t *testing.T) {
+	if isBinaryContent([]byte("plain text\n")) || isBinaryContent(nil) {
+		t.Error("text should not be binary")
+	}
+	if !isBinaryContent([]byte(pngHeader)) {
+		t.Error("a NUL byte should make content binary")
+	}
+	if isBinaryContent([]byte(strings.Repeat("x", binarySniffLen) + "\x00")) {
+		t.Error("a NUL byte past the sniffed prefix should be ignored, as in git")
+	}
+
+	for content, want := range map[string]string{
+		pngHeader:             "image/png",
+		"\x7fELF\x02\x01\x01": "application/x-elf",
+		"PK\x03\x04\x14\x00":  "application/zip",
+		"\x00\x01\x02\x03":    "application/octet-stream",
+	} {
+		if got := guessMIMEType([]byte(content)); got != want {
+			t.Errorf("guessMIMEType(%q) = %q, want %q", content, got, want)
+		}
+	}
+
+	for size, want := range map[int]string{0: "0 B", 1023: "1023 B", 1536: "1.5 KiB", 3 << 20: "3.0 MiB"} {
+		if got := formatByteSize(size); got != want {
+			t.Errorf("formatByteSize(%d) = %q, want %q", size, got, want)
+		}
+	}
+}
+
+func TestBinarySummaryLines(t *testing.T) {
+	file := newBinaryFileDiff("logo.png", Modified, []byte(pngHeader), []byte(pngHeader+strings.Repeat("\x00", 2048)), true, true)
+	got := binarySummaryLines(file.Binary)
+	want := []string{
+		"Binary file (+2.0 KiB)",
+		"  old: 16 B  image/png  " + file.Binary.Old.Hash[:7],
+		"  new: 2.0 KiB  image/png  " + file.Binary.New.Hash[:7],
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("summary = %q, want %q", got, want)
+	}
+
+	added := newBinaryFileDiff("logo.png", Added, nil, []byte(pngHeader), false, true)
+	if got := binarySummaryLines(added.Binary); got[0] != "Binary file" || got[1] != "  old: none" {
+		t.Errorf("added summary = %q", got)
+	}
+}
+
+func TestGetDiffSummarizesBinaryFiles(t *testing.T) {
+	gitService, root := setupTempGitService(t, map[string]string{
+		"logo.png":       pngHeader,
+		"data/dump.sql":  "insert 1;\n",
+		"data/notes.txt": "note\n",
+		".gitattributes": "*.sql -diff\n",
+	})
+	logger := newDefaultLogger(ERROR)
+	writeWorktreeFile(t, root, "logo.png", pngHeader+"more")
+	writeWorktreeFile(t, root, "data/dump.sql", "insert 2;\n")
+	writeWorktreeFile(t, root, "data/notes.txt", "notes\n")
+	// A deeper .gitattributes overrides the root one
+	writeWorktreeFile(t, root, "data/.gitattributes", "notes.txt binary\n")
+
+	files, err := gitService.GetDiffWithContext(Unstaged, DiffOnly, DiffOptions{Context: 3}, Pathspec{}, logger)
+	if err != nil {
+		t.Fatalf("unstaged diff: %v", err)
+	}
+	binary := make(map[string]bool)
+	for _, file := range files {
+		binary[file.Path] = file.Binary != nil
+		if file.Binary != nil && (len(file.Hunks) > 0 || file.LinesAdded > 0) {
+			t.Errorf("%s: binary files should have no hunks: %+v", file.Path, file)
+		}
+	}
+	want := map[string]bool{"logo.png": true, "data/dump.sql": true, "data/notes.txt": true, "data/.gitattributes": false}
+	for path, isBinary := range want {
+		if binary[path] != isBinary {
+			t.Errorf("%s: binary = %v, want %v (files %+v)", path, binary[path], isBinary, binary)
+		}
+	}
+
+	for _, file := range files {
+		if file.Path == "logo.png" && (file.Binary.Old.Size != 16 || file.Binary.New.Size != 20 || file.Binary.New.MIME != "image/png") {
+			t.Errorf("logo.png summary = %+v", file.Binary)
+		}
+	}
+}
+
+func TestModelLaysOutBinarySummary(t *testing.T) {
+	model := setupModel(t)
+	model.width = 100
+	model.height = 30
+	binary := newBinaryFileDiff("logo.png", Modified, []byte(pngHeader), []byte(pngHeader+"more"), true, true)
+	newModel, _ := model.Update(allDiffsLoadedMsg{files: []FileDiff{*binary}})
+	model = newModel.(Model)
+
+	lines := model.buildDiffPanelLines()
+	if got := model.computeDiffLayout(model.getSelectedDiffFiles()).totalLines; got != len(lines) {
+		t.Errorf("layout has %d lines, the panel renders %d", got, len(lines))
+	}
+	if !strings.Contains(stripAnsi(strings.Join(lines, "\n")), "Binary file (+4 B)") {
+		t.Errorf("panel should show the binary summary:\n%s", stripAnsi(strings.Join(lines, "\n")))
+	}
+}
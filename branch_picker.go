@@ -0,0 +1,106 @@
+package main
+
+import (
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// branchPicker is the modal for choosing the Branch Compare base branch
+type branchPicker struct {
+	branches []string
+	cursor   int
+	offset   int // first listed branch
+}
+
+// openBranchPicker shows the picker with the cursor on the current base branch
+func (m *Model) openBranchPicker(branches []string) {
+	if len(branches) == 0 {
+		return
+	}
+	picker := branchPicker{branches: branches}
+	if m.resolvedBase != nil {
+		for i, branch := range branches {
+			if branch == m.resolvedBase.Name {
+				picker.cursor = i
+				break
+			}
+		}
+	}
+	picker.scrollToCursor(m.branchPickerRows(len(branches)))
+	m.branchPicker = &picker
+}
+
+// branchPickerRows returns how many branches fit in the picker
+func (m Model) branchPickerRows(count int) int {
+	return max(1, min(count, branchPickerMaxRows, m.height-helpModalPadding-8))
+}
+
+func (p *branchPicker) scrollToCursor(rows int) {
+	if p.cursor < p.offset {
+		p.offset = p.cursor
+	} else if p.cursor >= p.offset+rows {
+		p.offset = p.cursor - rows + 1
+	}
+}
+
+// handleBranchPickerKey moves through the picker and switches to Branch Compare
+// against the chosen branch on enter
+func (m *Model) handleBranchPickerKey(key string) tea.Cmd {
+	picker := m.branchPicker
+	switch key {
+	case "ctrl+c":
+		return m.quitCmd()
+	case "esc", "q", "b":
+		m.branchPicker = nil
+	case "up", "k":
+		picker.cursor = max(0, picker.cursor-1)
+	case "down", "j":
+		picker.cursor = min(len(picker.branches)-1, picker.cursor+1)
+	case "enter":
+		m.branchPicker = nil
+		m.baseBranch = picker.branches[picker.cursor]
+		m.resolvedBase = nil
+		return m.switchDiffMode(BranchCompare)
+	}
+	picker.scrollToCursor(m.branchPickerRows(len(picker.branches)))
+	return nil
+}
+
+// renderBranchPicker renders the base branch picker overlay
+func (m Model) renderBranchPicker() string {
+	picker := m.branchPicker
+	modalWidth := min(branchPickerMaxWidth, max(0, m.width-helpModalPadding))
+
+	modalStyle := lipgloss.NewStyle().
+		Width(modalWidth).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(colorBlue).
+		Background(colorGray235).
+		Padding(1, 2)
+
+	var content strings.Builder
+	content.WriteString(helpTitleStyle.Render("Base branch for Branch Compare"))
+	content.WriteString("\n\n")
+
+	start, end := visibleRange(picker.offset, m.branchPickerRows(len(picker.branches)), len(picker.branches))
+	for i := start; i < end; i++ {
+		branch := picker.branches[i]
+		if m.resolvedBase != nil && branch == m.resolvedBase.Name {
+			branch += " (current)"
+		}
+		if i == picker.cursor {
+			content.WriteString(selectedStyle.Render("› " + branch))
+		} else {
+			content.WriteString(helpDescStyle.Render("  " + branch))
+		}
+		content.WriteString("\n")
+	}
+
+	content.WriteString("\n")
+	content.WriteString(helpKeyStyle.Render("[enter]") + " " + helpDescStyle.Render("Compare") + "   ")
+	content.WriteString(helpKeyStyle.Render("[esc]") + " " + helpDescStyle.Render("Cancel"))
+
+	return centerModal(modalStyle.Render(content.String()), modalWidth, m.width, m.height)
+}
@@ -0,0 +1,309 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// outputFormat selects what better_diff writes: the TUI or a stdout format
+type outputFormat int
+
+const (
+	outputTUI   outputFormat = iota
+	outputText               // --print/--no-tui/--format=text
+	outputJSON               // --format=json
+	outputPatch              // --format=patch
+)
+
+// cliOptions holds the options parsed from the command line
+type cliOptions struct {
+	revisions    *RevisionRange // nil when no revision was given
+	baseBranch   string         // --base: Branch Compare base branch
+	branch       bool           // --branch: start in Branch Compare mode
+	staged       bool           // --staged/--cached: start in Staged mode
+	commit       string         // --commit: diff a single commit against its parent
+	log          bool           // --log: browse the commit log
+	logRef       string         // ref the log starts from: --log=<ref> or the revision argument (empty for HEAD)
+	pathspecs    []string       // patterns after --
+	patchFile    string         // patch file to show instead of the repository, "-" for stdin
+	external     *externalDiff  // file pair from git when run as an external diff or difftool
+	noIndex      *noIndexDiff   // --no-index: two files or directories outside a repository
+	output       outputFormat
+	color        colorMode      // --color: colors in --print output
+	contextLines *int           // -U/--unified: context lines around changes (nil for the default)
+	algorithm    DiffAlgorithm  // --diff-algorithm, --minimal, --patience, --histogram
+	whitespace   WhitespaceMode // -w, -b, --ignore-cr-at-eol
+	blankLines   bool           // --ignore-blank-lines
+	renames      RenameOptions  // -M/--find-renames, -C/--find-copies, --no-renames
+	images       *imageProtocol // --image-protocol: full-size image preview (nil detects it)
+	semantic     bool           // --semantic: summarize the changed declarations of Go files
+	structural   bool           // --structural: compare JSON and YAML files by value
+}
+
+// diffContext returns the number of context lines to show around changes
+func (opts cliOptions) diffContext() int {
+	if opts.contextLines == nil {
+		return DefaultDiffContext
+	}
+	return *opts.contextLines
+}
+
+// diffOptions returns how hunks are computed for stdout formats
+func (opts cliOptions) diffOptions() DiffOptions {
+	return DiffOptions{
+		Context:          opts.diffContext(),
+		Algorithm:        opts.algorithm,
+		Whitespace:       opts.whitespace,
+		IgnoreBlankLines: opts.blankLines,
+		Renames:          opts.renames,
+		Semantic:         opts.semantic,
+		Structural:       opts.structural,
+	}
+}
+
+// imageProtocol returns how the full-size image preview is drawn: the
+// protocol given with --image-protocol or the one the terminal supports
+func (opts cliOptions) imageProtocol() imageProtocol {
+	if opts.images != nil {
+		return *opts.images
+	}
+	return detectImageProtocol(os.Getenv)
+}
+
+// diffMode returns the mode the options select: revisions compare commits,
+// --staged shows the index, --branch or --base compares against a branch,
+// --log browses history
+func (opts cliOptions) diffMode() DiffMode {
+	switch {
+	case opts.log:
+		return CommitLog
+	case opts.revisions != nil:
+		return RefCompare
+	case opts.staged:
+		return Staged
+	case opts.branch || opts.baseBranch != "":
+		return BranchCompare
+	default:
+		return Unstaged
+	}
+}
+
+// parseCLIArgs parses the arguments left after handleCLIArgs: options, at
+// most one revision argument (<rev>, <rev1>..<rev2> or <rev1>...<rev2>) or
+// patch file ("-" for stdin), and pathspecs after --. With --no-index the
+// arguments are the two files or directories to compare. When git runs
+// better_diff as GIT_EXTERNAL_DIFF or a difftool command, its arguments follow
+// the options.
+func parseCLIArgs(args []string) (cliOptions, error) {
+	opts := cliOptions{renames: DefaultRenameOptions}
+	if external, rest, ok := splitExternalDiffArgs(args, os.Getenv); ok {
+		opts.external = &external
+		args = rest
+	}
+	var positional []string
+	noIndex := false
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			opts.pathspecs = args[i+1:]
+			break
+		}
+		if arg == "-" || !strings.HasPrefix(arg, "-") {
+			positional = append(positional, arg)
+			continue
+		}
+
+		name, value, hasValue := strings.Cut(arg, "=")
+		if len(arg) > 2 && (strings.HasPrefix(arg, "-U") || strings.HasPrefix(arg, "-M") || strings.HasPrefix(arg, "-C")) {
+			// -U<n>, -M<n> and -C<n> as in git diff
+			name, value, hasValue = arg[:2], arg[2:], true
+		}
+		switch name {
+		case "--print", "--no-tui":
+			opts.output = outputText
+		case "--staged", "--cached":
+			opts.staged = true
+		case "--branch":
+			opts.branch = true
+		case "--no-index":
+			noIndex = true
+		case "--minimal":
+			opts.algorithm = AlgorithmMyers
+		case "--patience":
+			opts.algorithm = AlgorithmPatience
+		case "--histogram":
+			opts.algorithm = AlgorithmHistogram
+		case "-w", "--ignore-all-space":
+			opts.whitespace = max(opts.whitespace, IgnoreAllSpace)
+		case "-b", "--ignore-space-change":
+			opts.whitespace = max(opts.whitespace, IgnoreSpaceChange)
+		case "--ignore-cr-at-eol":
+			opts.whitespace = max(opts.whitespace, IgnoreCRAtEOL)
+		case "--ignore-blank-lines":
+			opts.blankLines = true
+		case "-M", "--find-renames", "-C", "--find-copies":
+			if err := opts.findRenames(value, hasValue, name == "-C" || name == "--find-copies"); err != nil {
+				return cliOptions{}, err
+			}
+		case "--no-renames":
+			opts.renames.Detect = false
+		case "--semantic":
+			opts.semantic = true
+		case "--structural":
+			opts.structural = true
+		case "--log":
+			opts.log = true
+			if hasValue {
+				opts.logRef = value
+			}
+		case "--base", "--color", "--format", "--commit", "--unified", "-U", "--diff-algorithm", "--image-protocol":
+			if !hasValue {
+				if i+1 >= len(args) {
+					return cliOptions{}, fmt.Errorf("option %s requires a value", name)
+				}
+				i++
+				value = args[i]
+			}
+			if err := opts.setValue(name, value); err != nil {
+				return cliOptions{}, err
+			}
+		default:
+			return cliOptions{}, fmt.Errorf("unknown option %q", arg)
+		}
+	}
+
+	if noIndex {
+		if len(positional) != 2 {
+			return cliOptions{}, fmt.Errorf("--no-index requires two paths to compare")
+		}
+		diff, err := newNoIndexDiff(positional[0], positional[1])
+		if err != nil {
+			return cliOptions{}, err
+		}
+		opts.noIndex = &diff
+		positional = nil
+	}
+	for _, arg := range positional {
+		if opts.revisions != nil || opts.patchFile != "" {
+			return cliOptions{}, fmt.Errorf("unexpected argument %q: only one revision, range or patch file is supported", arg)
+		}
+		if arg == "-" || isPatchFile(arg) {
+			opts.patchFile = arg
+			continue
+		}
+		revisions, err := parseRevisionRange(arg)
+		if err != nil {
+			return cliOptions{}, err
+		}
+		opts.revisions = &revisions
+	}
+	if opts.log && opts.revisions != nil {
+		// git log <ref>: the revision is where the log starts
+		if opts.revisions.To != "" || opts.logRef != "" {
+			return cliOptions{}, fmt.Errorf("--log takes a single ref, got %q", opts.revisions)
+		}
+		opts.logRef = opts.revisions.From
+		opts.revisions = nil
+	}
+
+	return opts, opts.validate()
+}
+
+// validate rejects options that select more than one diff
+func (opts cliOptions) validate() error {
+	selected := 0
+	for _, set := range []bool{opts.revisions != nil, opts.staged, opts.branch || opts.baseBranch != "", opts.commit != "", opts.log, opts.patchFile != "", opts.external != nil, opts.noIndex != nil} {
+		if set {
+			selected++
+		}
+	}
+	if selected > 1 {
+		return fmt.Errorf("a revision, --staged, --branch/--base, --commit, --log, a patch file, --no-index and external diff arguments cannot be combined")
+	}
+	if opts.commit != "" && opts.output == outputTUI {
+		return fmt.Errorf("--commit requires --print or --format")
+	}
+	if opts.output == outputPatch && !opts.diffOptions().exact() {
+		return fmt.Errorf("--format=patch cannot be combined with -w, -b, --ignore-cr-at-eol or --ignore-blank-lines: the patch would not apply")
+	}
+	if opts.log && opts.output != outputTUI {
+		return fmt.Errorf("--log cannot be combined with --print or --format; use --commit to print a commit")
+	}
+	return nil
+}
+
+// setValue stores the value of an option that takes one
+func (opts *cliOptions) setValue(name, value string) error {
+	if value == "" {
+		return fmt.Errorf("option %s requires a value", name)
+	}
+	switch name {
+	case "--base":
+		opts.baseBranch = value
+	case "--color":
+		mode, err := parseColorMode(value)
+		if err != nil {
+			return err
+		}
+		opts.color = mode
+	case "--format":
+		switch value {
+		case "text":
+			opts.output = outputText
+		case "json":
+			opts.output = outputJSON
+		case "patch":
+			opts.output = outputPatch
+		default:
+			return fmt.Errorf("invalid format %q: want text, json or patch", value)
+		}
+	case "--commit":
+		opts.commit = value
+	case "--unified", "-U":
+		lines, err := strconv.Atoi(value)
+		if err != nil || lines < 0 {
+			return fmt.Errorf("invalid context size %q: want a number of lines", value)
+		}
+		opts.contextLines = &lines
+	case "--diff-algorithm":
+		algorithm, err := parseDiffAlgorithm(value)
+		if err != nil {
+			return err
+		}
+		opts.algorithm = algorithm
+	case "--image-protocol":
+		protocol, err := parseImageProtocol(value)
+		if err != nil {
+			return err
+		}
+		opts.images = protocol
+	}
+	return nil
+}
+
+// findRenames turns on rename detection, and copy detection with copies, at
+// the similarity given with the option, if any
+func (opts *cliOptions) findRenames(value string, hasValue, copies bool) error {
+	opts.renames.Detect = true
+	opts.renames.Copies = opts.renames.Copies || copies
+	if !hasValue {
+		return nil
+	}
+	threshold, err := parseRenameScore(value)
+	if err != nil {
+		return err
+	}
+	opts.renames.Threshold = threshold
+	return nil
+}
+
+// isPatchFile reports whether a command line argument names an existing file,
+// which is shown as a patch rather than resolved as a revision. A file that
+// is also a revision's name is rejected when run checks it against the
+// repository.
+func isPatchFile(arg string) bool {
+	info, err := os.Stat(arg)
+	return err == nil && info.Mode().IsRegular()
+}
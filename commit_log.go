@@ -0,0 +1,119 @@
+package main
+
+import (
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// enterLogRefMode opens the prompt for the ref whose history the commit log
+// lists, prefilled with the current one
+func (m *Model) enterLogRefMode() {
+	if m.diffMode == InputDiff {
+		return
+	}
+	m.logRefMode = true
+	m.logRefInput = defaultRevision(m.logRef)
+}
+
+// handleLogRefInput handles keyboard input in the log ref prompt. Enter
+// switches to the commit log of the typed ref, HEAD when it is empty.
+func (m *Model) handleLogRefInput(key string, msg tea.KeyMsg) tea.Cmd {
+	switch key {
+	case "esc", "ctrl+c":
+		m.logRefMode = false
+		m.logRefInput = ""
+	case "enter":
+		m.logRef = strings.TrimSpace(m.logRefInput)
+		m.logPath = ""
+		m.logRefMode = false
+		m.logRefInput = ""
+		return m.switchDiffMode(CommitLog)
+	case "backspace":
+		if len(m.logRefInput) > 0 {
+			m.logRefInput = m.logRefInput[:len(m.logRefInput)-1]
+		}
+	default:
+		if len(msg.Runes) > 0 && isPrintableRune(msg.Runes[0]) {
+			m.logRefInput += string(msg.Runes)
+		}
+	}
+	return nil
+}
+
+// applyCommitLogLoaded appends a page of the log, and loads the diff of the
+// newest commit with the first page. Pages for another log or offset are stale.
+func (m *Model) applyCommitLogLoaded(msg commitLogLoadedMsg) tea.Cmd {
+	m.logLoading = false
+	if m.diffMode != CommitLog || msg.offset != len(m.commits) {
+		return nil
+	}
+	// Non-nil once loaded, so an empty log is not shown as loading
+	m.commits = append(m.commits, msg.commits...)
+	if m.commits == nil {
+		m.commits = []Commit{}
+	}
+	m.logHasMore = msg.hasMore
+	m.err = nil
+	if msg.offset == 0 && len(m.commits) > 0 {
+		return m.selectCommit()
+	}
+	return nil
+}
+
+// applyCommitDiffLoaded shows a commit chosen in the log: its header and diff
+// replace the previous commit's at once
+func (m *Model) applyCommitDiffLoaded(msg commitDiffLoadedMsg) {
+	if m.diffMode != CommitLog {
+		return
+	}
+	if m.selectedCommit == nil || m.selectedCommit.Hash != msg.commit.Hash {
+		m.diffScroll = 0
+	}
+	m.selectedCommit = &msg.commit
+	m.applyAllDiffsLoaded(allDiffsLoadedMsg{msg.files})
+}
+
+// openFileHistory lists the commits that changed the file selected in the
+// tree, following renames; Esc returns to the current mode
+func (m *Model) openFileHistory() tea.Cmd {
+	if m.diffMode == InputDiff || m.diffMode == CommitLog {
+		return nil
+	}
+	flatTree := m.flattenTree()
+	if m.selectedIndex < 0 || m.selectedIndex >= len(flatTree) || flatTree[m.selectedIndex].isDir {
+		return nil
+	}
+	m.historyFrom = m.diffMode
+	m.logPath = flatTree[m.selectedIndex].path
+	m.logRef = ""
+	m.panel = FileTreePanel
+	return m.switchDiffMode(CommitLog)
+}
+
+// closeFileHistory returns from a file history to the mode it was opened from
+func (m *Model) closeFileHistory() tea.Cmd {
+	if m.diffMode != CommitLog || m.logPath == "" {
+		return nil
+	}
+	return m.switchDiffMode(m.historyFrom)
+}
+
+// loadMoreCommits loads the next page of the log once the cursor reaches the
+// last loaded commit
+func (m *Model) loadMoreCommits() tea.Cmd {
+	if m.diffMode != CommitLog || !m.logHasMore || m.logLoading || m.selectedIndex < len(m.commits)-1 {
+		return nil
+	}
+	m.logLoading = true
+	return m.LoadCommitLog(len(m.commits))
+}
+
+// selectCommit loads the diff of the commit under the cursor against its
+// parent; the previous commit stays shown until it arrives
+func (m *Model) selectCommit() tea.Cmd {
+	if m.selectedIndex < 0 || m.selectedIndex >= len(m.commits) {
+		return nil
+	}
+	return m.LoadLogCommit(m.commits[m.selectedIndex])
+}
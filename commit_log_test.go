@@ -0,0 +1,220 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// setupCommitLogRepo returns a repository with the commits "initial" and
+// "change 2" to "change 5", each rewriting a.txt
+func setupCommitLogRepo(t *testing.T) *GitService {
+	t.Helper()
+	gitService, root := setupTempGitService(t, map[string]string{"a.txt": "version 1\n"})
+	for i := 2; i <= 5; i++ {
+		writeWorktreeFile(t, root, "a.txt", fmt.Sprintf("version %d\n", i))
+		commitAll(t, gitService, fmt.Sprintf("change %d\n\nWhy version %d is needed.\n", i, i))
+	}
+	return gitService
+}
+
+func commitMessages(commits []Commit) []string {
+	messages := make([]string, len(commits))
+	for i, commit := range commits {
+		messages[i] = commit.Message
+	}
+	return messages
+}
+
+func TestGetCommitLog(t *testing.T) {
+	gitService := setupCommitLogRepo(t)
+
+	commits, hasMore, err := gitService.GetCommitLog("", 0, 2)
+	if err != nil {
+		t.Fatalf("GetCommitLog: %v", err)
+	}
+	if got := strings.Join(commitMessages(commits), ", "); got != "change 5, change 4" || !hasMore {
+		t.Errorf("first page = %s (more: %v), want change 5, change 4 with more", got, hasMore)
+	}
+	if commits[0].FullMessage != "change 5\n\nWhy version 5 is needed." || commits[0].Email != "test@example.com" {
+		t.Errorf("commit = %+v, want the full message and author email", commits[0])
+	}
+
+	commits, hasMore, err = gitService.GetCommitLog("HEAD~2", 1, 5)
+	if err != nil {
+		t.Fatalf("GetCommitLog(HEAD~2): %v", err)
+	}
+	if got := strings.Join(commitMessages(commits), ", "); got != "change 2, initial" || hasMore {
+		t.Errorf("log of HEAD~2 after one commit = %s (more: %v), want change 2, initial", got, hasMore)
+	}
+
+	if _, _, err := gitService.GetCommitLog("no-such-ref", 0, 5); err == nil {
+		t.Error("an unknown ref should fail")
+	}
+}
+
+// runCmd delivers the message of a loader to the model
+func runCmd(t *testing.T, model Model, cmd tea.Cmd) Model {
+	t.Helper()
+	if cmd == nil {
+		t.Fatal("expected a command")
+	}
+	newModel, _ := model.Update(cmd())
+	return newModel.(Model)
+}
+
+func TestModelBrowsesCommitLog(t *testing.T) {
+	gitService := setupCommitLogRepo(t)
+	model := NewModel(gitService, newDefaultLogger(ERROR)).WithLog("")
+	model.width = 120
+	model.height = 30
+
+	// The first page shows its newest commit; the rest loads at the end of it
+	commits, _, err := gitService.GetCommitLog("", 0, 5)
+	if err != nil {
+		t.Fatalf("GetCommitLog: %v", err)
+	}
+	newModel, cmd := model.Update(commitLogLoadedMsg{offset: 0, commits: commits[:3], hasMore: true})
+	model = runCmd(t, newModel.(Model), cmd)
+	if model.selectedCommit == nil || model.selectedCommit.Hash != commits[0].Hash {
+		t.Fatalf("shown commit = %+v, want the newest", model.selectedCommit)
+	}
+
+	lines := model.buildDiffPanelLines()
+	if layout := model.computeDiffLayout(model.getSelectedDiffFiles()); layout.totalLines != len(lines) {
+		t.Errorf("layout has %d lines, the panel renders %d", layout.totalLines, len(lines))
+	}
+	view := stripAnsi(strings.Join(lines, "\n"))
+	for _, want := range []string{"commit " + commits[0].Hash, "Author: Test <test@example.com>", "    change 5\n    \n    Why version 5 is needed.", "+ version 5"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("diff panel should contain %q:\n%s", want, view)
+		}
+	}
+
+	down := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'j'}}
+	newModel, cmd = model.Update(down)
+	if cmd != nil {
+		t.Error("the next page should load only at the last commit")
+	}
+	newModel, cmd = newModel.(Model).Update(down)
+	model = runCmd(t, newModel.(Model), cmd)
+	if got := strings.Join(commitMessages(model.commits), ", "); got != "change 5, change 4, change 3, change 2, initial" || model.logHasMore {
+		t.Errorf("log after paging = %s (more: %v)", got, model.logHasMore)
+	}
+
+	newModel, cmd = model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	model = runCmd(t, newModel.(Model), cmd)
+	if model.selectedCommit == nil || model.selectedCommit.Message != "change 3" {
+		t.Fatalf("shown commit = %+v, want change 3", model.selectedCommit)
+	}
+	if view := stripAnsi(strings.Join(model.buildDiffPanelLines(), "\n")); !strings.Contains(view, "- version 2") || !strings.Contains(view, "+ version 3") {
+		t.Errorf("change 3 should be diffed against its parent:\n%s", view)
+	}
+}
+
+// setupFileHistoryRepo returns a repository where old.go is edited, renamed
+// to new.go with an edit, and edited again, next to commits to other.txt
+func setupFileHistoryRepo(t *testing.T) (*GitService, string) {
+	t.Helper()
+	source := strings.Join(numberedLines(10), "\n") + "\n"
+	gitService, root := setupTempGitService(t, map[string]string{"old.go": source, "other.txt": "one\n"})
+	source = strings.Replace(source, "line b", "line B", 1)
+	writeWorktreeFile(t, root, "old.go", source)
+	commitAll(t, gitService, "edit old")
+	writeWorktreeFile(t, root, "other.txt", "two\n")
+	commitAll(t, gitService, "touch other")
+
+	worktree, err := gitService.repo.Worktree()
+	if err != nil {
+		t.Fatalf("get worktree: %v", err)
+	}
+	if _, err := worktree.Remove("old.go"); err != nil {
+		t.Fatalf("remove old.go: %v", err)
+	}
+	source = strings.Replace(source, "line j", "line J", 1)
+	writeWorktreeFile(t, root, "new.go", source)
+	commitAll(t, gitService, "rename")
+	writeWorktreeFile(t, root, "new.go", strings.Replace(source, "line e", "line E", 1))
+	commitAll(t, gitService, "edit new")
+	return gitService, root
+}
+
+func TestGetFileHistory(t *testing.T) {
+	gitService, root := setupFileHistoryRepo(t)
+	logger := newDefaultLogger(ERROR)
+
+	commits, err := gitService.GetFileHistory("", "new.go", DefaultRenameOptions, logger)
+	if err != nil {
+		t.Fatalf("GetFileHistory: %v", err)
+	}
+	var got []string
+	for _, commit := range commits {
+		got = append(got, fmt.Sprintf("%s %s<-%s", commit.Message, commit.Path, commit.OldPath))
+	}
+	want := []string{"edit new new.go<-", "rename new.go<-old.go", "edit old old.go<-", "initial old.go<-"}
+	if strings.Join(got, ", ") != strings.Join(want, ", ") {
+		t.Errorf("history = %q, want %q", got, want)
+	}
+
+	if _, err := exec.LookPath("git"); err == nil {
+		cmd := exec.Command("git", "log", "--follow", "--format=%s", "--", "new.go")
+		cmd.Dir = root
+		out, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("git log --follow: %v", err)
+		}
+		if gitLog := strings.Join(commitMessages(commits), "\n") + "\n"; string(out) != gitLog {
+			t.Errorf("history:\n%s\ngit log --follow:\n%s", gitLog, out)
+		}
+	}
+
+	files, err := gitService.GetFileCommitDiff(commits[1], DiffOnly, DiffOptions{Context: 1, Renames: DefaultRenameOptions}, logger)
+	if err != nil {
+		t.Fatalf("GetFileCommitDiff: %v", err)
+	}
+	if len(files) != 1 || files[0].ChangeType != Renamed || files[0].OldPath != "old.go" || files[0].LinesAdded != 1 {
+		t.Errorf("rename commit diff = %+v, want old.go renamed to new.go with one changed line", files)
+	}
+
+	// Without rename detection the history ends where the file appeared
+	commits, err = gitService.GetFileHistory("", "new.go", RenameOptions{}, logger)
+	if err != nil || strings.Join(commitMessages(commits), ", ") != "edit new, rename" {
+		t.Errorf("history without renames = %q, %v", commitMessages(commits), err)
+	}
+}
+
+func TestModelShowsFileHistory(t *testing.T) {
+	gitService, root := setupFileHistoryRepo(t)
+	writeWorktreeFile(t, root, "new.go", "rewritten\n")
+	model := NewModel(gitService, newDefaultLogger(ERROR))
+	model.width = 120
+	model.height = 30
+	files, err := gitService.GetDiffWithContext(Unstaged, DiffOnly, model.diffOptions(), Pathspec{}, model.logger)
+	if err != nil {
+		t.Fatalf("GetDiffWithContext: %v", err)
+	}
+	newModel, _ := model.Update(allDiffsLoadedMsg{files: files})
+	model = newModel.(Model)
+
+	newModel, cmd := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'H'}})
+	model = runCmd(t, newModel.(Model), cmd)
+	if model.diffMode != CommitLog || model.diffModeLabel() != "History new.go" || len(model.commits) != 4 {
+		t.Fatalf("H should list the history of new.go, got %q with %d commits", model.diffModeLabel(), len(model.commits))
+	}
+
+	newModel, _ = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'j'}})
+	newModel, cmd = newModel.(Model).Update(tea.KeyMsg{Type: tea.KeyEnter})
+	model = runCmd(t, newModel.(Model), cmd)
+	view := stripAnsi(strings.Join(model.buildDiffPanelLines(), "\n"))
+	if !strings.Contains(view, "    rename") || !strings.Contains(view, "old.go → new.go") || strings.Contains(view, "other.txt") {
+		t.Errorf("the rename commit should show only the followed file:\n%s", view)
+	}
+
+	newModel, _ = model.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if model = newModel.(Model); model.diffMode != Unstaged || model.logPath != "" {
+		t.Errorf("esc should return to Unstaged, got mode %d with path %q", model.diffMode, model.logPath)
+	}
+}
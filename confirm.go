@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// discardRequest is a discard of worktree changes waiting for confirmation
+type discardRequest struct {
+	path string
+	hunk *Hunk // nil discards every unstaged change in the file
+}
+
+func (r discardRequest) prompt() string {
+	if r.hunk == nil {
+		return fmt.Sprintf("Discard all unstaged changes in %s?", r.path)
+	}
+	return fmt.Sprintf("Discard unstaged hunk at line %d of %s?", r.hunk.NewStart, r.path)
+}
+
+// requestDiscard asks for confirmation before discarding the hunk under the cursor
+// (diff panel) or the selected file (file tree, whole file view).
+func (m *Model) requestDiscard() {
+	if m.diffMode != Unstaged {
+		return
+	}
+
+	if m.panel == DiffPanel && m.diffViewMode.showsHunks() {
+		if m.hunkEditsBlocked() {
+			return
+		}
+		loc, ok := m.currentHunk()
+		if !ok {
+			return
+		}
+		hunk := loc.hunk()
+		m.pendingDiscard = &discardRequest{path: loc.file.Path, hunk: &hunk}
+		return
+	}
+
+	path, ok := m.selectedFilePath()
+	if !ok {
+		return
+	}
+	m.pendingDiscard = &discardRequest{path: path}
+}
+
+// selectedFilePath returns the path of the file selected in the tree
+func (m Model) selectedFilePath() (string, bool) {
+	flatTree := m.flattenTree()
+	if m.selectedIndex < 0 || m.selectedIndex >= len(flatTree) || flatTree[m.selectedIndex].isDir {
+		return "", false
+	}
+	return flatTree[m.selectedIndex].path, true
+}
+
+// handleConfirmKey answers the pending discard; every other key is ignored
+func (m *Model) handleConfirmKey(key string) tea.Cmd {
+	switch key {
+	case "ctrl+c":
+		return m.quitCmd()
+	case "y", "Y", "enter":
+		request := *m.pendingDiscard
+		m.pendingDiscard = nil
+		if request.hunk == nil {
+			return m.DiscardFile(request.path)
+		}
+		return m.DiscardHunk(request.path, *request.hunk)
+	case "n", "N", "esc", "q":
+		m.pendingDiscard = nil
+	}
+	return nil
+}
+
+// renderConfirmModal renders the discard confirmation overlay
+func (m Model) renderConfirmModal() string {
+	prompt := m.pendingDiscard.prompt()
+	modalWidth := min(confirmModalMaxWidth, max(0, m.width-helpModalPadding))
+
+	modalStyle := lipgloss.NewStyle().
+		Width(modalWidth).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(colorRed196).
+		Background(colorGray235).
+		Padding(1, 2)
+
+	var content strings.Builder
+	content.WriteString(confirmTitleStyle.Render("Discard changes"))
+	content.WriteString("\n\n")
+	content.WriteString(helpDescStyle.Render(prompt))
+	content.WriteString("\n")
+	content.WriteString(subtleStyle.Render("This cannot be undone."))
+	content.WriteString("\n\n")
+	content.WriteString(helpKeyStyle.Render("[y]") + " " + helpDescStyle.Render("Discard") + "   ")
+	content.WriteString(helpKeyStyle.Render("[n/Esc]") + " " + helpDescStyle.Render("Cancel"))
+
+	return centerModal(modalStyle.Render(content.String()), modalWidth, m.width, m.height)
+}
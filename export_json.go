@@ -0,0 +1,254 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// jsonSchemaVersion is bumped whenever a field of the JSON output is removed,
+// renamed or changes meaning. Adding fields does not change the version.
+const jsonSchemaVersion = 1
+
+// jsonDocument is the top level of --format=json output
+type jsonDocument struct {
+	Version   int        `json:"version"`
+	Mode      string     `json:"mode"`                // unstaged, staged, branch, range, commit, patch, external or no-index
+	Base      string     `json:"base,omitempty"`      // branch mode: base branch
+	Revisions string     `json:"revisions,omitempty"` // range mode: <rev>, A..B or A...B
+	Commit    string     `json:"commit,omitempty"`    // commit mode: full hash
+	Patch     string     `json:"patch,omitempty"`     // patch mode: file name or "-" for stdin
+	NoIndex   []string   `json:"no_index,omitempty"`  // no-index mode: the old and new file or directory
+	Pathspec  []string   `json:"pathspec,omitempty"`
+	Files     []jsonFile `json:"files"`
+}
+
+type jsonFile struct {
+	Path       string      `json:"path"`
+	OldPath    string      `json:"old_path"` // path before a rename or copy, empty otherwise
+	ChangeType string      `json:"change_type"`
+	Similarity int         `json:"similarity,omitempty"` // renames and copies: percent of content shared with old_path
+	Stats      jsonStats   `json:"stats"`
+	Hunks      []jsonHunk  `json:"hunks"`
+	Binary     *jsonBinary `json:"binary,omitempty"`       // binary files, which have no hunks
+	Decls      []jsonDecl  `json:"declarations,omitempty"` // --semantic: changed Go declarations
+	Structure  []jsonValue `json:"structure,omitempty"`    // --structural: changed JSON and YAML values
+}
+
+// jsonValue is a JSON or YAML value that changed at a key path. Old and new
+// values are JSON text, omitted on the side where the path does not exist.
+type jsonValue struct {
+	Change string `json:"change"` // added, removed or modified
+	Path   string `json:"path"`
+	Old    string `json:"old,omitempty"`
+	New    string `json:"new,omitempty"`
+}
+
+// jsonDecl is a top-level Go declaration that changed. Lines are 1-based and
+// omitted on the side where the declaration does not exist.
+type jsonDecl struct {
+	Change   string   `json:"change"` // added, removed or modified
+	Name     string   `json:"name"`
+	Details  []string `json:"details,omitempty"`
+	OldStart int      `json:"old_start,omitempty"`
+	OldEnd   int      `json:"old_end,omitempty"`
+	NewStart int      `json:"new_start,omitempty"`
+	NewEnd   int      `json:"new_end,omitempty"`
+}
+
+// jsonBinary describes both versions of a binary file; a side that does not
+// exist is null
+type jsonBinary struct {
+	Old *jsonBlob `json:"old"`
+	New *jsonBlob `json:"new"`
+}
+
+type jsonBlob struct {
+	Size int    `json:"size"`
+	Hash string `json:"hash"` // git blob hash
+	MIME string `json:"mime"` // guessed from the leading bytes
+	// Dimensions of PNG, JPEG and GIF images
+	Width  int `json:"width,omitempty"`
+	Height int `json:"height,omitempty"`
+}
+
+type jsonStats struct {
+	Added   int `json:"added"`
+	Removed int `json:"removed"`
+}
+
+type jsonHunk struct {
+	OldStart int        `json:"old_start"`
+	OldLines int        `json:"old_lines"`
+	NewStart int        `json:"new_start"`
+	NewLines int        `json:"new_lines"`
+	Function string     `json:"function,omitempty"` // enclosing function or section
+	Lines    []jsonLine `json:"lines"`
+}
+
+// jsonLine is one line of a hunk. Line numbers are 1-based and omitted on the
+// side the line does not exist on (old_line for added, new_line for removed).
+type jsonLine struct {
+	Type    string `json:"type"` // context, added or removed
+	Content string `json:"content"`
+	OldLine int    `json:"old_line,omitempty"`
+	NewLine int    `json:"new_line,omitempty"`
+}
+
+func changeTypeName(changeType ChangeType) string {
+	switch changeType {
+	case Added:
+		return "added"
+	case Deleted:
+		return "deleted"
+	case Renamed:
+		return "renamed"
+	case Copied:
+		return "copied"
+	default:
+		return "modified"
+	}
+}
+
+func lineTypeName(lineType LineType) string {
+	switch lineType {
+	case LineAdded:
+		return "added"
+	case LineRemoved:
+		return "removed"
+	default:
+		return "context"
+	}
+}
+
+// newJSONFiles converts the diff model to its JSON form
+func newJSONFiles(files []FileDiff) []jsonFile {
+	result := make([]jsonFile, 0, len(files))
+	for _, file := range files {
+		hunks := make([]jsonHunk, 0, len(file.Hunks))
+		for _, hunk := range file.Hunks {
+			lines := make([]jsonLine, 0, len(hunk.Lines))
+			for _, line := range hunk.Lines {
+				lines = append(lines, jsonLine{
+					Type:    lineTypeName(line.Type),
+					Content: line.Content,
+					OldLine: line.OldLineNum,
+					NewLine: line.NewLineNum,
+				})
+			}
+			hunks = append(hunks, jsonHunk{
+				OldStart: hunk.OldStart,
+				OldLines: hunk.OldCount,
+				NewStart: hunk.NewStart,
+				NewLines: hunk.NewCount,
+				Function: hunk.Function,
+				Lines:    lines,
+			})
+		}
+
+		result = append(result, jsonFile{
+			Path:       file.Path,
+			OldPath:    file.OldPath,
+			ChangeType: changeTypeName(file.ChangeType),
+			Similarity: file.Similarity,
+			Stats:      jsonStats{Added: file.LinesAdded, Removed: file.LinesRemoved},
+			Hunks:      hunks,
+			Binary:     newJSONBinary(file.Binary),
+			Decls:      newJSONDecls(file.Decls),
+			Structure:  newJSONValues(file.Structure),
+		})
+	}
+	return result
+}
+
+func newJSONValues(diff *StructuralDiff) []jsonValue {
+	if diff == nil {
+		return nil
+	}
+	result := make([]jsonValue, 0, len(diff.Changes))
+	for _, change := range diff.Changes {
+		result = append(result, jsonValue{Change: change.Kind.String(), Path: change.Path, Old: change.Old, New: change.New})
+	}
+	return result
+}
+
+func newJSONDecls(decls []DeclChange) []jsonDecl {
+	var result []jsonDecl
+	for _, decl := range decls {
+		result = append(result, jsonDecl{
+			Change:   decl.Kind.String(),
+			Name:     decl.Name,
+			Details:  decl.Details,
+			OldStart: decl.OldStart,
+			OldEnd:   decl.OldEnd,
+			NewStart: decl.NewStart,
+			NewEnd:   decl.NewEnd,
+		})
+	}
+	return result
+}
+
+func newJSONBinary(info *BinaryInfo) *jsonBinary {
+	if info == nil {
+		return nil
+	}
+	blob := func(b BinaryBlob) *jsonBlob {
+		if !b.Exists {
+			return nil
+		}
+		return &jsonBlob{Size: b.Size, Hash: b.Hash, MIME: b.MIME, Width: b.Width, Height: b.Height}
+	}
+	return &jsonBinary{Old: blob(info.Old), New: blob(info.New)}
+}
+
+// writeJSON writes the document as indented JSON
+func writeJSON(w io.Writer, doc jsonDocument) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	return encoder.Encode(doc)
+}
+
+// runJSON writes the diff selected on the command line to stdout as JSON
+func runJSON(w io.Writer, gitService *GitService, opts cliOptions, pathspec Pathspec, logger *Logger) error {
+	files, err := loadDiffs(gitService, opts, pathspec, logger)
+	if err != nil {
+		return fmt.Errorf("load diff: %w", err)
+	}
+
+	doc := jsonDocument{
+		Version:  jsonSchemaVersion,
+		Pathspec: opts.pathspecs,
+		Files:    newJSONFiles(files),
+	}
+	switch {
+	case opts.patchFile != "":
+		doc.Mode, doc.Patch = "patch", opts.patchFile
+	case opts.external != nil:
+		doc.Mode = "external"
+	case opts.noIndex != nil:
+		doc.Mode, doc.NoIndex = "no-index", []string{opts.noIndex.oldPath, opts.noIndex.newPath}
+	case opts.commit != "":
+		commit, err := gitService.resolveCommit(opts.commit)
+		if err != nil {
+			return err
+		}
+		doc.Mode, doc.Commit = "commit", commit.Hash.String()
+	case opts.revisions != nil:
+		doc.Mode, doc.Revisions = "range", opts.revisions.String()
+	case opts.diffMode() == BranchCompare:
+		doc.Mode = "branch"
+		if base, err := gitService.ResolveBaseBranch(opts.baseBranch); err == nil {
+			doc.Base = base.Name
+		}
+	case opts.diffMode() == Staged:
+		doc.Mode = "staged"
+	default:
+		doc.Mode = "unstaged"
+	}
+
+	if err := writeJSON(w, doc); err != nil {
+		return fmt.Errorf("write json: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func decodeJSONOutput(t *testing.T, gitService *GitService, opts cliOptions) (jsonDocument, string) {
+	t.Helper()
+	var out bytes.Buffer
+	if err := runJSON(&out, gitService, opts, Pathspec{}, newDefaultLogger(ERROR)); err != nil {
+		t.Fatalf("runJSON: %v", err)
+	}
+	var doc jsonDocument
+	if err := json.Unmarshal(out.Bytes(), &doc); err != nil {
+		t.Fatalf("decode output: %v\n%s", err, out.String())
+	}
+	return doc, out.String()
+}
+
+func TestJSONExportUnstaged(t *testing.T) {
+	gitService, root := setupTempGitService(t, map[string]string{"a.txt": "one\ntwo\nthree\n"})
+	writeWorktreeFile(t, root, "a.txt", "one\n2\nthree\n")
+
+	doc, raw := decodeJSONOutput(t, gitService, cliOptions{output: outputJSON})
+	if doc.Version != jsonSchemaVersion || doc.Mode != "unstaged" || len(doc.Files) != 1 {
+		t.Fatalf("unexpected document: %s", raw)
+	}
+
+	file := doc.Files[0]
+	if file.Path != "a.txt" || file.ChangeType != "modified" || file.Stats != (jsonStats{Added: 1, Removed: 1}) || len(file.Hunks) != 1 {
+		t.Fatalf("unexpected file: %+v", file)
+	}
+	hunk := file.Hunks[0]
+	if hunk.OldStart != 1 || hunk.OldLines != 3 || hunk.NewStart != 1 || hunk.NewLines != 3 {
+		t.Errorf("hunk range = %+v", hunk)
+	}
+	want := []jsonLine{
+		{Type: "context", Content: "one", OldLine: 1, NewLine: 1},
+		{Type: "removed", Content: "two", OldLine: 2},
+		{Type: "added", Content: "2", NewLine: 2},
+		{Type: "context", Content: "three", OldLine: 3, NewLine: 3},
+	}
+	if len(hunk.Lines) != len(want) {
+		t.Fatalf("hunk lines = %+v, want %+v", hunk.Lines, want)
+	}
+	for i := range want {
+		if hunk.Lines[i] != want[i] {
+			t.Errorf("line %d = %+v, want %+v", i, hunk.Lines[i], want[i])
+		}
+	}
+
+	// Missing line numbers are omitted rather than written as 0
+	if strings.Contains(raw, `"old_line": 0`) || !strings.Contains(raw, `"old_path": ""`) {
+		t.Errorf("unexpected field encoding:\n%s", raw)
+	}
+}
+
+func TestJSONExportCommit(t *testing.T) {
+	gitService, root := setupTempGitService(t, map[string]string{"a.txt": "a\n"})
+	first := headHash(t, gitService).String()
+	writeWorktreeFile(t, root, "b.txt", "b\n")
+	writeWorktreeFile(t, root, "logo.png", pngHeader)
+	commitAll(t, gitService, "add b")
+
+	doc, raw := decodeJSONOutput(t, gitService, cliOptions{output: outputJSON, commit: "HEAD"})
+	if doc.Mode != "commit" || doc.Commit != headHash(t, gitService).String() {
+		t.Fatalf("unexpected document header: %s", raw)
+	}
+	if len(doc.Files) != 2 || doc.Files[0].Path != "b.txt" || doc.Files[0].ChangeType != "added" || doc.Files[0].Binary != nil {
+		t.Fatalf("HEAD should add b.txt and logo.png: %s", raw)
+	}
+	if binary := doc.Files[1].Binary; binary == nil || binary.Old != nil || *binary.New != (jsonBlob{Size: 16, Hash: binary.New.Hash, MIME: "image/png"}) || len(binary.New.Hash) != 40 {
+		t.Errorf("logo.png should be summarized as binary: %s", raw)
+	}
+
+	// A root commit is diffed against an empty tree
+	doc, raw = decodeJSONOutput(t, gitService, cliOptions{output: outputJSON, commit: first})
+	if len(doc.Files) != 1 || doc.Files[0].Path != "a.txt" || doc.Files[0].Hunks[0].Lines[0].NewLine != 1 {
+		t.Fatalf("root commit should add a.txt: %s", raw)
+	}
+}
+
+func TestParseCLIArgsOutputFormats(t *testing.T) {
+	opts, err := parseCLIArgs([]string{"--format=json", "--commit", "abc123"})
+	if err != nil || opts.output != outputJSON || opts.commit != "abc123" {
+		t.Fatalf("parseCLIArgs(json commit) = %+v, %v", opts, err)
+	}
+	opts, err = parseCLIArgs([]string{"--format", "text", "--branch"})
+	if err != nil || opts.output != outputText || opts.diffMode() != BranchCompare {
+		t.Fatalf("parseCLIArgs(text branch) = %+v, %v", opts, err)
+	}
+
+	for _, args := range [][]string{{"--format=xml"}, {"--commit", "HEAD"}, {"--print", "--commit", "HEAD", "--staged"}} {
+		if _, err := parseCLIArgs(args); err == nil {
+			t.Errorf("parseCLIArgs(%q) should fail", args)
+		}
+	}
+}
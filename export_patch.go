@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+)
+
+// defaultPatchFile is where the export prompt suggests writing the patch
+const defaultPatchFile = "better_diff.patch"
+
+const noNewlineMarker = `\ No newline at end of file`
+
+// writePatch writes files as a git-style unified diff that git apply accepts.
+// Hunks are written as loaded, so the patch has the context size of the view.
+// Files without hunks (binary files, newline-only changes) are left out,
+// except renames and copies, which git apply performs from their headers.
+// Binary renames and copies are kept only when the content is unchanged.
+func writePatch(w io.Writer, files []FileDiff) error {
+	out := bufio.NewWriter(w)
+	for _, file := range patchFiles(files) {
+		for _, line := range patchFileLines(file) {
+			out.WriteString(line)
+			out.WriteByte('\n')
+		}
+	}
+	return out.Flush()
+}
+
+// patchFiles returns the files a patch can express: those with hunks
+func patchFiles(files []FileDiff) []FileDiff {
+	result := make([]FileDiff, 0, len(files))
+	for _, file := range files {
+		if file.Binary != nil && file.Binary.Old.Hash != file.Binary.New.Hash {
+			continue
+		}
+		if len(file.Hunks) > 0 || file.displayPath() != file.Path || file.modeChanged() {
+			result = append(result, file)
+		}
+	}
+	return result
+}
+
+// patchFileLines returns the headers and hunks of one file of a patch
+func patchFileLines(file FileDiff) []string {
+	oldPath := file.Path
+	if (file.ChangeType == Renamed || file.ChangeType == Copied) && file.OldPath != "" {
+		oldPath = file.OldPath
+	}
+
+	oldName, newName := quotePatchPath("a/"+oldPath), quotePatchPath("b/"+file.Path)
+	lines := []string{"diff --git " + oldName + " " + newName}
+	switch file.ChangeType {
+	case Added:
+		lines = append(lines, "new file mode "+patchFileMode(file.NewMode))
+		oldName = "/dev/null"
+	case Deleted:
+		lines = append(lines, "deleted file mode "+patchFileMode(file.OldMode))
+		newName = "/dev/null"
+	}
+	if file.modeChanged() {
+		lines = append(lines, "old mode "+patchFileMode(file.OldMode), "new mode "+patchFileMode(file.NewMode))
+	}
+	switch file.ChangeType {
+	case Renamed, Copied:
+		if oldPath == file.Path {
+			break
+		}
+		if file.Similarity > 0 {
+			lines = append(lines, fmt.Sprintf("similarity index %d%%", file.Similarity))
+		}
+		verb := "rename"
+		if file.ChangeType == Copied {
+			verb = "copy"
+		}
+		lines = append(lines, verb+" from "+quotePatchPath(oldPath), verb+" to "+quotePatchPath(file.Path))
+	}
+	if len(file.Hunks) == 0 {
+		return lines
+	}
+	lines = append(lines, "--- "+oldName, "+++ "+newName)
+
+	for _, hunk := range file.Hunks {
+		lines = append(lines, formatHunkHeader(hunk))
+		lines = append(lines, patchHunkLines(file, hunk)...)
+	}
+	return lines
+}
+
+// modeChanged reports whether a file that exists on both sides changed mode,
+// which a patch writes as old mode and new mode lines
+func (f FileDiff) modeChanged() bool {
+	return f.ChangeType != Added && f.ChangeType != Deleted &&
+		f.OldMode != filemode.Empty && f.NewMode != filemode.Empty && f.OldMode != f.NewMode
+}
+
+// patchFileMode formats a file mode as git writes it; an unknown mode is
+// written as a regular file
+func patchFileMode(mode filemode.FileMode) string {
+	if mode == filemode.Empty {
+		mode = filemode.Regular
+	}
+	return fmt.Sprintf("%06o", uint32(mode))
+}
+
+// patchHunkLines writes the body of a hunk, marking the last line of a side
+// that has no trailing newline. A context line that ends only one side is
+// written as a removal and an addition, since the two lines differ in their
+// newline.
+func patchHunkLines(file FileDiff, hunk Hunk) []string {
+	lines := make([]string, 0, len(hunk.Lines))
+	for _, line := range hunk.Lines {
+		oldLast := line.Type != LineAdded && line.OldLineNum == file.OldNoNewlineLine
+		newLast := line.Type != LineRemoved && line.NewLineNum == file.NewNoNewlineLine
+
+		switch {
+		case line.Type == LineRemoved:
+			lines = appendPatchLine(lines, "-", line.Content, oldLast)
+		case line.Type == LineAdded:
+			lines = appendPatchLine(lines, "+", line.Content, newLast)
+		case oldLast != newLast:
+			lines = appendPatchLine(lines, "-", line.Content, oldLast)
+			lines = appendPatchLine(lines, "+", line.Content, newLast)
+		default:
+			lines = appendPatchLine(lines, " ", line.Content, oldLast)
+		}
+	}
+	return lines
+}
+
+func appendPatchLine(lines []string, prefix, content string, noNewline bool) []string {
+	lines = append(lines, prefix+content)
+	if noNewline {
+		lines = append(lines, noNewlineMarker)
+	}
+	return lines
+}
+
+// quotePatchPath quotes a path the way git does when it contains characters
+// that would break header parsing
+func quotePatchPath(path string) string {
+	if !strings.ContainsAny(path, "\"\\\t\n") {
+		return path
+	}
+	return strconv.Quote(path)
+}
+
+// runPatch writes the diff selected on the command line to stdout as a patch
+func runPatch(w io.Writer, gitService *GitService, opts cliOptions, pathspec Pathspec, logger *Logger) error {
+	files, err := loadDiffs(gitService, opts, pathspec, logger)
+	if err != nil {
+		return fmt.Errorf("load diff: %w", err)
+	}
+	if err := writePatch(w, files); err != nil {
+		return fmt.Errorf("write patch: %w", err)
+	}
+	return nil
+}
+
+var errNothingToExport = errors.New("no changes to export")
+
+// patchExportedMsg reports a patch written from the TUI
+type patchExportedMsg struct {
+	path  string
+	files int
+}
+
+// exportPatch writes the loaded diffs to path as a patch, with the context
+// size currently shown
+func (m Model) exportPatch(path string) tea.Cmd {
+	files := patchFiles(m.diffFiles)
+	if len(files) == 0 {
+		return func() tea.Msg { return errMsg{errNothingToExport} }
+	}
+	return func() tea.Msg {
+		file, err := os.Create(path)
+		if err != nil {
+			return m.logAndWrapError("create patch file", err, map[string]any{"path": path})
+		}
+		writeErr := writePatch(file, files)
+		if closeErr := file.Close(); writeErr == nil {
+			writeErr = closeErr
+		}
+		if writeErr != nil {
+			return m.logAndWrapError("write patch file", writeErr, map[string]any{"path": path})
+		}
+		return patchExportedMsg{path: path, files: len(files)}
+	}
+}
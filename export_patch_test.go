@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+)
+
+// gitApplyCheck runs git apply --check with args on a patch file in root
+func gitApplyCheck(t *testing.T, root, patch string, args ...string) {
+	t.Helper()
+	patchPath := filepath.Join(t.TempDir(), "export.patch")
+	if err := os.WriteFile(patchPath, []byte(patch), 0o644); err != nil {
+		t.Fatalf("write patch: %v", err)
+	}
+
+	cmd := exec.Command("git", append(append([]string{"apply", "--check"}, args...), patchPath)...)
+	cmd.Dir = root
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git apply --check %s: %v\n%s\npatch:\n%s", strings.Join(args, " "), err, output, patch)
+	}
+}
+
+func TestPatchExportAppliesWithGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	var long strings.Builder
+	for i := 1; i <= 30; i++ {
+		long.WriteString("line " + strings.Repeat("x", i%4) + "\n")
+	}
+	gitService, root := setupTempGitService(t, map[string]string{
+		"src/long.txt":   long.String(),
+		"notes.txt":      "first\nlast",
+		"removed.txt":    "gone\n",
+		"file name.txt":  "spaced\n",
+		"keep/stays.txt": "unchanged\n",
+		"logo.png":       pngHeader,
+	})
+	writeWorktreeFile(t, root, "src/long.txt", strings.Replace(long.String(), "line x\n", "line one\n", 1)+"tail\n")
+	writeWorktreeFile(t, root, "notes.txt", "first\nlast\nappended")
+	writeWorktreeFile(t, root, "file name.txt", "spaced out\n")
+	writeWorktreeFile(t, root, "created.txt", "new\nfile\n")
+	for _, path := range []string{"removed.txt", "keep/stays.txt"} {
+		if err := os.Remove(filepath.Join(root, path)); err != nil {
+			t.Fatalf("remove file: %v", err)
+		}
+	}
+	writeWorktreeFile(t, root, "moved/stays.txt", "unchanged\n")
+	writeWorktreeFile(t, root, "logo.png", pngHeader+"edited")
+
+	for _, contextLines := range []int{1, 3, DefaultDiffContext, WholeFileContext} {
+		var out bytes.Buffer
+		opts := cliOptions{output: outputPatch, contextLines: &contextLines, renames: DefaultRenameOptions}
+		if err := runPatch(&out, gitService, opts, Pathspec{}, newDefaultLogger(ERROR)); err != nil {
+			t.Fatalf("runPatch: %v", err)
+		}
+		patch := out.String()
+		for _, want := range []string{
+			"diff --git a/created.txt b/created.txt\nnew file mode 100644\n--- /dev/null\n+++ b/created.txt\n@@ -0,0 +1,2 @@\n",
+			"diff --git a/removed.txt b/removed.txt\ndeleted file mode 100644\n--- a/removed.txt\n+++ /dev/null\n@@ -1,1 +0,0 @@\n-gone\n",
+			"-last\n" + noNewlineMarker + "\n+last\n+appended\n" + noNewlineMarker + "\n",
+			"diff --git a/keep/stays.txt b/moved/stays.txt\nsimilarity index 100%\nrename from keep/stays.txt\nrename to moved/stays.txt\n",
+		} {
+			if !strings.Contains(patch, want) {
+				t.Errorf("context %d: patch missing %q:\n%s", contextLines, want, patch)
+			}
+		}
+		if strings.Contains(patch, "logo.png") {
+			t.Errorf("context %d: the changed binary file should be left out:\n%s", contextLines, patch)
+		}
+
+		// The patch turns the index into the worktree and back
+		gitApplyCheck(t, root, patch, "--cached")
+		gitApplyCheck(t, root, patch, "-R")
+	}
+}
+
+func TestPatchExportKeepsFileModes(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	gitService, root := setupTempGitService(t, map[string]string{"old.sh": "echo old\n"})
+	if err := os.Chmod(filepath.Join(root, "old.sh"), 0o755); err != nil {
+		t.Fatalf("chmod old.sh: %v", err)
+	}
+	commitAll(t, gitService, "make old.sh executable")
+	if err := os.Remove(filepath.Join(root, "old.sh")); err != nil {
+		t.Fatalf("remove old.sh: %v", err)
+	}
+	writeWorktreeFile(t, root, "new.sh", "echo new\n")
+	if err := os.Chmod(filepath.Join(root, "new.sh"), 0o755); err != nil {
+		t.Fatalf("chmod new.sh: %v", err)
+	}
+
+	var out bytes.Buffer
+	if err := runPatch(&out, gitService, cliOptions{output: outputPatch}, Pathspec{}, newDefaultLogger(ERROR)); err != nil {
+		t.Fatalf("runPatch: %v", err)
+	}
+	patch := out.String()
+	for _, want := range []string{"new file mode 100755\n", "deleted file mode 100755\n"} {
+		if !strings.Contains(patch, want) {
+			t.Errorf("patch missing %q:\n%s", want, patch)
+		}
+	}
+	gitApplyCheck(t, root, patch, "--cached")
+	gitApplyCheck(t, root, patch, "-R")
+}
+
+func TestPatchExportKeepsModeChanges(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	gitService, root := setupTempGitService(t, map[string]string{"run.sh": "echo run\n", "mode.sh": "echo mode\n"})
+	writeWorktreeFile(t, root, "run.sh", "echo ran\n")
+	for _, name := range []string{"run.sh", "mode.sh"} {
+		if err := os.Chmod(filepath.Join(root, name), 0o755); err != nil {
+			t.Fatalf("chmod %s: %v", name, err)
+		}
+	}
+
+	var out bytes.Buffer
+	if err := runPatch(&out, gitService, cliOptions{output: outputPatch}, Pathspec{}, newDefaultLogger(ERROR)); err != nil {
+		t.Fatalf("runPatch: %v", err)
+	}
+	patch := out.String()
+	// The file whose content did not change is kept for its mode
+	for _, want := range []string{"diff --git a/run.sh b/run.sh\nold mode 100644\nnew mode 100755\n", "diff --git a/mode.sh b/mode.sh\nold mode 100644\nnew mode 100755\n"} {
+		if !strings.Contains(patch, want) {
+			t.Errorf("patch missing %q:\n%s", want, patch)
+		}
+	}
+	gitApplyCheck(t, root, patch, "--cached")
+	gitApplyCheck(t, root, patch, "-R")
+
+	files, err := ParsePatch(strings.NewReader(patch))
+	if err != nil || len(files) != 2 || files[0].OldMode != filemode.Regular || files[0].NewMode != filemode.Executable {
+		t.Errorf("parsed modes = %+v, %v", files, err)
+	}
+}
+
+func TestFormatHunkHeaderEmptyRange(t *testing.T) {
+	tests := []struct {
+		hunk Hunk
+		want string
+	}{
+		{Hunk{OldStart: 4, OldCount: 3, NewStart: 4, NewCount: 4}, "@@ -4,3 +4,4 @@"},
+		{Hunk{OldStart: 1, OldCount: 0, NewStart: 1, NewCount: 2}, "@@ -0,0 +1,2 @@"},
+		{Hunk{OldStart: 7, OldCount: 1, NewStart: 7, NewCount: 0}, "@@ -7,1 +6,0 @@"},
+		{Hunk{OldStart: 12, OldCount: 2, NewStart: 12, NewCount: 3, Function: "func main() {"}, "@@ -12,2 +12,3 @@ func main() {"},
+	}
+	for _, tt := range tests {
+		if got := formatHunkHeader(tt.hunk); got != tt.want {
+			t.Errorf("formatHunkHeader(%+v) = %q, want %q", tt.hunk, got, tt.want)
+		}
+	}
+}
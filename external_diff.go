@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+)
+
+var (
+	externalDiffHexPattern  = regexp.MustCompile(`^([0-9a-f]{40}|[0-9a-f]{64}|\.)$`)
+	externalDiffModePattern = regexp.MustCompile(`^([0-7]{6}|\.)$`)
+)
+
+// nullFile is the file git passes for the missing side of an added or deleted file
+const nullFile = "/dev/null"
+
+// externalDiff is one file pair git hands to better_diff as GIT_EXTERNAL_DIFF
+// (path old-file old-hex old-mode new-file new-hex new-mode [new-path info])
+// or as a git difftool -x command (old-file new-file, with the path in $BASE)
+type externalDiff struct {
+	path     string // path in the repository
+	newPath  string // path after a rename, empty otherwise
+	oldFile  string // file holding the old contents, nullFile when added
+	newFile  string // file holding the new contents, nullFile when deleted
+	oldHex   string // blob names, "." or empty when unknown
+	newHex   string
+	oldMode  string // octal file modes, "." or empty when unknown
+	newMode  string
+	unmerged bool // git passes only the path of an unmerged file
+}
+
+// splitExternalDiffArgs recognizes the arguments git passes to an external
+// diff program at the end of args. It returns the options before them.
+func splitExternalDiffArgs(args []string, getenv func(string) string) (externalDiff, []string, bool) {
+	for _, n := range []int{9, 7} {
+		if len(args) < n {
+			continue
+		}
+		tail := args[len(args)-n:]
+		if !externalDiffHexPattern.MatchString(tail[2]) || !externalDiffHexPattern.MatchString(tail[5]) ||
+			!externalDiffModePattern.MatchString(tail[3]) || !externalDiffModePattern.MatchString(tail[6]) {
+			continue
+		}
+		diff := externalDiff{path: tail[0], oldFile: tail[1], oldHex: tail[2], oldMode: tail[3], newFile: tail[4], newHex: tail[5], newMode: tail[6]}
+		if n == 9 && tail[7] != tail[0] {
+			diff.newPath = tail[7]
+		}
+		return diff, args[:len(args)-n], true
+	}
+
+	// The remaining forms are only recognized when git is the caller
+	if getenv("GIT_DIFF_PATH_TOTAL") == "" || len(args) == 0 {
+		return externalDiff{}, args, false
+	}
+	last := args[len(args)-1]
+	if len(args) >= 2 && getenv("BASE") != "" && isDiffableFile(args[len(args)-2]) && isDiffableFile(last) {
+		diff := externalDiff{path: getenv("BASE"), oldFile: args[len(args)-2], newFile: last}
+		return diff, args[:len(args)-2], true
+	}
+	if !strings.HasPrefix(last, "-") {
+		return externalDiff{path: last, unmerged: true}, args[:len(args)-1], true
+	}
+	return externalDiff{}, args, false
+}
+
+func isDiffableFile(name string) bool {
+	return name == nullFile || isPatchFile(name)
+}
+
+// fileDiffs computes the diff of the file pair
+func (d externalDiff) fileDiffs(diffOpts DiffOptions) ([]FileDiff, error) {
+	oldContent, oldExists, err := readExternalDiffFile(d.oldFile)
+	if err != nil {
+		return nil, err
+	}
+	newContent, newExists, err := readExternalDiffFile(d.newFile)
+	if err != nil {
+		return nil, err
+	}
+
+	path := d.path
+	if d.newPath != "" {
+		path = d.newPath
+	}
+	file, err := newFileDiffFromContents(path, oldContent, newContent, oldExists, newExists, false, diffOpts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute diff for %s: %w", path, err)
+	}
+	if file == nil {
+		return []FileDiff{}, nil
+	}
+	// A missing side's mode is "." or 000000, which both give 0
+	file.OldMode, _ = filemode.New(d.oldMode)
+	file.NewMode, _ = filemode.New(d.newMode)
+	if d.newPath != "" {
+		file.ChangeType = Renamed
+		file.OldPath = d.path
+	}
+	return []FileDiff{*file}, nil
+}
+
+func readExternalDiffFile(name string) ([]byte, bool, error) {
+	if name == nullFile {
+		return nil, false, nil
+	}
+	content, err := os.ReadFile(name)
+	if err != nil {
+		return nil, false, fmt.Errorf("read %s: %w", name, err)
+	}
+	return content, true, nil
+}
+
+// diffInput shows the file pair in the TUI; the context size can change
+// since the hunks are computed from the contents
+func (d externalDiff) diffInput() diffInput {
+	title := "External diff: " + d.path
+	if d.newPath != "" {
+		title += " → " + d.newPath
+	}
+	if short := shortBlobRange(d.oldHex, d.newHex); short != "" {
+		title += " (" + short + ")"
+	}
+	return diffInput{label: "External Diff", title: title, compute: d.fileDiffs}
+}
+
+// shortBlobRange formats the blob names git passed like the index line of a patch
+func shortBlobRange(oldHex, newHex string) string {
+	if oldHex == "" && newHex == "" {
+		return ""
+	}
+	short := func(hex string) string {
+		if len(hex) < 7 {
+			return strings.Repeat("0", 7)
+		}
+		return hex[:7]
+	}
+	return short(oldHex) + ".." + short(newHex)
+}
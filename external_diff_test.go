@@ -0,0 +1,110 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const (
+	testOldHex = "1111111111111111111111111111111111111111"
+	testNewHex = "2222222222222222222222222222222222222222"
+)
+
+func TestSplitExternalDiffArgs(t *testing.T) {
+	noEnv := func(string) string { return "" }
+
+	diff, rest, ok := splitExternalDiffArgs([]string{"-U1", "lib/a.go", "/tmp/old", testOldHex, "100644", "lib/a.go", testNewHex, "100644"}, noEnv)
+	if !ok || diff.path != "lib/a.go" || diff.oldFile != "/tmp/old" || diff.newFile != "lib/a.go" || diff.newPath != "" {
+		t.Fatalf("7 args = %+v, %v", diff, ok)
+	}
+	if len(rest) != 1 || rest[0] != "-U1" {
+		t.Errorf("options before git's arguments = %q, want [-U1]", rest)
+	}
+
+	rename := []string{"old.go", "/tmp/a", testOldHex, "100644", "/tmp/b", testNewHex, "100644", "new.go", "similarity index 90%\n"}
+	if diff, _, ok := splitExternalDiffArgs(rename, noEnv); !ok || diff.path != "old.go" || diff.newPath != "new.go" {
+		t.Errorf("9 args = %+v, %v; want a rename to new.go", diff, ok)
+	}
+
+	added := []string{"new.txt", nullFile, ".", ".", "new.txt", testNewHex, "100644"}
+	if diff, _, ok := splitExternalDiffArgs(added, noEnv); !ok || diff.oldFile != nullFile {
+		t.Errorf("added file = %+v, %v", diff, ok)
+	}
+
+	if _, _, ok := splitExternalDiffArgs([]string{"main..", "--", "a", "b", "c", "d", "e"}, noEnv); ok {
+		t.Error("ordinary arguments should not be taken for git's")
+	}
+}
+
+func TestSplitExternalDiffArgsFromGitEnv(t *testing.T) {
+	dir := t.TempDir()
+	oldFile := filepath.Join(dir, "old")
+	newFile := filepath.Join(dir, "new")
+	for _, name := range []string{oldFile, newFile} {
+		if err := os.WriteFile(name, []byte("x\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	env := map[string]string{"GIT_DIFF_PATH_TOTAL": "2", "BASE": "docs/readme.md"}
+	getenv := func(key string) string { return env[key] }
+
+	diff, rest, ok := splitExternalDiffArgs([]string{"--print", oldFile, newFile}, getenv)
+	if !ok || diff.path != "docs/readme.md" || diff.oldFile != oldFile || diff.newFile != newFile || len(rest) != 1 {
+		t.Errorf("difftool args = %+v, %q, %v", diff, rest, ok)
+	}
+
+	diff, _, ok = splitExternalDiffArgs([]string{"conflicted.go"}, getenv)
+	if !ok || !diff.unmerged || diff.path != "conflicted.go" {
+		t.Errorf("unmerged path = %+v, %v", diff, ok)
+	}
+
+	if _, _, ok := splitExternalDiffArgs([]string{oldFile, newFile}, func(string) string { return "" }); ok {
+		t.Error("two files without git's environment should not be taken for difftool arguments")
+	}
+}
+
+func TestExternalDiffFileDiffs(t *testing.T) {
+	dir := t.TempDir()
+	oldFile := filepath.Join(dir, "old")
+	newFile := filepath.Join(dir, "new")
+	if err := os.WriteFile(oldFile, []byte("one\ntwo\nthree\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(newFile, []byte("one\n2\nthree\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := externalDiff{path: "a.txt", oldFile: oldFile, newFile: newFile}.fileDiffs(DiffOptions{})
+	if err != nil || len(files) != 1 {
+		t.Fatalf("fileDiffs = %+v, %v", files, err)
+	}
+	if f := files[0]; f.Path != "a.txt" || f.ChangeType != Modified || f.LinesAdded != 1 || f.LinesRemoved != 1 || len(f.Hunks[0].Lines) != 2 {
+		t.Errorf("modified file = %+v", f)
+	}
+
+	files, err = externalDiff{path: "a.txt", newPath: "b.txt", oldFile: nullFile, newFile: newFile}.fileDiffs(DiffOptions{Context: DefaultDiffContext})
+	if err != nil || len(files) != 1 || files[0].Path != "b.txt" || files[0].OldPath != "a.txt" || files[0].LinesAdded != 3 {
+		t.Errorf("added rename = %+v, %v", files, err)
+	}
+
+	files, err = externalDiff{path: "a.txt", oldFile: newFile, newFile: newFile}.fileDiffs(DiffOptions{Context: DefaultDiffContext})
+	if err != nil || len(files) != 0 {
+		t.Errorf("identical files = %+v, %v; want no diff", files, err)
+	}
+
+	if _, err := (externalDiff{path: "a.txt", oldFile: filepath.Join(dir, "missing"), newFile: newFile}).fileDiffs(DiffOptions{Context: DefaultDiffContext}); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
+
+func TestExternalDiffInputTitle(t *testing.T) {
+	input := externalDiff{path: "old.go", newPath: "new.go", oldHex: testOldHex, newHex: "."}.diffInput()
+	if input.title != "External diff: old.go → new.go (1111111..0000000)" {
+		t.Errorf("title = %q", input.title)
+	}
+	if input := (externalDiff{path: "a.go"}).diffInput(); strings.Contains(input.title, "(") {
+		t.Errorf("title without blob names = %q", input.title)
+	}
+}
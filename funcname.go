@@ -0,0 +1,178 @@
+package main
+
+import (
+	"path"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxFunctionLen is the longest function context shown, in bytes, as in git
+const maxFunctionLen = 80
+
+// funcnamePattern is one line of a funcname driver. A line matching a
+// negative pattern is never a function line; otherwise the first matching
+// pattern names the function with its first group, or the whole match.
+type funcnamePattern struct {
+	re       *regexp.Regexp
+	negative bool
+}
+
+// funcnameDriver finds the lines that start a function, class or section in
+// one language, like the xfuncname drivers built into git
+type funcnameDriver []funcnamePattern
+
+func funcname(pattern string) funcnamePattern {
+	return funcnamePattern{re: regexp.MustCompile(pattern)}
+}
+
+func notFuncname(pattern string) funcnamePattern {
+	return funcnamePattern{re: regexp.MustCompile(pattern), negative: true}
+}
+
+// defaultFuncname is git's rule when no driver applies: a line that starts
+// with a letter, "_" or "$"
+var defaultFuncname = funcnameDriver{funcname(`^[A-Za-z_$].*`)}
+
+var (
+	goFuncname = funcnameDriver{
+		funcname(`^[ \t]*(func[ \t].*)$`),
+		funcname(`^[ \t]*(type[ \t].*(struct|interface)[ \t]*\{?[ \t]*)$`),
+	}
+	pythonFuncname = funcnameDriver{
+		funcname(`^[ \t]*((class|(async[ \t]+)?def)[ \t].*)$`),
+	}
+	javaFuncname = funcnameDriver{
+		notFuncname(`^[ \t]*(catch|do|for|if|instanceof|new|return|switch|throw|while)\b`),
+		funcname(`^[ \t]*(([a-z]+[ \t]+)*(class|enum|interface|record)[ \t]+.*)$`),
+		funcname(`^[ \t]*(([A-Za-z_][\]\[?&<>.,A-Za-z_0-9]*[ \t]+)+[A-Za-z_][A-Za-z_0-9]*[ \t]*\([^;]*)$`),
+	}
+	cFuncname = funcnameDriver{
+		// goto labels and access specifiers
+		notFuncname(`^[ \t]*[A-Za-z_][A-Za-z_0-9]*:[ \t]*($|/[/*])`),
+		funcname(`^((::[ \t]*)?[A-Za-z_].*)$`),
+	}
+	rustFuncname = funcnameDriver{
+		funcname(`^[\t ]*((pub(\([^\)]+\))?[\t ]+)?((async|const|unsafe|extern([\t ]+"[^"]+"))[\t ]+)?(struct|enum|union|mod|trait|fn|impl|macro_rules!)[<\t ]+[^;]*)$`),
+	}
+	jsFuncname = funcnameDriver{
+		notFuncname(`^[ \t]*(if|for|while|switch|catch|return)\b`),
+		funcname(`^[ \t]*((export[ \t]+)?(default[ \t]+)?(async[ \t]+)?function\b.*)$`),
+		funcname(`^[ \t]*((export[ \t]+)?(default[ \t]+)?(abstract[ \t]+)?(class|interface)[ \t].*)$`),
+		funcname(`^[ \t]*((export[ \t]+)?(const|let|var)[ \t]+[A-Za-z_$][A-Za-z0-9_$]*[ \t]*=[ \t]*(async[ \t]*)?(\([^)]*\)|[A-Za-z_$][A-Za-z0-9_$]*)[ \t]*=>.*)$`),
+		funcname(`^[ \t]*(((public|private|protected|static|async|get|set)[ \t]+)*[A-Za-z_$][A-Za-z0-9_$]*[ \t]*\([^)]*\)[ \t]*(:[^{]*)?\{[ \t]*)$`),
+	}
+	rubyFuncname = funcnameDriver{
+		funcname(`^[ \t]*((class|module|def)[ \t].*)$`),
+	}
+	phpFuncname = funcnameDriver{
+		funcname(`^[\t ]*(((public|protected|private|static|abstract|final)[\t ]+)*function.*)$`),
+		funcname(`^[\t ]*((((final|abstract)[\t ]+)?class|enum|interface|trait).*)$`),
+	}
+	csharpFuncname = funcnameDriver{
+		notFuncname(`^[ \t]*(do|while|for|foreach|if|else|instanceof|new|return|switch|case|throw|catch|using)\b`),
+		funcname(`^[ \t]*(([A-Za-z_][\]\[A-Za-z_0-9<>,.?]*[ \t]+)*(class|enum|interface|struct|record|namespace)[ \t]+.*)$`),
+		funcname(`^[ \t]*(([A-Za-z_][\]\[A-Za-z_0-9<>,.?]*[ \t]+)+[A-Za-z_][A-Za-z_0-9]*[ \t]*\(.*)$`),
+	}
+	kotlinFuncname = funcnameDriver{
+		funcname(`^[ \t]*(([a-z]+[ \t]+)*(fun|class|interface|object)[ \t]+.*)$`),
+	}
+	shellFuncname = funcnameDriver{
+		funcname(`^[ \t]*((function[ \t]+)?[A-Za-z_][A-Za-z_0-9]*[ \t]*\(\)[ \t]*(\{.*)?|function[ \t]+[A-Za-z_][A-Za-z_0-9]*.*)$`),
+	}
+	markdownFuncname = funcnameDriver{
+		funcname(`^ {0,3}#{1,6}[ \t].*`),
+	}
+	cssFuncname = funcnameDriver{
+		notFuncname(`[:;][ \t]*$`),
+		funcname(`^[:\[@.#]?[_a-zA-Z0-9].*$`),
+	}
+)
+
+// funcnameDrivers maps file extensions to their driver
+var funcnameDrivers = map[string]funcnameDriver{
+	".go":   goFuncname,
+	".py":   pythonFuncname,
+	".java": javaFuncname,
+	".c":    cFuncname, ".h": cFuncname, ".cc": cFuncname, ".cpp": cFuncname, ".cxx": cFuncname, ".hpp": cFuncname, ".hh": cFuncname,
+	".m":  cFuncname,
+	".rs": rustFuncname,
+	".js": jsFuncname, ".jsx": jsFuncname, ".mjs": jsFuncname, ".cjs": jsFuncname, ".ts": jsFuncname, ".tsx": jsFuncname,
+	".rb":  rubyFuncname,
+	".php": phpFuncname,
+	".cs":  csharpFuncname,
+	".kt":  kotlinFuncname, ".kts": kotlinFuncname,
+	".sh": shellFuncname, ".bash": shellFuncname, ".zsh": shellFuncname,
+	".md": markdownFuncname, ".markdown": markdownFuncname,
+	".css": cssFuncname, ".scss": cssFuncname, ".less": cssFuncname,
+}
+
+// funcnameDriverFor returns the driver for a file, by its extension
+func funcnameDriverFor(filePath string) funcnameDriver {
+	if driver, ok := funcnameDrivers[strings.ToLower(path.Ext(filePath))]; ok {
+		return driver
+	}
+	return defaultFuncname
+}
+
+// match returns the function named by line, if it starts one
+func (d funcnameDriver) match(line string) (string, bool) {
+	for _, pattern := range d {
+		match := pattern.re.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		if pattern.negative {
+			return "", false
+		}
+		if len(match) > 1 {
+			return match[1], true
+		}
+		return match[0], true
+	}
+	return "", false
+}
+
+// annotateHunkFunctions sets the function of each hunk: the nearest line
+// above the hunk in oldLines that starts a function, as git shows after the
+// ranges of a hunk header
+func annotateHunkFunctions(filePath string, oldLines []string, hunks []Hunk) {
+	driver := funcnameDriverFor(filePath)
+	for i := range hunks {
+		hunks[i].Function = ""
+		// OldStart is the first old line of the hunk, or the line after an
+		// insertion; the search starts on the line before it
+		for lineIdx := min(hunks[i].OldStart-2, len(oldLines)-1); lineIdx >= 0; lineIdx-- {
+			if function, ok := driver.match(oldLines[lineIdx]); ok {
+				hunks[i].Function = truncateFunction(function)
+				break
+			}
+		}
+	}
+}
+
+// truncateFunction trims trailing space and cuts the function context to
+// maxFunctionLen bytes without splitting a character
+func truncateFunction(function string) string {
+	function = strings.TrimRight(function, " \t\r")
+	if len(function) <= maxFunctionLen {
+		return function
+	}
+	cut := maxFunctionLen
+	for cut > 0 && !utf8.RuneStart(function[cut]) {
+		cut--
+	}
+	return strings.TrimRight(function[:cut], " \t")
+}
+
+// computeFileHunks diffs two versions of the file at filePath and finds the
+// function each hunk is in
+func computeFileHunks(filePath string, oldContent, newContent []byte, opts DiffOptions) ([]Hunk, error) {
+	oldLines := splitLines(string(oldContent))
+	hunks, err := computeHunksWithContext(oldLines, splitLines(string(newContent)), opts)
+	if err != nil {
+		return nil, err
+	}
+	annotateHunkFunctions(filePath, oldLines, hunks)
+	return hunks, nil
+}
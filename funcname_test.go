@@ -0,0 +1,120 @@
+package main
+
+import (
+	"os/exec"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestFuncnameDrivers(t *testing.T) {
+	tests := []struct {
+		path, line, want string
+	}{
+		{"main.go", "func (m Model) View() string {", "func (m Model) View() string {"},
+		{"main.go", "type Model struct {", "type Model struct {"},
+		{"main.go", "\treturn nil", ""},
+		{"app.py", "    async def fetch(self):", "async def fetch(self):"},
+		{"app.py", "class Handler(Base):", "class Handler(Base):"},
+		{"App.java", "    public static void main(String[] args) {", "public static void main(String[] args) {"},
+		{"App.java", "    return compute(x);", ""},
+		{"app.c", "static int parse(const char *s)", "static int parse(const char *s)"},
+		{"app.c", "out:", ""},
+		{"lib.rs", "pub(crate) async fn run() -> Result<()> {", "pub(crate) async fn run() -> Result<()> {"},
+		{"app.ts", "export const load = async (id) => {", "export const load = async (id) => {"},
+		{"app.ts", "  if (ready) {", ""},
+		{"README.md", "## Usage", "## Usage"},
+		{"README.md", "Some text", ""},
+		{"build.sh", "deploy() {", "deploy() {"},
+		{"notes.txt", "Chapter one", "Chapter one"},
+		{"notes.txt", "  indented", ""},
+	}
+	for _, tt := range tests {
+		got, ok := funcnameDriverFor(tt.path).match(tt.line)
+		if got != tt.want || ok != (tt.want != "") {
+			t.Errorf("match(%s, %q) = %q, %v; want %q", tt.path, tt.line, got, ok, tt.want)
+		}
+	}
+}
+
+func TestAnnotateHunkFunctions(t *testing.T) {
+	oldLines := []string{"package main", "", "func first() {", "\ta := 1", "\tb := 2", "}", "", "func second() {", "\treturn", "}"}
+	hunks := []Hunk{
+		{OldStart: 1, OldCount: 2},  // nothing above the first line
+		{OldStart: 3, OldCount: 1},  // the function line itself is not its context, nor is the package clause
+		{OldStart: 5, OldCount: 1},  // inside first
+		{OldStart: 10, OldCount: 0}, // insertion after the closing brace of second
+	}
+	annotateHunkFunctions("main.go", oldLines, hunks)
+	want := []string{"", "", "func first() {", "func second() {"}
+	for i, hunk := range hunks {
+		if hunk.Function != want[i] {
+			t.Errorf("hunk %d function = %q, want %q", i, hunk.Function, want[i])
+		}
+	}
+
+	long := "func " + strings.Repeat("é", 60) + "() {"
+	annotateHunkFunctions("main.go", []string{long, "\tx()"}, hunks[:1])
+	if hunks[0].Function != "" {
+		t.Errorf("hunk at line 1 function = %q", hunks[0].Function)
+	}
+	hunks[0].OldStart = 2
+	annotateHunkFunctions("main.go", []string{long, "\tx()"}, hunks[:1])
+	if got := hunks[0].Function; len(got) > maxFunctionLen || !strings.HasPrefix(long, got) || !strings.HasSuffix(got, "é") {
+		t.Errorf("long function = %q (%d bytes)", got, len(got))
+	}
+}
+
+// hunkHeaderLines returns the hunk headers of a patch
+func hunkHeaderLines(patch string) []string {
+	return regexp.MustCompile(`(?m)^@@ .*$`).FindAllString(patch, -1)
+}
+
+func TestHunkHeadersMatchGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	goSource := "package main\n\nimport \"fmt\"\n\n" +
+		"type Config struct {\n\tName string\n\tSize int\n\tDebug bool\n\tPath string\n}\n\n" +
+		"func (c Config) String() string {\n\tname := c.Name\n\tsize := c.Size\n\tpath := c.Path\n\treturn fmt.Sprint(name, size, path)\n}\n\n" +
+		"func main() {\n\tfmt.Println(1)\n\tfmt.Println(2)\n\tfmt.Println(3)\n\tfmt.Println(4)\n}\n"
+	markdown := "# Title\n\nIntro text\n\n## Install\n\nStep one\nStep two\nStep three\nStep four\n"
+	gitService, root := setupTempGitService(t, map[string]string{
+		".gitattributes": "*.go diff=golang\n*.md diff=markdown\n",
+		"main.go":        goSource,
+		"README.md":      markdown,
+		"notes.txt":      "Heading\n  a\n  b\n  c\n  d\n  e\n",
+	})
+	goChanged := strings.NewReplacer("\tPath string", "\tPath string\n\tMode int", "size := c.Size", "size := c.Size * 2", "fmt.Println(4)", "fmt.Println(5)").Replace(goSource)
+	writeWorktreeFile(t, root, "main.go", goChanged)
+	writeWorktreeFile(t, root, "README.md", strings.Replace(markdown, "Step four", "Step 4", 1))
+	writeWorktreeFile(t, root, "notes.txt", "Heading\n  a\n  b\n  c\n  d\n  E\n")
+
+	files, err := gitService.GetDiffWithContext(Unstaged, DiffOnly, DiffOptions{Context: 1}, Pathspec{}, newDefaultLogger(ERROR))
+	if err != nil {
+		t.Fatalf("GetDiffWithContext: %v", err)
+	}
+	if len(files) != 3 {
+		t.Fatalf("got %d changed files, want 3", len(files))
+	}
+	for _, file := range files {
+		cmd := exec.Command("git", "diff", "--no-color", "-U1", "--", file.Path)
+		cmd.Dir = root
+		output, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("git diff %s: %v", file.Path, err)
+		}
+		want := hunkHeaderLines(string(output))
+		var got []string
+		for _, hunk := range file.Hunks {
+			got = append(got, formatHunkHeader(hunk))
+		}
+		if strings.Join(got, "\n") != strings.Join(want, "\n") {
+			t.Errorf("%s hunk headers:\n%s\nwant, as git writes them:\n%s", file.Path, strings.Join(got, "\n"), strings.Join(want, "\n"))
+		}
+		if file.Path == "main.go" && !strings.HasSuffix(got[len(got)-1], "@@ func main() {") {
+			t.Errorf("last main.go hunk should be in main: %q", got)
+		}
+	}
+}
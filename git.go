@@ -2,6 +2,8 @@ package main
 
 import (
 	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/filemode"
 )
 
 // ChangeType represents the type of change
@@ -12,25 +14,84 @@ const (
 	Added
 	Deleted
 	Renamed
+	Copied
+)
+
+// ChangeKind is how something within a file changed: a Go declaration, or a
+// value of a JSON or YAML file
+type ChangeKind int
+
+const (
+	KindAdded ChangeKind = iota
+	KindRemoved
+	KindModified
 )
 
+func (k ChangeKind) String() string {
+	switch k {
+	case KindAdded:
+		return "added"
+	case KindRemoved:
+		return "removed"
+	default:
+		return "modified"
+	}
+}
+
+// Symbol is the mark of the change in the declaration and structural summaries
+func (k ChangeKind) Symbol() string {
+	switch k {
+	case KindAdded:
+		return "+"
+	case KindRemoved:
+		return "-"
+	default:
+		return "~"
+	}
+}
+
 // FileDiff represents a file with its changes
 type FileDiff struct {
 	Path         string
-	OldPath      string // for renames
+	OldPath      string // for renames and copies
+	Similarity   int    // percent of the content shared with OldPath
 	ChangeType   ChangeType
 	Hunks        []Hunk
 	LinesAdded   int
 	LinesRemoved int
+	// Number of the last old/new line when that content does not end with a
+	// newline, 0 otherwise. Patch export marks it "\ No newline at end of file".
+	OldNoNewlineLine int
+	NewNoNewlineLine int
+	// Modes of the old and new file, 0 for a missing side or when unknown
+	OldMode, NewMode filemode.FileMode
+	Binary           *BinaryInfo     // set for binary files, which have no hunks
+	Decls            []DeclChange    // Go declarations changed, with the semantic summary on
+	Structure        *StructuralDiff // JSON and YAML values changed, with the structural view on
+}
+
+// displayPath returns the path shown for the file: "old → new" for a
+// renamed or copied file
+func (f FileDiff) displayPath() string {
+	if (f.ChangeType == Renamed || f.ChangeType == Copied) && f.OldPath != "" && f.OldPath != f.Path {
+		return f.OldPath + " → " + f.Path
+	}
+	return f.Path
 }
 
 // Commit represents a git commit
 type Commit struct {
-	Hash      string
-	ShortHash string
-	Author    string
-	Message   string
-	Date      string
+	Hash        string
+	ShortHash   string
+	Author      string
+	Email       string
+	Message     string // first line of the message
+	FullMessage string
+	Date        string
+	// Path of the file a file history follows in this commit, and the path
+	// it had in the parent when the commit renamed it
+	Path    string
+	OldPath string
 }
 
 // Hunk represents a section of changes
@@ -39,6 +100,7 @@ type Hunk struct {
 	OldCount int
 	NewStart int
 	NewCount int
+	Function string // Enclosing function or section, shown after the ranges
 	Lines    []DiffLine
 }
 
@@ -48,6 +110,7 @@ type DiffLine struct {
 	Content    string
 	OldLineNum int // Line number in the old file (0 if added)
 	NewLineNum int // Line number in the new file (0 if removed)
+	MoveID     int // Shared by the removed and added copies of a moved block (0 if not moved)
 }
 
 // LineType represents the type of line in a diff
@@ -66,16 +129,33 @@ const (
 	Unstaged DiffMode = iota
 	Staged
 	BranchCompare
+	RefCompare // revisions given on the command line
+	InputDiff  // diff given on the command line: a patch, or files from git's external diff
+	CommitLog  // history of a ref, showing the diff of the chosen commit
 )
 
+// loadsAllDiffs reports whether the mode diffs commits or shows a given diff
+// rather than the index, so every file diff is loaded up front and the file list
+// derives from it.
+func (mode DiffMode) loadsAllDiffs() bool {
+	return mode == BranchCompare || mode == RefCompare || mode == InputDiff || mode == CommitLog
+}
+
 // DiffViewMode represents how much context to show in diff
 type DiffViewMode int
 
 const (
 	DiffOnly DiffViewMode = iota
 	WholeFile
+	SideBySide
 )
 
+// showsHunks reports whether the view mode renders hunks with limited context
+// (as opposed to the whole file).
+func (mode DiffViewMode) showsHunks() bool {
+	return mode != WholeFile
+}
+
 const (
 	// DefaultDiffContext is the default number of context lines in diff
 	DefaultDiffContext = 5
@@ -88,7 +168,7 @@ const (
 // computeHunks computes hunks with default grouping behavior and normalizes
 // hunk headers to start at the first changed line.
 func computeHunks(oldLines, newLines []string) ([]Hunk, error) {
-	hunks, err := computeHunksWithContext(oldLines, newLines, DefaultDiffContext)
+	hunks, err := computeHunksWithContext(oldLines, newLines, DiffOptions{Context: DefaultDiffContext})
 	if err != nil {
 		return nil, err
 	}
@@ -102,14 +182,21 @@ func computeHunks(oldLines, newLines []string) ([]Hunk, error) {
 	return normalized, nil
 }
 
-// computeHunksWithContext computes diff hunks using Myers diff algorithm with a custom context size.
-func computeHunksWithContext(oldLines, newLines []string, contextLines int) ([]Hunk, error) {
-	return computeHunksWithDifflib(oldLines, newLines, contextLines)
+// computeHunksWithContext computes diff hunks with the algorithm, context
+// size and whitespace handling of opts.
+func computeHunksWithContext(oldLines, newLines []string, opts DiffOptions) ([]Hunk, error) {
+	contextLines := max(0, opts.Context)
+	lineDiffs := computeLineDiffs(opts, oldLines, newLines)
+	if opts.IgnoreBlankLines {
+		lineDiffs = hideBlankLineChanges(lineDiffs, contextLines, opts.Whitespace)
+	}
+	return buildHunks(lineDiffs, contextLines), nil
 }
 
 type lineDiff struct {
-	Type  diffOp
-	Lines []string
+	Type   diffOp
+	Lines  []string
+	hidden bool // left out of the hunks by --ignore-blank-lines
 }
 
 type diffOp int
@@ -158,6 +245,10 @@ func buildHunks(lineDiffs []lineDiff, contextLines int) []Hunk {
 }
 
 func (b *hunkBuilder) process(diff lineDiff) {
+	if diff.hidden {
+		b.processHiddenLines(diff)
+		return
+	}
 	switch diff.Type {
 	case diffEqual:
 		b.processEqualLines(diff.Lines)
@@ -216,6 +307,21 @@ func (b *hunkBuilder) processAddedLines(lines []string) {
 	}
 }
 
+// processHiddenLines skips lines that are not shown: they end the current
+// hunk, and the next one starts after them
+func (b *hunkBuilder) processHiddenLines(diff lineDiff) {
+	if b.currentHunk != nil {
+		b.finalize()
+		b.currentHunk = nil
+	}
+	if diff.Type == diffDelete {
+		b.oldLineNum += len(diff.Lines)
+	} else {
+		b.newLineNum += len(diff.Lines)
+	}
+	b.pendingContext = b.pendingContext[:0]
+}
+
 func (b *hunkBuilder) ensureCurrentHunk(diffLineCount int) {
 	if b.currentHunk != nil {
 		return
@@ -230,9 +336,12 @@ func (b *hunkBuilder) ensureCurrentHunk(diffLineCount int) {
 	b.pendingContext = b.pendingContext[:0]
 }
 
+// maybeCloseCurrentHunk closes the hunk once more equal lines follow it than
+// its trailing context and the next hunk's leading context need, so that, as
+// in git, hunks never overlap
 func (b *hunkBuilder) maybeCloseCurrentHunk(equalLines []string) {
 	trailingContext := countTrailingContextLines(b.currentHunk.Lines)
-	if trailingContext < b.contextLines || !hunkHasChanges(b.currentHunk.Lines) {
+	if trailingContext <= 2*b.contextLines || !hunkHasChanges(b.currentHunk.Lines) {
 		return
 	}
 
@@ -369,6 +478,15 @@ func joinLinesForDiff(lines []string) string {
 	return strings.Join(lines, "\n") + "\n"
 }
 
+// missingNewlineLine returns the number of the last line of content when it
+// does not end with a newline, 0 otherwise
+func missingNewlineLine(content []byte) int {
+	if endsWithNewline(content) {
+		return 0
+	}
+	return len(splitLines(string(content)))
+}
+
 // splitLines splits content by newline and normalizes the result
 // It removes the trailing empty string that results from splitting text with a trailing newline
 // For example: "a\nb\n" -> ["a", "b"] instead of ["a", "b", ""]
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DiffAlgorithm selects how the lines of two files are matched
+type DiffAlgorithm int
+
+const (
+	AlgorithmMyers     DiffAlgorithm = iota // minimal diff, git's default and the zero value
+	AlgorithmPatience                       // anchors on lines unique to both sides
+	AlgorithmHistogram                      // anchors on the rarest common lines, as git diff --histogram
+	AlgorithmDifflib                        // Python difflib SequenceMatcher heuristics
+)
+
+var diffAlgorithmNames = []string{"myers", "patience", "histogram", "difflib"}
+
+func (a DiffAlgorithm) String() string {
+	if a < 0 || int(a) >= len(diffAlgorithmNames) {
+		return fmt.Sprintf("DiffAlgorithm(%d)", int(a))
+	}
+	return diffAlgorithmNames[a]
+}
+
+// Label returns the name shown in the UI
+func (a DiffAlgorithm) Label() string {
+	name := a.String()
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+// Next returns the algorithm after a, wrapping around
+func (a DiffAlgorithm) Next() DiffAlgorithm {
+	return (a + 1) % DiffAlgorithm(len(diffAlgorithmNames))
+}
+
+// parseDiffAlgorithm parses a --diff-algorithm value. As in git, "default" and
+// "minimal" are Myers, which always finds a minimal diff here.
+func parseDiffAlgorithm(name string) (DiffAlgorithm, error) {
+	switch strings.ToLower(name) {
+	case "default", "minimal":
+		return AlgorithmMyers, nil
+	}
+	for i, known := range diffAlgorithmNames {
+		if strings.EqualFold(name, known) {
+			return DiffAlgorithm(i), nil
+		}
+	}
+	return AlgorithmMyers, fmt.Errorf("invalid diff algorithm %q: want myers, minimal, patience, histogram or difflib", name)
+}
+
+// differ returns the implementation of the algorithm
+func (a DiffAlgorithm) differ() lineDiffer {
+	switch a {
+	case AlgorithmPatience:
+		return patienceDiffer{}
+	case AlgorithmHistogram:
+		return histogramDiffer{}
+	case AlgorithmDifflib:
+		return difflibDiffer{}
+	default:
+		return myersDiffer{}
+	}
+}
+
+// DiffOptions controls how hunks are computed
+type DiffOptions struct {
+	Context          int            // context lines around changes
+	Algorithm        DiffAlgorithm  // line matching algorithm
+	Whitespace       WhitespaceMode // whitespace differences ignored when comparing lines
+	IgnoreBlankLines bool           // hide changes that only add or remove blank lines
+	Renames          RenameOptions  // pairing of deleted and added files
+	Semantic         bool           // summarize the changed declarations of Go files
+	Structural       bool           // compare JSON and YAML files by value
+}
+
+// exact reports whether hunks list every difference between the files, so
+// they can be applied back to them
+func (o DiffOptions) exact() bool {
+	return o.Whitespace == WhitespaceExact && !o.IgnoreBlankLines
+}
+
+// forView returns the options for a view mode; Whole File shows every line
+func (o DiffOptions) forView(viewMode DiffViewMode) DiffOptions {
+	o.Context = effectiveContextLines(viewMode, o.Context)
+	return o
+}
+
+// lineDiffer is a diff algorithm: it adds the pairs of equal lines the diff
+// keeps to lm.matches, in increasing order on both sides. Everything else is
+// removed or added.
+type lineDiffer interface {
+	match(lm *lineMatcher)
+}
+
+// lineMatch pairs an old line index with the equal new line index
+type lineMatch struct {
+	old int
+	new int
+}
+
+// computeLineDiffs runs the algorithm on the lines as normalized for the
+// whitespace mode, shifts the changes to where git shows them and groups the
+// result into runs of equal, removed and added lines. Equal lines are taken
+// from the new side, as git shows them when whitespace is ignored.
+func computeLineDiffs(opts DiffOptions, oldLines, newLines []string) []lineDiff {
+	lm := newLineMatcher(opts.Whitespace.normalizeLines(oldLines), opts.Whitespace.normalizeLines(newLines))
+	opts.Algorithm.differ().match(lm)
+
+	oldSide, newSide := lm.sides(oldLines, newLines)
+	compactChanges(oldSide, newSide)
+	compactChanges(newSide, oldSide)
+
+	lineDiffs := make([]lineDiff, 0, 2*len(lm.matches)+2)
+	i, j := 0, 0
+	for i < len(oldLines) || j < len(newLines) {
+		oldStart, newStart := i, j
+		for i < len(oldLines) && oldSide.changed[i] {
+			i++
+		}
+		for j < len(newLines) && newSide.changed[j] {
+			j++
+		}
+		if oldStart < i {
+			lineDiffs = appendMergedLineDiff(lineDiffs, diffDelete, oldLines[oldStart:i:i])
+		}
+		if newStart < j {
+			lineDiffs = appendMergedLineDiff(lineDiffs, diffInsert, newLines[newStart:j:j])
+		}
+		if i < len(oldLines) && j < len(newLines) {
+			lineDiffs = appendMergedLineDiff(lineDiffs, diffEqual, newLines[j:j+1:j+1])
+			i++
+			j++
+		}
+	}
+	return lineDiffs
+}
+
+// lineMatcher holds the compared form of two files with their lines interned
+// to numbers, so the algorithms compare ints, and collects the matches they find
+type lineMatcher struct {
+	oldKeys, newKeys []string
+	a, b             []int
+	matches          []lineMatch
+}
+
+func newLineMatcher(oldKeys, newKeys []string) *lineMatcher {
+	ids := make(map[string]int, len(oldKeys))
+	intern := func(lines []string) []int {
+		out := make([]int, len(lines))
+		for i, line := range lines {
+			id, ok := ids[line]
+			if !ok {
+				id = len(ids)
+				ids[line] = id
+			}
+			out[i] = id
+		}
+		return out
+	}
+	a := intern(oldKeys)
+	return &lineMatcher{oldKeys: oldKeys, newKeys: newKeys, a: a, b: intern(newKeys)}
+}
+
+func (lm *lineMatcher) add(oldIdx, newIdx, count int) {
+	for i := 0; i < count; i++ {
+		lm.matches = append(lm.matches, lineMatch{old: oldIdx + i, new: newIdx + i})
+	}
+}
+
+// sides returns both files with every line the matches leave out marked
+// changed. The indent heuristic measures the original lines.
+func (lm *lineMatcher) sides(oldLines, newLines []string) (*diffSide, *diffSide) {
+	oldSide := &diffSide{ids: lm.a, lines: oldLines, changed: make([]bool, len(lm.a))}
+	newSide := &diffSide{ids: lm.b, lines: newLines, changed: make([]bool, len(lm.b))}
+	for i := range oldSide.changed {
+		oldSide.changed[i] = true
+	}
+	for j := range newSide.changed {
+		newSide.changed[j] = true
+	}
+	for _, match := range lm.matches {
+		oldSide.changed[match.old] = false
+		newSide.changed[match.new] = false
+	}
+	return oldSide, newSide
+}
+
+// trimCommon matches the common prefix of a[aLo:aHi] and b[bLo:bHi] and
+// returns the ranges left without it and without the common suffix, and the
+// length of that suffix. Callers add the suffix after the middle.
+func (lm *lineMatcher) trimCommon(aLo, aHi, bLo, bHi int) (int, int, int, int, int) {
+	prefix := 0
+	for aLo+prefix < aHi && bLo+prefix < bHi && lm.a[aLo+prefix] == lm.b[bLo+prefix] {
+		prefix++
+	}
+	lm.add(aLo, bLo, prefix)
+	aLo, bLo = aLo+prefix, bLo+prefix
+
+	suffix := 0
+	for aLo < aHi-suffix && bLo < bHi-suffix && lm.a[aHi-1-suffix] == lm.b[bHi-1-suffix] {
+		suffix++
+	}
+	return aLo, aHi - suffix, bLo, bHi - suffix, suffix
+}
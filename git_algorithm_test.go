@@ -0,0 +1,276 @@
+package main
+
+import (
+	"math/rand"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// Bram Cohen's example: a function is added above one that changes, and
+// another is removed
+var (
+	patienceExampleOld = strings.Split(`#include <stdio.h>
+
+// Frobs foo heartily
+int frobnitz(int foo)
+{
+    int i;
+    for(i = 0; i < 10; i++)
+    {
+        printf("Your answer is: ");
+        printf("%d\n", foo);
+    }
+}
+
+int fact(int n)
+{
+    if(n > 1)
+    {
+        return fact(n-1) * n;
+    }
+    return 1;
+}
+
+int main(int argc, char **argv)
+{
+    frobnitz(fact(10));
+}`, "\n")
+	patienceExampleNew = strings.Split(`#include <stdio.h>
+
+int fib(int n)
+{
+    if(n > 2)
+    {
+        return fib(n-1) + fib(n-2);
+    }
+    return 1;
+}
+
+// Frobs foo heartily
+int frobnitz(int foo)
+{
+    int i;
+    for(i = 0; i < 10; i++)
+    {
+        printf("%d\n", foo);
+    }
+}
+
+int main(int argc, char **argv)
+{
+    frobnitz(fib(10));
+}`, "\n")
+)
+
+var (
+	histogramExampleOld = strings.Split(`public class File1 {
+
+  public int add (int a, int b)
+  {
+    log();
+    return a + b;
+  }
+
+  public int sub (int a, int b)
+  {
+    if (a == b)
+    {
+        return 0;
+    }
+    log();
+    return a - b;
+  }
+
+}`, "\n")
+	histogramExampleNew = strings.Split(`public class File1 {
+
+  public int sub (int a, int b)
+  {
+    // TODO: JIRA1234
+    if ( isNull(a, b) )
+    {
+        return null
+    }
+    log();
+    return a - b;
+  }
+
+  public int mul (int a, int b)
+  {
+    if ( isNull(a, b) )
+    {
+        return null;
+    }
+    log();
+    return a * b;
+  }
+
+}`, "\n")
+)
+
+var allAlgorithms = []DiffAlgorithm{AlgorithmMyers, AlgorithmPatience, AlgorithmHistogram, AlgorithmDifflib}
+
+// replayLineDiffs rebuilds both files from line diffs
+func replayLineDiffs(lineDiffs []lineDiff) (oldLines, newLines []string) {
+	for _, d := range lineDiffs {
+		if d.Type != diffInsert {
+			oldLines = append(oldLines, d.Lines...)
+		}
+		if d.Type != diffDelete {
+			newLines = append(newLines, d.Lines...)
+		}
+	}
+	return oldLines, newLines
+}
+
+func changedLineCount(lineDiffs []lineDiff) int {
+	count := 0
+	for _, d := range lineDiffs {
+		if d.Type != diffEqual {
+			count += len(d.Lines)
+		}
+	}
+	return count
+}
+
+// lcsLength is the textbook dynamic program, for checking minimality
+func lcsLength(a, b []string) int {
+	row := make([]int, len(b)+1)
+	for i := range a {
+		diag := 0
+		for j := range b {
+			next := row[j+1]
+			if a[i] == b[j] {
+				row[j+1] = diag + 1
+			} else {
+				row[j+1] = max(row[j+1], row[j])
+			}
+			diag = next
+		}
+	}
+	return row[len(b)]
+}
+
+func randomEdit(rng *rand.Rand, alphabet []string) ([]string, []string) {
+	oldLines := make([]string, rng.Intn(40))
+	for i := range oldLines {
+		oldLines[i] = alphabet[rng.Intn(len(alphabet))]
+	}
+	newLines := slices.Clone(oldLines)
+	for edits := 1 + rng.Intn(10); edits > 0; edits-- {
+		switch pos := rng.Intn(len(newLines) + 1); {
+		case rng.Intn(2) == 0 && pos < len(newLines):
+			newLines = slices.Delete(newLines, pos, pos+1)
+		default:
+			newLines = slices.Insert(newLines, pos, alphabet[rng.Intn(len(alphabet))])
+		}
+	}
+	return oldLines, newLines
+}
+
+func TestLineDiffsReplayToInputs(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	alphabet := []string{"a", "b", "c", "{", "}", "", "  x", "\ty"}
+	for i := 0; i < 500; i++ {
+		oldLines, newLines := randomEdit(rng, alphabet)
+		lcs := lcsLength(oldLines, newLines)
+		for _, algorithm := range allAlgorithms {
+			lineDiffs := computeLineDiffs(DiffOptions{Algorithm: algorithm}, oldLines, newLines)
+			gotOld, gotNew := replayLineDiffs(lineDiffs)
+			if !slices.Equal(gotOld, oldLines) || !slices.Equal(gotNew, newLines) {
+				t.Fatalf("%s: %q -> %q replays to %q -> %q", algorithm, oldLines, newLines, gotOld, gotNew)
+			}
+			if algorithm == AlgorithmMyers && changedLineCount(lineDiffs) != len(oldLines)+len(newLines)-2*lcs {
+				t.Fatalf("myers diff of %q -> %q is not minimal: %+v", oldLines, newLines, lineDiffs)
+			}
+		}
+	}
+}
+
+func TestAlgorithmsOnHistogramExample(t *testing.T) {
+	// Myers matches the braces and blank lines of add() against sub(), so
+	// sub() shows as rewritten; the other algorithms keep its signature as
+	// context
+	const signature = "  public int sub (int a, int b)"
+	for _, algorithm := range allAlgorithms {
+		hunks, _ := computeHunksWithContext(histogramExampleOld, histogramExampleNew, DiffOptions{Algorithm: algorithm})
+		kept := true
+		for _, hunk := range hunks {
+			for _, line := range hunk.Lines {
+				if line.Content == signature {
+					kept = false
+				}
+			}
+		}
+		if want := algorithm != AlgorithmMyers; kept != want {
+			t.Errorf("%s: sub() signature kept as context = %v, want %v", algorithm, kept, want)
+		}
+	}
+}
+
+func TestPatienceExample(t *testing.T) {
+	// The fib() function is added as a whole instead of being matched
+	// line by line against the braces of frobnitz()
+	hunks, _ := computeHunksWithContext(patienceExampleOld, patienceExampleNew, DiffOptions{Algorithm: AlgorithmPatience})
+	var added []string
+	for _, line := range hunks[0].Lines {
+		if line.Type == LineAdded {
+			added = append(added, line.Content)
+		}
+	}
+	want := patienceExampleNew[2:11]
+	if !slices.Equal(added, want) {
+		t.Errorf("first hunk adds %q, want %q", added, want)
+	}
+}
+
+func TestCompactChangesLikeGit(t *testing.T) {
+	// An added function could start at "func b() {" or at the blank line
+	// above; git's indent heuristic adds the blank line after it instead
+	oldLines := []string{"func a() {", "}", "", "func c() {", "}"}
+	newLines := []string{"func a() {", "}", "", "func b() {", "}", "", "func c() {", "}"}
+	for _, algorithm := range allAlgorithms {
+		hunks, _ := computeHunksWithContext(oldLines, newLines, DiffOptions{Algorithm: algorithm})
+		if len(hunks) != 1 || hunks[0].NewStart != 4 {
+			t.Fatalf("%s: hunks = %+v", algorithm, hunks)
+		}
+		var added []string
+		for _, line := range hunks[0].Lines {
+			added = append(added, line.Content)
+		}
+		if want := []string{"func b() {", "}", ""}; !slices.Equal(added, want) {
+			t.Errorf("%s: added %q, want %q", algorithm, added, want)
+		}
+	}
+
+	// A change next to a change on the other side is shown with it
+	lineDiffs := computeLineDiffs(DiffOptions{}, []string{"x", "a", "b"}, []string{"a", "a", "b"})
+	if len(lineDiffs) != 3 || lineDiffs[0].Type != diffDelete || lineDiffs[1].Type != diffInsert {
+		t.Errorf("replaced line = %+v, want the removal and addition together", lineDiffs)
+	}
+}
+
+func TestParseDiffAlgorithm(t *testing.T) {
+	for name, want := range map[string]DiffAlgorithm{
+		"myers":     AlgorithmMyers,
+		"default":   AlgorithmMyers,
+		"minimal":   AlgorithmMyers,
+		"Patience":  AlgorithmPatience,
+		"histogram": AlgorithmHistogram,
+		"difflib":   AlgorithmDifflib,
+	} {
+		if got, err := parseDiffAlgorithm(name); err != nil || got != want {
+			t.Errorf("parseDiffAlgorithm(%q) = %v, %v; want %v", name, got, err, want)
+		}
+	}
+	if _, err := parseDiffAlgorithm("fastest"); err == nil {
+		t.Error("expected an error for an unknown algorithm")
+	}
+	if AlgorithmDifflib.Next() != AlgorithmMyers || AlgorithmHistogram.Label() != "Histogram" {
+		t.Error("algorithms should cycle back to myers and be labelled capitalized")
+	}
+	if (DiffOptions{}).Algorithm != AlgorithmMyers {
+		t.Error("options without an algorithm should use myers")
+	}
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// Repo config key naming the Branch Compare base: git config better-diff.baseBranch trunk
+const (
+	baseBranchConfigSection = "better-diff"
+	baseBranchConfigKey     = "baseBranch"
+)
+
+// fallbackBaseBranches are probed in order when nothing names the base branch
+var fallbackBaseBranches = []string{"main", "master", "trunk", "develop"}
+
+var errNoBaseBranch = errors.New("no base branch found")
+
+// BaseBranchSource tells how the Branch Compare base branch was chosen
+type BaseBranchSource int
+
+const (
+	BaseFromUser       BaseBranchSource = iota // --base flag or the branch picker
+	BaseFromConfig                             // better-diff.baseBranch config key
+	BaseFromRemoteHead                         // refs/remotes/<remote>/HEAD
+	BaseFromUpstream                           // upstream of the current branch
+	BaseFromFallback                           // first existing of fallbackBaseBranches
+)
+
+func (s BaseBranchSource) String() string {
+	switch s {
+	case BaseFromUser:
+		return "selected"
+	case BaseFromConfig:
+		return baseBranchConfigSection + "." + baseBranchConfigKey
+	case BaseFromRemoteHead:
+		return "remote HEAD"
+	case BaseFromUpstream:
+		return "upstream"
+	default:
+		return "fallback"
+	}
+}
+
+// BaseBranch is the branch Branch Compare diffs the working tree against
+type BaseBranch struct {
+	Name   string                 // short name, e.g. trunk or upstream/trunk
+	Ref    plumbing.ReferenceName // reference the name resolved to
+	Source BaseBranchSource
+}
+
+// ResolveBaseBranch picks the Branch Compare base. A name given by the user
+// wins, then the better-diff.baseBranch config key, the remote HEAD, the
+// upstream of the current branch and finally main, master, trunk or develop.
+func (gs *GitService) ResolveBaseBranch(name string) (BaseBranch, error) {
+	cfg, err := gs.repo.Config()
+	if err != nil {
+		return BaseBranch{}, fmt.Errorf("failed to read repository config: %w", err)
+	}
+	remotes := gs.orderedRemotes(cfg)
+
+	if name != "" {
+		return gs.baseBranchNamed(name, BaseFromUser, remotes)
+	}
+
+	if configured := cfg.Raw.Section(baseBranchConfigSection).Option(baseBranchConfigKey); configured != "" {
+		return gs.baseBranchNamed(configured, BaseFromConfig, remotes)
+	}
+
+	if ref, ok := gs.remoteHeadTarget(remotes); ok {
+		return BaseBranch{Name: ref.Short(), Ref: ref, Source: BaseFromRemoteHead}, nil
+	}
+
+	if ref, ok := gs.upstreamRef(cfg); ok {
+		return BaseBranch{Name: ref.Short(), Ref: ref, Source: BaseFromUpstream}, nil
+	}
+
+	for _, fallback := range fallbackBaseBranches {
+		if base, err := gs.baseBranchNamed(fallback, BaseFromFallback, remotes); err == nil {
+			return base, nil
+		}
+	}
+	return BaseBranch{}, errNoBaseBranch
+}
+
+// baseBranchNamed resolves a branch name given by the user or the config
+func (gs *GitService) baseBranchNamed(name string, source BaseBranchSource, remotes []string) (BaseBranch, error) {
+	ref, err := gs.findBranchReference(name, remotes)
+	if err != nil {
+		return BaseBranch{}, err
+	}
+	return BaseBranch{Name: name, Ref: ref.Name(), Source: source}, nil
+}
+
+// branchRefCandidates returns possible reference names for a branch: a local
+// branch, a remote branch written as <remote>/<branch>, or the branch on any remote
+func branchRefCandidates(branch string, remotes []string) []plumbing.ReferenceName {
+	candidates := []plumbing.ReferenceName{
+		plumbing.NewBranchReferenceName(branch),
+		plumbing.ReferenceName("refs/remotes/" + branch),
+	}
+	for _, remote := range remotes {
+		candidates = append(candidates, plumbing.NewRemoteReferenceName(remote, branch))
+	}
+	return candidates
+}
+
+// findBranchReference finds a branch reference by name
+func (gs *GitService) findBranchReference(branch string, remotes []string) (*plumbing.Reference, error) {
+	for _, refName := range branchRefCandidates(branch, remotes) {
+		ref, err := gs.repo.Reference(refName, true)
+		if err == nil && ref != nil {
+			return ref, nil
+		}
+	}
+	return nil, fmt.Errorf("branch not found: %s", branch)
+}
+
+// orderedRemotes lists the remotes to search: the remote of the current
+// branch's upstream first, then origin, then the rest by name
+func (gs *GitService) orderedRemotes(cfg *config.Config) []string {
+	preferred := []string{"origin"}
+	if branch, ok := gs.currentBranchConfig(cfg); ok && branch.Remote != "" && branch.Remote != "." {
+		preferred = []string{branch.Remote, "origin"}
+	}
+
+	others := make([]string, 0, len(cfg.Remotes))
+	for name := range cfg.Remotes {
+		others = append(others, name)
+	}
+	sort.Strings(others)
+
+	remotes := make([]string, 0, len(others))
+	for _, name := range append(preferred, others...) {
+		if _, ok := cfg.Remotes[name]; ok && !containsString(remotes, name) {
+			remotes = append(remotes, name)
+		}
+	}
+	return remotes
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// remoteHeadTarget returns the branch the first remote HEAD that exists points to
+func (gs *GitService) remoteHeadTarget(remotes []string) (plumbing.ReferenceName, bool) {
+	for _, remote := range remotes {
+		head, err := gs.repo.Reference(plumbing.NewRemoteHEADReferenceName(remote), false)
+		if err != nil || head.Type() != plumbing.SymbolicReference {
+			continue
+		}
+		if _, err := gs.repo.Reference(head.Target(), true); err == nil {
+			return head.Target(), true
+		}
+	}
+	return "", false
+}
+
+// upstreamRef returns the configured upstream of the current branch, if it exists
+func (gs *GitService) upstreamRef(cfg *config.Config) (plumbing.ReferenceName, bool) {
+	branch, ok := gs.currentBranchConfig(cfg)
+	if !ok || branch.Remote == "" || branch.Merge == "" {
+		return "", false
+	}
+
+	ref := branch.Merge
+	if branch.Remote != "." {
+		ref = plumbing.NewRemoteReferenceName(branch.Remote, branch.Merge.Short())
+	}
+	if _, err := gs.repo.Reference(ref, true); err != nil {
+		return "", false
+	}
+	return ref, true
+}
+
+// currentBranchConfig returns the [branch] config section of the checked out branch
+func (gs *GitService) currentBranchConfig(cfg *config.Config) (*config.Branch, bool) {
+	head, err := gs.repo.Head()
+	if err != nil || !head.Name().IsBranch() {
+		return nil, false
+	}
+	branch, ok := cfg.Branches[head.Name().Short()]
+	return branch, ok
+}
+
+// ListBranches returns the local branches followed by the remote branches,
+// each sorted by name, for choosing a base branch
+func (gs *GitService) ListBranches() ([]string, error) {
+	refs, err := gs.repo.References()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list references: %w", err)
+	}
+	defer refs.Close()
+
+	var local, remote []string
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		name := ref.Name()
+		switch {
+		case name.IsBranch():
+			local = append(local, name.Short())
+		case name.IsRemote() && !strings.HasSuffix(name.String(), "/HEAD"):
+			remote = append(remote, name.Short())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list references: %w", err)
+	}
+
+	sort.Strings(local)
+	sort.Strings(remote)
+	return append(local, remote...), nil
+}
+
+// baseBranchCommit resolves the base branch and returns its tip commit
+func (gs *GitService) baseBranchCommit(name string) (*object.Commit, error) {
+	base, err := gs.ResolveBaseBranch(name)
+	if err != nil {
+		return nil, err
+	}
+
+	ref, err := gs.repo.Reference(base.Ref, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get base branch reference: %w", err)
+	}
+
+	commit, err := gs.repo.CommitObject(ref.Hash())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get base branch commit: %w", err)
+	}
+	return commit, nil
+}
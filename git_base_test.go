@@ -0,0 +1,139 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+func setReference(t *testing.T, gitService *GitService, ref *plumbing.Reference) {
+	t.Helper()
+	if err := gitService.repo.Storer.SetReference(ref); err != nil {
+		t.Fatalf("set reference %s: %v", ref.Name(), err)
+	}
+}
+
+// setupTrunkRepo creates a repo whose only local branch is trunk, with the
+// remotes origin and upstream
+func setupTrunkRepo(t *testing.T) *GitService {
+	t.Helper()
+	gitService, _ := setupTempGitService(t, map[string]string{"a.txt": "a\n"})
+	head := headHash(t, gitService)
+
+	setReference(t, gitService, plumbing.NewHashReference("refs/heads/trunk", head))
+	setReference(t, gitService, plumbing.NewSymbolicReference(plumbing.HEAD, "refs/heads/trunk"))
+	if err := gitService.repo.Storer.RemoveReference("refs/heads/master"); err != nil {
+		t.Fatalf("remove master: %v", err)
+	}
+
+	for _, remote := range []string{"origin", "upstream"} {
+		if _, err := gitService.repo.CreateRemote(&config.RemoteConfig{Name: remote, URLs: []string{"https://example.com/" + remote}}); err != nil {
+			t.Fatalf("create remote %s: %v", remote, err)
+		}
+	}
+	return gitService
+}
+
+func setRepoConfig(t *testing.T, gitService *GitService, update func(cfg *config.Config)) {
+	t.Helper()
+	cfg, err := gitService.repo.Config()
+	if err != nil {
+		t.Fatalf("read config: %v", err)
+	}
+	update(cfg)
+	if err := gitService.repo.SetConfig(cfg); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+}
+
+func mustResolveBase(t *testing.T, gitService *GitService, name string) BaseBranch {
+	t.Helper()
+	base, err := gitService.ResolveBaseBranch(name)
+	if err != nil {
+		t.Fatalf("ResolveBaseBranch(%q): %v", name, err)
+	}
+	return base
+}
+
+func TestResolveBaseBranchOrder(t *testing.T) {
+	gitService := setupTrunkRepo(t)
+	head := headHash(t, gitService)
+
+	base := mustResolveBase(t, gitService, "")
+	if base.Name != "trunk" || base.Source != BaseFromFallback {
+		t.Fatalf("fallback base = %+v, want trunk", base)
+	}
+
+	// The current branch tracks upstream/release
+	setReference(t, gitService, plumbing.NewHashReference("refs/remotes/upstream/release", head))
+	setRepoConfig(t, gitService, func(cfg *config.Config) {
+		cfg.Branches["trunk"] = &config.Branch{Name: "trunk", Remote: "upstream", Merge: "refs/heads/release"}
+	})
+	base = mustResolveBase(t, gitService, "")
+	if base.Ref != "refs/remotes/upstream/release" || base.Source != BaseFromUpstream {
+		t.Fatalf("upstream base = %+v, want upstream/release", base)
+	}
+
+	// The upstream remote is searched first because the current branch tracks it
+	setReference(t, gitService, plumbing.NewHashReference("refs/remotes/origin/main", head))
+	setReference(t, gitService, plumbing.NewSymbolicReference("refs/remotes/origin/HEAD", "refs/remotes/origin/main"))
+	setReference(t, gitService, plumbing.NewHashReference("refs/remotes/upstream/trunk", head))
+	setReference(t, gitService, plumbing.NewSymbolicReference("refs/remotes/upstream/HEAD", "refs/remotes/upstream/trunk"))
+	base = mustResolveBase(t, gitService, "")
+	if base.Ref != "refs/remotes/upstream/trunk" || base.Source != BaseFromRemoteHead {
+		t.Fatalf("remote HEAD base = %+v, want upstream/trunk", base)
+	}
+
+	setRepoConfig(t, gitService, func(cfg *config.Config) {
+		cfg.Raw.Section(baseBranchConfigSection).SetOption(baseBranchConfigKey, "origin/main")
+	})
+	base = mustResolveBase(t, gitService, "")
+	if base.Ref != "refs/remotes/origin/main" || base.Source != BaseFromConfig {
+		t.Fatalf("configured base = %+v, want origin/main", base)
+	}
+
+	base = mustResolveBase(t, gitService, "release")
+	if base.Ref != "refs/remotes/upstream/release" || base.Source != BaseFromUser {
+		t.Fatalf("selected base = %+v, want upstream/release", base)
+	}
+
+	if _, err := gitService.ResolveBaseBranch("no-such-branch"); err == nil {
+		t.Fatal("expected an error for an unknown base branch")
+	}
+}
+
+func TestResolveBaseBranchNotFound(t *testing.T) {
+	gitService := setupTrunkRepo(t)
+	setReference(t, gitService, plumbing.NewHashReference("refs/heads/topic", headHash(t, gitService)))
+	setReference(t, gitService, plumbing.NewSymbolicReference(plumbing.HEAD, "refs/heads/topic"))
+	if err := gitService.repo.Storer.RemoveReference("refs/heads/trunk"); err != nil {
+		t.Fatalf("remove trunk: %v", err)
+	}
+
+	if _, err := gitService.ResolveBaseBranch(""); err != errNoBaseBranch {
+		t.Fatalf("ResolveBaseBranch error = %v, want %v", err, errNoBaseBranch)
+	}
+	commits, err := gitService.GetCommitsAheadOfBase("")
+	if err != nil || len(commits) != 0 {
+		t.Fatalf("GetCommitsAheadOfBase without a base = %v, %v; want no commits", commits, err)
+	}
+}
+
+func TestListBranches(t *testing.T) {
+	gitService := setupTrunkRepo(t)
+	head := headHash(t, gitService)
+	setReference(t, gitService, plumbing.NewHashReference("refs/heads/feature", head))
+	setReference(t, gitService, plumbing.NewHashReference("refs/remotes/origin/trunk", head))
+	setReference(t, gitService, plumbing.NewSymbolicReference("refs/remotes/origin/HEAD", "refs/remotes/origin/trunk"))
+
+	branches, err := gitService.ListBranches()
+	if err != nil {
+		t.Fatalf("ListBranches: %v", err)
+	}
+	want := []string{"feature", "trunk", "origin/trunk"}
+	if !reflect.DeepEqual(branches, want) {
+		t.Fatalf("ListBranches = %v, want %v", branches, want)
+	}
+}
@@ -1,78 +1,42 @@
 package main
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/go-git/go-git/v5"
-	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/go-git/go-git/v5/plumbing/format/diff"
 	"github.com/go-git/go-git/v5/plumbing/object"
 )
 
 var errStopCommitIteration = errors.New("stop commit iteration")
 
-// branchRefCandidates returns possible reference names for a branch
-func branchRefCandidates(branch string) []plumbing.ReferenceName {
-	return []plumbing.ReferenceName{
-		plumbing.ReferenceName("refs/heads/" + branch),
-		plumbing.ReferenceName("refs/remotes/origin/" + branch),
-	}
-}
-
-// findBranchReference finds a branch reference by name
-func (gs *GitService) findBranchReference(branch string) (*plumbing.Reference, error) {
-	var lastErr error
-	for _, refName := range branchRefCandidates(branch) {
-		ref, err := gs.repo.Reference(refName, false)
-		if err == nil && ref != nil {
-			return ref, nil
-		}
-		lastErr = err
-	}
-	if lastErr == nil {
-		lastErr = fmt.Errorf("branch not found: %s", branch)
-	}
-	return nil, lastErr
-}
-
-// GetDefaultBranch returns the default branch name (main or master)
-func (gs *GitService) GetDefaultBranch() (string, error) {
-	for _, branch := range []string{"main", "master", "develop"} {
-		if _, err := gs.findBranchReference(branch); err == nil {
-			return branch, nil
-		}
-	}
-
-	// Default to main if nothing else found
-	return "main", nil
-}
-
-// GetCommitsAheadOfMain gets commits that are on the current branch but not on main
-func (gs *GitService) GetCommitsAheadOfMain() ([]Commit, error) {
-	currentBranch, err := gs.GetCurrentBranch()
+// GetCommitsAheadOfBase gets commits that are on HEAD but not on the base
+// branch. base names the branch explicitly; empty resolves it automatically.
+func (gs *GitService) GetCommitsAheadOfBase(base string) ([]Commit, error) {
+	head, err := gs.repo.Head()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get current branch: %w", err)
+		return nil, fmt.Errorf("failed to get HEAD reference: %w", err)
 	}
 
-	defaultBranch, err := gs.GetDefaultBranch()
+	currentCommit, err := gs.repo.CommitObject(head.Hash())
 	if err != nil {
-		return nil, fmt.Errorf("failed to get default branch: %w", err)
+		return nil, fmt.Errorf("failed to get current commit: %w", err)
 	}
 
-	// If we're on the default branch, no commits ahead
-	if currentBranch == defaultBranch {
-		return []Commit{}, nil
-	}
-
-	currentCommit, defaultCommit, err := gs.resolveBranchHeadCommits(currentBranch, defaultBranch)
+	baseCommit, err := gs.baseBranchCommit(base)
 	if err != nil {
-		return nil, err
+		if errors.Is(err, errNoBaseBranch) {
+			return []Commit{}, nil
+		}
+		return nil, fmt.Errorf("failed to get base branch: %w", err)
 	}
 
 	// Get merge base
-	mergeBase, err := getMergeBase(currentCommit, defaultCommit)
+	mergeBase, err := getMergeBase(currentCommit, baseCommit)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get merge base: %w", err)
 	}
@@ -89,33 +53,6 @@ func (gs *GitService) GetCommitsAheadOfMain() ([]Commit, error) {
 	return commits, nil
 }
 
-// resolveBranchHeadCommits resolves the head commits for two branches
-func (gs *GitService) resolveBranchHeadCommits(currentBranch, defaultBranch string) (*object.Commit, *object.Commit, error) {
-	// Get current branch reference.
-	currentRef, err := gs.repo.Reference(plumbing.ReferenceName("refs/heads/"+currentBranch), true)
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to get current branch reference: %w", err)
-	}
-
-	// Get default branch reference.
-	defaultRef, err := gs.findBranchReference(defaultBranch)
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to get default branch reference: %w", err)
-	}
-
-	currentCommit, err := gs.repo.CommitObject(currentRef.Hash())
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to get current commit: %w", err)
-	}
-
-	defaultCommit, err := gs.repo.CommitObject(defaultRef.Hash())
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to get default commit: %w", err)
-	}
-
-	return currentCommit, defaultCommit, nil
-}
-
 // collectCommitsAhead collects commits ahead of a merge base
 func (gs *GitService) collectCommitsAhead(currentCommit, mergeBase *object.Commit) ([]Commit, bool, error) {
 	// Get commits from merge base to current head (exclusive of merge base).
@@ -158,11 +95,13 @@ func commitToSummary(commit *object.Commit) Commit {
 	}
 
 	return Commit{
-		Hash:      hash,
-		ShortHash: shortHash,
-		Author:    commit.Author.Name,
-		Message:   getFirstLine(commit.Message),
-		Date:      commit.Author.When.Format("2006-01-02 15:04"),
+		Hash:        hash,
+		ShortHash:   shortHash,
+		Author:      commit.Author.Name,
+		Email:       commit.Author.Email,
+		Message:     getFirstLine(commit.Message),
+		FullMessage: strings.TrimRight(commit.Message, "\n"),
+		Date:        commit.Author.When.Format("2006-01-02 15:04"),
 	}
 }
 
@@ -196,19 +135,19 @@ func getFirstLine(message string) string {
 }
 
 // GetBranchCompareDiffs gets both staged and unstaged changes for branch comparison.
-func (gs *GitService) GetBranchCompareDiffs(viewMode DiffViewMode, contextLines int, logger *Logger) ([]FileDiff, []FileDiff, error) {
+func (gs *GitService) GetBranchCompareDiffs(viewMode DiffViewMode, diffOpts DiffOptions, pathspec Pathspec, logger *Logger) ([]FileDiff, []FileDiff, error) {
 	if logger == nil {
 		return nil, nil, fmt.Errorf("logger is required")
 	}
 
 	// Get staged changes
-	staged, err := gs.GetDiffWithContext(Staged, viewMode, contextLines, logger)
+	staged, err := gs.GetDiffWithContext(Staged, viewMode, diffOpts, pathspec, logger)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to get staged changes: %w", err)
 	}
 
 	// Get unstaged changes
-	unstaged, err := gs.GetDiffWithContext(Unstaged, viewMode, contextLines, logger)
+	unstaged, err := gs.GetDiffWithContext(Unstaged, viewMode, diffOpts, pathspec, logger)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to get unstaged changes: %w", err)
 	}
@@ -216,14 +155,15 @@ func (gs *GitService) GetBranchCompareDiffs(viewMode DiffViewMode, contextLines
 	return staged, unstaged, nil
 }
 
-// GetUnifiedBranchCompareDiff returns a single unified diff per file:
-// default branch tip (main/master) vs current working tree state.
-func (gs *GitService) GetUnifiedBranchCompareDiff(viewMode DiffViewMode, contextLines int, logger *Logger) ([]FileDiff, error) {
+// GetUnifiedBranchCompareDiff returns a single unified diff per file: base
+// branch tip vs current working tree state, for paths matching pathspec. base
+// names the branch explicitly; empty resolves it with ResolveBaseBranch.
+func (gs *GitService) GetUnifiedBranchCompareDiff(base string, viewMode DiffViewMode, diffOpts DiffOptions, pathspec Pathspec, logger *Logger) ([]FileDiff, error) {
 	if logger == nil {
 		return nil, fmt.Errorf("logger is required")
 	}
 
-	worktree, baseCommit, paths, effectiveContext, err := gs.branchCompareInputs(viewMode, contextLines, logger)
+	worktree, baseCommit, paths, viewOpts, err := gs.branchCompareInputs(base, viewMode, diffOpts, logger)
 	if err != nil {
 		if errors.Is(err, errSkipBranchCompare) {
 			return []FileDiff{}, nil
@@ -231,9 +171,16 @@ func (gs *GitService) GetUnifiedBranchCompareDiff(viewMode DiffViewMode, context
 		return nil, err
 	}
 
+	return gs.buildWorktreeCompareFileDiffs(pathspec.filter(paths), baseCommit, worktree, viewOpts, logger)
+}
+
+// buildWorktreeCompareFileDiffs diffs each path between a base commit and the working tree
+func (gs *GitService) buildWorktreeCompareFileDiffs(paths []string, baseCommit *object.Commit, worktree *git.Worktree, diffOpts DiffOptions, logger *Logger) ([]FileDiff, error) {
 	files := make([]FileDiff, 0, len(paths))
+	renames := newRenameDetector(diffOpts.Renames)
+	attrs := newBinaryAttributes(worktree.Filesystem)
 	for _, path := range paths {
-		fileDiff, err := gs.buildUnifiedBranchCompareFileDiff(path, baseCommit, worktree, effectiveContext, logger)
+		fileDiff, err := gs.buildUnifiedBranchCompareFileDiff(path, baseCommit, worktree, diffOpts, renames, attrs, logger)
 		if err != nil {
 			return nil, fmt.Errorf("failed to compute unified diff for %s: %w", path, err)
 		}
@@ -243,41 +190,41 @@ func (gs *GitService) GetUnifiedBranchCompareDiff(viewMode DiffViewMode, context
 		files = append(files, *fileDiff)
 	}
 
-	return files, nil
+	return renames.apply(files, diffOpts)
 }
 
 var errSkipBranchCompare = errors.New("skip branch compare")
 
 // branchCompareInputs gathers inputs for branch compare operations
-func (gs *GitService) branchCompareInputs(viewMode DiffViewMode, contextLines int, logger *Logger) (*git.Worktree, *object.Commit, []string, int, error) {
+func (gs *GitService) branchCompareInputs(base string, viewMode DiffViewMode, diffOpts DiffOptions, logger *Logger) (*git.Worktree, *object.Commit, []string, DiffOptions, error) {
 	worktree, err := gs.repo.Worktree()
 	if err != nil {
-		return nil, nil, nil, 0, fmt.Errorf("failed to get worktree: %w", err)
+		return nil, nil, nil, DiffOptions{}, fmt.Errorf("failed to get worktree: %w", err)
 	}
 
 	headCommit, shouldSkip, err := gs.getHeadCommitForBranchCompare(logger)
 	if err != nil {
-		return nil, nil, nil, 0, err
+		return nil, nil, nil, DiffOptions{}, err
 	}
 	if shouldSkip {
-		return nil, nil, nil, 0, errSkipBranchCompare
+		return nil, nil, nil, DiffOptions{}, errSkipBranchCompare
 	}
 
-	baseCommit, err := gs.getDefaultBranchCommit()
+	baseCommit, err := gs.baseBranchCommit(base)
 	if err != nil {
-		if isObjectNotFoundError(err) {
-			logger.Warn("skip branch compare: default branch commit unavailable", nil)
-			return nil, nil, nil, 0, errSkipBranchCompare
+		if errors.Is(err, errNoBaseBranch) || isObjectNotFoundError(err) {
+			logger.Warn("skip branch compare: base branch commit unavailable", map[string]any{"error": err})
+			return nil, nil, nil, DiffOptions{}, errSkipBranchCompare
 		}
-		return nil, nil, nil, 0, fmt.Errorf("failed to resolve default branch commit: %w", err)
+		return nil, nil, nil, DiffOptions{}, fmt.Errorf("failed to resolve base branch commit: %w", err)
 	}
 
 	paths, err := gs.collectBranchComparePaths(baseCommit, headCommit, worktree)
 	if err != nil {
-		return nil, nil, nil, 0, err
+		return nil, nil, nil, DiffOptions{}, err
 	}
 
-	return worktree, baseCommit, paths, resolveEffectiveContextLines(viewMode, contextLines), nil
+	return worktree, baseCommit, paths, diffOpts.forView(viewMode), nil
 }
 
 // getHeadCommitForBranchCompare gets the HEAD commit for branch compare
@@ -303,14 +250,6 @@ func (gs *GitService) getHeadCommitForBranchCompare(logger *Logger) (*object.Com
 	return headCommit, false, nil
 }
 
-// resolveEffectiveContextLines resolves context lines for branch compare
-func resolveEffectiveContextLines(viewMode DiffViewMode, contextLines int) int {
-	if viewMode == WholeFile {
-		return WholeFileContext
-	}
-	return contextLines
-}
-
 // resolveBranchCompareChangeType determines change type from existence flags
 func resolveBranchCompareChangeType(oldExists, newExists bool) ChangeType {
 	if !oldExists && newExists {
@@ -322,8 +261,9 @@ func resolveBranchCompareChangeType(oldExists, newExists bool) ChangeType {
 	return Modified
 }
 
-// buildUnifiedBranchCompareFileDiff builds a unified diff for a file in branch compare
-func (gs *GitService) buildUnifiedBranchCompareFileDiff(path string, baseCommit *object.Commit, worktree *git.Worktree, contextLines int, logger *Logger) (*FileDiff, error) {
+// buildUnifiedBranchCompareFileDiff builds a unified diff for a file in branch
+// compare and records its contents for rename detection
+func (gs *GitService) buildUnifiedBranchCompareFileDiff(path string, baseCommit *object.Commit, worktree *git.Worktree, diffOpts DiffOptions, renames *renameDetector, attrs *binaryAttributes, logger *Logger) (*FileDiff, error) {
 	oldContent, oldExists, err := gs.readFileFromCommit(baseCommit, path, logger)
 	if err != nil {
 		logger.Error("skip file in branch compare: read base content", err, map[string]any{
@@ -340,11 +280,31 @@ func (gs *GitService) buildUnifiedBranchCompareFileDiff(path string, baseCommit
 		return nil, nil
 	}
 
+	binary := attrs.isBinary(path)
+	renames.add(path, resolveBranchCompareChangeType(oldExists, newExists), oldContent, newContent, binary)
+	fileDiff, err := newFileDiffFromContents(path, oldContent, newContent, oldExists, newExists, binary, diffOpts)
+	if fileDiff != nil {
+		fileDiff.OldMode, fileDiff.NewMode = commitFileMode(baseCommit, path), worktreeFileMode(worktree, path)
+	}
+	return fileDiff, err
+}
+
+// newFileDiffFromContents diffs two versions of a file. It returns nil when the
+// file exists on neither side or the contents are identical. Binary files,
+// those with a NUL byte or marked binary by the caller, get a summary instead
+// of hunks.
+func newFileDiffFromContents(path string, oldContent, newContent []byte, oldExists, newExists, binary bool, diffOpts DiffOptions) (*FileDiff, error) {
 	if !oldExists && !newExists {
 		return nil, nil
 	}
+	if binary || isBinaryContent(oldContent) || isBinaryContent(newContent) {
+		if oldExists == newExists && bytes.Equal(oldContent, newContent) {
+			return nil, nil
+		}
+		return newBinaryFileDiff(path, resolveBranchCompareChangeType(oldExists, newExists), oldContent, newContent, oldExists, newExists), nil
+	}
 
-	hunks, err := buildUnifiedBranchCompareHunks(oldContent, newContent, contextLines)
+	hunks, err := computeFileHunks(path, oldContent, newContent, diffOpts)
 	if err != nil {
 		return nil, err
 	}
@@ -354,38 +314,18 @@ func (gs *GitService) buildUnifiedBranchCompareFileDiff(path string, baseCommit
 
 	linesAdded, linesRemoved := countHunkLineStats(hunks)
 	return &FileDiff{
-		Path:         path,
-		ChangeType:   resolveBranchCompareChangeType(oldExists, newExists),
-		Hunks:        hunks,
-		LinesAdded:   linesAdded,
-		LinesRemoved: linesRemoved,
+		Path:             path,
+		ChangeType:       resolveBranchCompareChangeType(oldExists, newExists),
+		Hunks:            hunks,
+		LinesAdded:       linesAdded,
+		LinesRemoved:     linesRemoved,
+		OldNoNewlineLine: missingNewlineLine(oldContent),
+		NewNoNewlineLine: missingNewlineLine(newContent),
+		Decls:            goDeclChanges(path, oldContent, newContent, diffOpts),
+		Structure:        structuralDiff(path, oldContent, newContent, diffOpts),
 	}, nil
 }
 
-// buildUnifiedBranchCompareHunks builds hunks for branch compare
-func buildUnifiedBranchCompareHunks(oldContent, newContent []byte, contextLines int) ([]Hunk, error) {
-	return computeHunksWithContext(splitLines(string(oldContent)), splitLines(string(newContent)), contextLines)
-}
-
-// getDefaultBranchCommit gets the commit for the default branch
-func (gs *GitService) getDefaultBranchCommit() (*object.Commit, error) {
-	defaultBranch, err := gs.GetDefaultBranch()
-	if err != nil {
-		return nil, err
-	}
-
-	ref, err := gs.findBranchReference(defaultBranch)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get default branch reference: %w", err)
-	}
-
-	commit, err := gs.repo.CommitObject(ref.Hash())
-	if err != nil {
-		return nil, fmt.Errorf("failed to get default branch commit: %w", err)
-	}
-	return commit, nil
-}
-
 // collectBranchComparePaths collects paths for branch compare
 func (gs *GitService) collectBranchComparePaths(baseCommit, headCommit *object.Commit, worktree *git.Worktree) ([]string, error) {
 	pathSet := make(map[string]struct{})
@@ -439,8 +379,11 @@ func addChangedStatusPaths(pathSet map[string]struct{}, status git.Status) {
 	}
 }
 
-// readFileFromCommit reads a file from a commit
+// readFileFromCommit reads a file from a commit; a nil commit has no files
 func (gs *GitService) readFileFromCommit(commit *object.Commit, path string, logger *Logger) ([]byte, bool, error) {
+	if commit == nil {
+		return nil, false, nil
+	}
 	file, err := commit.File(path)
 	if err != nil {
 		if errors.Is(err, object.ErrFileNotFound) {
@@ -484,177 +427,45 @@ func (gs *GitService) readFileFromWorktree(worktree *git.Worktree, path string,
 	return content, true, nil
 }
 
-// GetCommitDiff gets the diff for a specific commit (compares commit to its parent)
-func (gs *GitService) GetCommitDiff(commitHash string, logger *Logger) ([]FileDiff, error) {
+// GetCommitDiff gets the diff for a specific commit (compares commit to its
+// parent), restricted to paths matching pathspec. A root commit is diffed
+// against an empty tree.
+func (gs *GitService) GetCommitDiff(commitHash string, viewMode DiffViewMode, diffOpts DiffOptions, pathspec Pathspec, logger *Logger) ([]FileDiff, error) {
 	if logger == nil {
 		return nil, fmt.Errorf("logger is required")
 	}
 
 	commit, parentCommit, err := gs.resolveCommitAndParent(commitHash)
 	if err != nil {
-		if errors.Is(err, object.ErrParentNotFound) {
-			// First commit has no parent, so there is no parent-based diff.
-			return []FileDiff{}, nil
-		}
 		return nil, err
 	}
 
-	patch, err := parentCommit.Patch(commit)
+	files, err := gs.diffCommits(parentCommit, commit, diffOpts.forView(viewMode), pathspec, logger)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get patch: %w", err)
+		return nil, err
 	}
 
-	logger.Info("got patch from git", map[string]any{
-		"commit":       commitHash[:7],
-		"file_patches": len(patch.FilePatches()),
+	logger.Info("loaded commit diff", map[string]any{
+		"commit":     commit.Hash.String()[:7],
+		"file_count": len(files),
 	})
-
-	return convertPatchToFileDiffs(patch.FilePatches(), logger, commitHash), nil
+	return files, nil
 }
 
-// resolveCommitAndParent resolves a commit and its parent
+// resolveCommitAndParent resolves a commit and its first parent; a root
+// commit has a nil parent
 func (gs *GitService) resolveCommitAndParent(commitHash string) (*object.Commit, *object.Commit, error) {
-	commit, err := gs.repo.CommitObject(plumbing.NewHash(commitHash))
+	commit, err := gs.resolveCommit(commitHash)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to get commit: %w", err)
+		return nil, nil, err
 	}
-
-	if len(commit.ParentHashes) == 0 {
-		return nil, nil, object.ErrParentNotFound
+	if commit.NumParents() == 0 {
+		return commit, nil, nil
 	}
 
-	parentCommit, err := gs.repo.CommitObject(commit.ParentHashes[0])
+	parentCommit, err := commit.Parent(0)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to get parent commit: %w", err)
 	}
-
 	return commit, parentCommit, nil
 }
-
-// convertPatchToFileDiffs converts file patches to FileDiff slices
-func convertPatchToFileDiffs(filePatches []diff.FilePatch, logger *Logger, commitHash string) []FileDiff {
-	files := make([]FileDiff, 0, len(filePatches))
-
-	for _, filePatch := range filePatches {
-		path := filePatchPath(filePatch)
-		hunks, err := convertPatchToHunks(filePatch)
-		if err != nil {
-			logger.Warn("convert patch to hunks", map[string]any{
-				"file":  path,
-				"error": err,
-			})
-			continue
-		}
-
-		if len(hunks) == 0 {
-			continue
-		}
-
-		linesAdded, linesRemoved := countHunkLineStats(hunks)
-		logger.Info("processed file patch", map[string]any{
-			"path":          path,
-			"hunks":         len(hunks),
-			"lines_added":   linesAdded,
-			"lines_removed": linesRemoved,
-		})
-
-		files = append(files, FileDiff{
-			Path:         path,
-			ChangeType:   Modified,
-			Hunks:        hunks,
-			LinesAdded:   linesAdded,
-			LinesRemoved: linesRemoved,
-		})
-	}
-
-	logger.Info("loaded commit diff", map[string]any{
-		"commit":     commitHash[:7],
-		"file_count": len(files),
-	})
-
-	return files
-}
-
-// filePatchPath extracts the path from a file patch
-func filePatchPath(filePatch diff.FilePatch) string {
-	from, to := filePatch.Files()
-	if to != nil && to.Path() != "" {
-		return to.Path()
-	}
-	if from != nil && from.Path() != "" {
-		return from.Path()
-	}
-	return "unknown"
-}
-
-// convertPatchToHunks converts a git Patch to our Hunk format
-func convertPatchToHunks(filePatch diff.FilePatch) ([]Hunk, error) {
-	chunks := filePatch.Chunks()
-	if len(chunks) == 0 {
-		return []Hunk{}, nil
-	}
-
-	var hunks []Hunk
-
-	for _, chunk := range chunks {
-		content := chunk.Content()
-		if content == "" {
-			continue
-		}
-
-		lines := splitLines(content)
-
-		var currentHunk *Hunk
-		for _, line := range lines {
-			if len(line) == 0 {
-				continue
-			}
-
-			if len(line) > 2 && line[0] == '@' && line[1] == '@' {
-				if currentHunk != nil && len(currentHunk.Lines) > 0 {
-					hunks = append(hunks, *currentHunk)
-				}
-				currentHunk = &Hunk{
-					Lines: []DiffLine{},
-				}
-				continue
-			}
-
-			var lineType LineType
-			var contentStr string
-
-			switch line[0] {
-			case '+':
-				lineType = LineAdded
-				contentStr = line[1:]
-			case '-':
-				lineType = LineRemoved
-				contentStr = line[1:]
-			case ' ':
-				lineType = LineContext
-				contentStr = line[1:]
-			default:
-				continue
-			}
-
-			if currentHunk == nil {
-				currentHunk = &Hunk{
-					Lines: []DiffLine{},
-				}
-			}
-
-			currentHunk.Lines = append(currentHunk.Lines, DiffLine{
-				Type:       lineType,
-				Content:    contentStr,
-				OldLineNum: 0,
-				NewLineNum: 0,
-			})
-		}
-
-		if currentHunk != nil && len(currentHunk.Lines) > 0 {
-			hunks = append(hunks, *currentHunk)
-		}
-	}
-
-	return hunks, nil
-}
@@ -0,0 +1,305 @@
+package main
+
+// The changes an algorithm finds can often be shifted: an added block that
+// starts and ends with the same line could equally be shown one line higher
+// or lower. compactChanges places them the way git does (xdl_change_compact
+// with the indent heuristic), so hunks read the same as in git diff.
+
+// diffSide is one file of a diff: its interned lines, their text and which of
+// them are changed
+type diffSide struct {
+	ids     []int
+	lines   []string
+	changed []bool
+}
+
+// diffGroup is a run of changed lines [start, end); an empty group sits
+// above the unchanged line at start
+type diffGroup struct {
+	start, end int
+}
+
+func (s *diffSide) changedAt(i int) bool {
+	return i >= 0 && i < len(s.changed) && s.changed[i]
+}
+
+func (s *diffSide) firstGroup() diffGroup {
+	var g diffGroup
+	for s.changedAt(g.end) {
+		g.end++
+	}
+	return g
+}
+
+// nextGroup moves g to the next, possibly empty, group and reports false at
+// the end of the file
+func (s *diffSide) nextGroup(g *diffGroup) bool {
+	if g.end == len(s.changed) {
+		return false
+	}
+	g.start = g.end + 1
+	g.end = g.start
+	for s.changedAt(g.end) {
+		g.end++
+	}
+	return true
+}
+
+// previousGroup moves g to the previous, possibly empty, group and reports
+// false at the start of the file
+func (s *diffSide) previousGroup(g *diffGroup) bool {
+	if g.start == 0 {
+		return false
+	}
+	g.end = g.start - 1
+	g.start = g.end
+	for s.changedAt(g.start - 1) {
+		g.start--
+	}
+	return true
+}
+
+// slideDown shifts g one line down when its first line equals the line after
+// it, merging any group it runs into
+func (s *diffSide) slideDown(g *diffGroup) bool {
+	if g.end >= len(s.changed) || s.ids[g.start] != s.ids[g.end] {
+		return false
+	}
+	s.changed[g.start] = false
+	s.changed[g.end] = true
+	g.start++
+	g.end++
+	for s.changedAt(g.end) {
+		g.end++
+	}
+	return true
+}
+
+// slideUp shifts g one line up when its last line equals the line before it,
+// merging any group it runs into
+func (s *diffSide) slideUp(g *diffGroup) bool {
+	if g.start == 0 || s.ids[g.start-1] != s.ids[g.end-1] {
+		return false
+	}
+	g.start--
+	g.end--
+	s.changed[g.start] = true
+	s.changed[g.end] = false
+	for s.changedAt(g.start - 1) {
+		g.start--
+	}
+	return true
+}
+
+// compactChanges shifts the groups of s. Each group is slid as far up and
+// down as it goes, merging with the groups it meets; it then ends up next to
+// a change in other when it can, or else where the indent heuristic scores
+// best. other's groups are walked in step so both sides stay aligned.
+func compactChanges(s, other *diffSide) {
+	g, og := s.firstGroup(), other.firstGroup()
+	for {
+		if g.end != g.start {
+			var size, earliestEnd, endMatchingOther int
+			for {
+				size = g.end - g.start
+				endMatchingOther = -1
+				for s.slideUp(&g) {
+					other.previousGroup(&og)
+				}
+				earliestEnd = g.end
+				if og.end > og.start {
+					endMatchingOther = g.end
+				}
+				for s.slideDown(&g) {
+					other.nextGroup(&og)
+					if og.end > og.start {
+						endMatchingOther = g.end
+					}
+				}
+				if size == g.end-g.start {
+					break
+				}
+			}
+
+			switch {
+			case g.end == earliestEnd:
+				// the group cannot move
+			case endMatchingOther != -1:
+				for og.end == og.start {
+					s.slideUp(&g)
+					other.previousGroup(&og)
+				}
+			default:
+				best := s.bestShift(g, size, earliestEnd)
+				for g.end > best {
+					s.slideUp(&g)
+					other.previousGroup(&og)
+				}
+			}
+		}
+		if !s.nextGroup(&g) {
+			return
+		}
+		other.nextGroup(&og)
+	}
+}
+
+// Weights of git's indent heuristic
+const (
+	indentMaxSliding         = 100
+	indentMax                = 200
+	indentMaxBlanks          = 20
+	startOfFilePenalty       = 1
+	endOfFilePenalty         = 21
+	totalBlankWeight         = -30
+	postBlankWeight          = 6
+	relativeIndentPenalty    = -4
+	relativeIndentWithBlank  = 10
+	relativeOutdentPenalty   = 24
+	relativeOutdentWithBlank = 17
+	relativeDedentPenalty    = 23
+	relativeDedentWithBlank  = 17
+	indentWeight             = 60
+)
+
+// bestShift returns the end the group g, slid all the way down, should be
+// moved up to: the one whose two boundaries fall at the least surprising
+// places given the indentation and blank lines around them
+func (s *diffSide) bestShift(g diffGroup, size, earliestEnd int) int {
+	shift := max(earliestEnd, g.end-size-1, g.end-indentMaxSliding)
+	best := -1
+	var bestScore splitScore
+	for ; shift <= g.end; shift++ {
+		var score splitScore
+		score.add(s.measureSplit(shift))
+		score.add(s.measureSplit(shift - size))
+		if best == -1 || score.compare(bestScore) <= 0 {
+			bestScore = score
+			best = shift
+		}
+	}
+	return best
+}
+
+// lineIndent returns the indentation width of a line with tabs to multiples
+// of 8, or -1 for a blank line
+func lineIndent(line string) int {
+	indent := 0
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case ' ':
+			indent++
+		case '\t':
+			indent += 8 - indent%8
+		case '\n', '\r', '\f', '\v':
+		default:
+			return indent
+		}
+		if indent >= indentMax {
+			return indentMax
+		}
+	}
+	return -1
+}
+
+// splitMeasurement describes the lines around a split before line split
+type splitMeasurement struct {
+	endOfFile  bool
+	indent     int // of the line after the split, -1 if blank
+	preBlank   int // blank lines before the split
+	preIndent  int // of the first non-blank line before, -1 for none
+	postBlank  int // blank lines after the line after the split
+	postIndent int // of the first non-blank line after those, -1 for none
+}
+
+func (s *diffSide) measureSplit(split int) splitMeasurement {
+	m := splitMeasurement{indent: -1, preIndent: -1, postIndent: -1}
+	if split >= len(s.lines) {
+		m.endOfFile = true
+	} else {
+		m.indent = lineIndent(s.lines[split])
+	}
+
+	for i := split - 1; i >= 0; i-- {
+		m.preIndent = lineIndent(s.lines[i])
+		if m.preIndent != -1 {
+			break
+		}
+		m.preBlank++
+		if m.preBlank == indentMaxBlanks {
+			m.preIndent = 0
+			break
+		}
+	}
+
+	for i := split + 1; i < len(s.lines); i++ {
+		m.postIndent = lineIndent(s.lines[i])
+		if m.postIndent != -1 {
+			break
+		}
+		m.postBlank++
+		if m.postBlank == indentMaxBlanks {
+			m.postIndent = 0
+			break
+		}
+	}
+	return m
+}
+
+// splitScore rates a placement of a group; lower is better
+type splitScore struct {
+	effectiveIndent int
+	penalty         int
+}
+
+func (score *splitScore) add(m splitMeasurement) {
+	if m.preIndent == -1 && m.preBlank == 0 {
+		score.penalty += startOfFilePenalty
+	}
+	if m.endOfFile {
+		score.penalty += endOfFilePenalty
+	}
+
+	postBlank := 0
+	if m.indent == -1 {
+		postBlank = 1 + m.postBlank
+	}
+	totalBlank := m.preBlank + postBlank
+	score.penalty += totalBlankWeight*totalBlank + postBlankWeight*postBlank
+
+	indent := m.indent
+	if indent == -1 {
+		indent = m.postIndent
+	}
+	anyBlanks := totalBlank != 0
+	score.effectiveIndent += indent
+
+	switch {
+	case indent == -1, m.preIndent == -1, indent == m.preIndent:
+	case indent > m.preIndent:
+		score.penalty += pick(anyBlanks, relativeIndentWithBlank, relativeIndentPenalty)
+	case m.postIndent != -1 && m.postIndent > indent:
+		// likely the start of a new block
+		score.penalty += pick(anyBlanks, relativeOutdentWithBlank, relativeOutdentPenalty)
+	default:
+		// likely the end of the previous block
+		score.penalty += pick(anyBlanks, relativeDedentWithBlank, relativeDedentPenalty)
+	}
+}
+
+func (score splitScore) compare(other splitScore) int {
+	cmpIndents := 0
+	if score.effectiveIndent > other.effectiveIndent {
+		cmpIndents = 1
+	} else if score.effectiveIndent < other.effectiveIndent {
+		cmpIndents = -1
+	}
+	return indentWeight*cmpIndents + score.penalty - other.penalty
+}
+
+func pick(cond bool, ifTrue, ifFalse int) int {
+	if cond {
+		return ifTrue
+	}
+	return ifFalse
+}
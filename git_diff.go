@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
 	"github.com/go-git/go-git/v5/plumbing/format/index"
 	"github.com/go-git/go-git/v5/plumbing/object"
 )
@@ -13,11 +14,12 @@ var errSkipDiffLoad = errors.New("skip diff load")
 
 // GetDiff gets the git diff based on mode with default context.
 func (gs *GitService) GetDiff(mode DiffMode, viewMode DiffViewMode, logger *Logger) ([]FileDiff, error) {
-	return gs.GetDiffWithContext(mode, viewMode, DefaultDiffContext, logger)
+	return gs.GetDiffWithContext(mode, viewMode, DiffOptions{Context: DefaultDiffContext}, Pathspec{}, logger)
 }
 
-// GetDiffWithContext gets the git diff based on mode and configurable context lines.
-func (gs *GitService) GetDiffWithContext(mode DiffMode, viewMode DiffViewMode, contextLines int, logger *Logger) ([]FileDiff, error) {
+// GetDiffWithContext gets the git diff based on mode, computed with the context
+// lines and algorithm of diffOpts, restricted to the paths matching pathspec.
+func (gs *GitService) GetDiffWithContext(mode DiffMode, viewMode DiffViewMode, diffOpts DiffOptions, pathspec Pathspec, logger *Logger) ([]FileDiff, error) {
 	if logger == nil {
 		return nil, fmt.Errorf("logger is required")
 	}
@@ -30,15 +32,17 @@ func (gs *GitService) GetDiffWithContext(mode DiffMode, viewMode DiffViewMode, c
 		return nil, err
 	}
 
-	paths := sortedStatusPaths(status)
+	paths := pathspec.filter(sortedStatusPaths(status))
 	files := make([]FileDiff, 0, len(paths))
+	renames := newRenameDetector(diffOpts.Renames)
+	attrs := newBinaryAttributes(worktree.Filesystem)
 	for _, path := range paths {
 		fileStatus := status[path]
 		if !isRelevantChange(mode, status, path, fileStatus) {
 			continue
 		}
 
-		fileDiff, err := gs.getFileDiff(worktree, idx, headCommit, path, mode, viewMode, contextLines, *fileStatus, logger)
+		fileDiff, err := gs.getFileDiff(worktree, idx, headCommit, path, mode, viewMode, diffOpts, *fileStatus, renames, attrs, logger)
 		if err != nil {
 			// Log error but continue with other files
 			logger.Error("get file diff", err, map[string]any{
@@ -53,7 +57,7 @@ func (gs *GitService) GetDiffWithContext(mode DiffMode, viewMode DiffViewMode, c
 		}
 	}
 
-	return files, nil
+	return renames.apply(files, diffOpts.forView(viewMode))
 }
 
 // diffInputs gathers the inputs needed for diff operations
@@ -128,8 +132,10 @@ func getWorktreeStatusForDiff(worktree *git.Worktree, logger *Logger) (git.Statu
 	return status, false, nil
 }
 
-// getFileDiff generates a FileDiff for a single file
-func (gs *GitService) getFileDiff(worktree *git.Worktree, idx *index.Index, headCommit *object.Commit, path string, mode DiffMode, viewMode DiffViewMode, contextLines int, fileStatus git.FileStatus, logger *Logger) (*FileDiff, error) {
+// getFileDiff generates a FileDiff for a single file and records its
+// contents for rename detection. Binary files, by content or by the
+// gitattributes in attrs, get a summary instead of hunks.
+func (gs *GitService) getFileDiff(worktree *git.Worktree, idx *index.Index, headCommit *object.Commit, path string, mode DiffMode, viewMode DiffViewMode, diffOpts DiffOptions, fileStatus git.FileStatus, renames *renameDetector, attrs *binaryAttributes, logger *Logger) (*FileDiff, error) {
 	changeType := statusCodeToChangeType(statusCodeForMode(mode, fileStatus))
 	oldContent, newContent, resolvedChangeType, err := gs.loadDiffContents(path, mode, fileStatus, idx, headCommit, worktree, logger)
 	if err != nil {
@@ -137,22 +143,31 @@ func (gs *GitService) getFileDiff(worktree *git.Worktree, idx *index.Index, head
 	}
 	changeType = resolvedChangeType
 
-	hunks, err := computeHunksWithContext(
-		splitLines(string(oldContent)),
-		splitLines(string(newContent)),
-		effectiveContextLines(viewMode, contextLines),
-	)
+	binary := attrs.isBinary(path)
+	renames.add(path, changeType, oldContent, newContent, binary)
+	if binary || isBinaryContent(oldContent) || isBinaryContent(newContent) {
+		return newBinaryFileDiff(path, changeType, oldContent, newContent, changeType != Added, changeType != Deleted), nil
+	}
+
+	hunks, err := computeFileHunks(path, oldContent, newContent, diffOpts.forView(viewMode))
 	if err != nil {
 		return nil, fmt.Errorf("failed to compute diff for %s: %w", path, err)
 	}
 
 	linesAdded, linesRemoved := countHunkLineStats(hunks)
+	oldMode, newMode := statusFileModes(path, mode, idx, headCommit, worktree)
 	return &FileDiff{
-		Path:         path,
-		ChangeType:   changeType,
-		Hunks:        hunks,
-		LinesAdded:   linesAdded,
-		LinesRemoved: linesRemoved,
+		Path:             path,
+		ChangeType:       changeType,
+		Hunks:            hunks,
+		LinesAdded:       linesAdded,
+		LinesRemoved:     linesRemoved,
+		OldNoNewlineLine: missingNewlineLine(oldContent),
+		NewNoNewlineLine: missingNewlineLine(newContent),
+		OldMode:          oldMode,
+		NewMode:          newMode,
+		Decls:            goDeclChanges(path, oldContent, newContent, diffOpts),
+		Structure:        structuralDiff(path, oldContent, newContent, diffOpts),
 	}, nil
 }
 
@@ -192,6 +207,50 @@ func (gs *GitService) loadDiffContents(path string, mode DiffMode, fileStatus gi
 	return oldContent, newContent, changeType, nil
 }
 
+// statusFileModes returns the old and new mode of a file: in the index and
+// the worktree for unstaged changes, in HEAD and the index for staged ones.
+// A side where the file is missing has mode 0.
+func statusFileModes(path string, mode DiffMode, idx *index.Index, headCommit *object.Commit, worktree *git.Worktree) (filemode.FileMode, filemode.FileMode) {
+	if mode == Unstaged {
+		return indexFileMode(idx, path), worktreeFileMode(worktree, path)
+	}
+	return commitFileMode(headCommit, path), indexFileMode(idx, path)
+}
+
+// worktreeFileMode returns the mode of a file in the worktree, 0 when it is missing
+func worktreeFileMode(worktree *git.Worktree, path string) filemode.FileMode {
+	info, err := worktree.Filesystem.Lstat(path)
+	if err != nil {
+		return filemode.Empty
+	}
+	mode, err := filemode.NewFromOSFileMode(info.Mode())
+	if err != nil {
+		return filemode.Empty
+	}
+	return mode
+}
+
+// indexFileMode returns the mode of a file in the index, 0 when it is missing
+func indexFileMode(idx *index.Index, path string) filemode.FileMode {
+	entry, err := idx.Entry(path)
+	if err != nil {
+		return filemode.Empty
+	}
+	return entry.Mode
+}
+
+// commitFileMode returns the mode of a file in a commit, 0 when it is missing
+func commitFileMode(commit *object.Commit, path string) filemode.FileMode {
+	if commit == nil {
+		return filemode.Empty
+	}
+	file, err := commit.File(path)
+	if err != nil {
+		return filemode.Empty
+	}
+	return file.Mode
+}
+
 // readRequiredWorktreeContent reads content from worktree (for untracked files)
 func (gs *GitService) readRequiredWorktreeContent(path string, worktree *git.Worktree, logger *Logger) ([]byte, error) {
 	file, err := worktree.Filesystem.Open(path)
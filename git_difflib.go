@@ -1,45 +1,22 @@
 package main
 
 import (
-	"fmt"
-
 	"github.com/pmezard/go-difflib/difflib"
 )
 
-// computeHunksWithDifflib computes diff hunks using difflib.SequenceMatcher.
-func computeHunksWithDifflib(oldLines, newLines []string, contextLines int) ([]Hunk, error) {
-	normalizedContext := max(0, contextLines)
-	matcher := difflib.NewMatcher(oldLines, newLines)
-	opCodes := matcher.GetOpCodes()
-	lineDiffs, err := opCodesToLineDiffs(opCodes, oldLines, newLines)
-	if err != nil {
-		return nil, err
-	}
-	return buildHunks(lineDiffs, normalizedContext), nil
-}
+// difflibDiffer matches lines with difflib.SequenceMatcher, the heuristics of
+// Python's difflib. It was the only algorithm before the native ones.
+type difflibDiffer struct{}
 
-func opCodesToLineDiffs(opCodes []difflib.OpCode, oldLines, newLines []string) ([]lineDiff, error) {
-	lineDiffs := make([]lineDiff, 0, len(opCodes))
-
-	for _, op := range opCodes {
-		switch op.Tag {
-		case 'e':
-			lineDiffs = appendMergedLineDiff(lineDiffs, diffEqual, oldLines[op.I1:op.I2])
-		case 'd':
-			lineDiffs = appendMergedLineDiff(lineDiffs, diffDelete, oldLines[op.I1:op.I2])
-		case 'i':
-			lineDiffs = appendMergedLineDiff(lineDiffs, diffInsert, newLines[op.J1:op.J2])
-		case 'r':
-			if op.I1 < op.I2 {
-				lineDiffs = appendMergedLineDiff(lineDiffs, diffDelete, oldLines[op.I1:op.I2])
-			}
-			if op.J1 < op.J2 {
-				lineDiffs = appendMergedLineDiff(lineDiffs, diffInsert, newLines[op.J1:op.J2])
-			}
-		default:
-			return nil, fmt.Errorf("unsupported opcode tag: %q", op.Tag)
+func (difflibDiffer) match(lm *lineMatcher) {
+	for _, op := range difflib.NewMatcher(lm.oldKeys, lm.newKeys).GetOpCodes() {
+		if op.Tag == 'e' {
+			lm.add(op.I1, op.J1, op.I2-op.I1)
 		}
 	}
+}
 
-	return lineDiffs, nil
+// computeHunksWithDifflib computes diff hunks using difflib.SequenceMatcher.
+func computeHunksWithDifflib(oldLines, newLines []string, contextLines int) ([]Hunk, error) {
+	return computeHunksWithContext(oldLines, newLines, DiffOptions{Context: contextLines, Algorithm: AlgorithmDifflib})
 }
@@ -38,7 +38,7 @@ func TestComputeHunksWithDifflib_Parity(t *testing.T) {
 	for _, tc := range testCases {
 		tc := tc
 		t.Run(tc.name, func(t *testing.T) {
-			baseline, err := computeHunksWithContext(tc.old, tc.new, tc.context)
+			baseline, err := computeHunksWithContext(tc.old, tc.new, DiffOptions{Context: tc.context, Algorithm: AlgorithmDifflib})
 			if err != nil {
 				t.Fatalf("baseline computeHunksWithContext() error = %v", err)
 			}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/format/index"
+)
+
+var (
+	errDiscardUntracked = errors.New("file is not in the index")
+	errDiscardSymlink   = errors.New("symlinks cannot be discarded")
+)
+
+// DiscardFile restores the index version of a file into the worktree
+func (gs *GitService) DiscardFile(path string, logger *Logger) error {
+	worktree, entry, indexContent, err := gs.discardInputs(path, logger)
+	if err != nil {
+		return err
+	}
+	return writeWorktreeContent(worktree, path, indexContent, entry.Mode)
+}
+
+// DiscardHunk reverts a hunk of the unstaged (index → worktree) diff in the worktree
+func (gs *GitService) DiscardHunk(path string, hunk Hunk, logger *Logger) error {
+	worktree, entry, indexContent, err := gs.discardInputs(path, logger)
+	if err != nil {
+		return err
+	}
+
+	worktreeContent, _, err := gs.readFileFromWorktree(worktree, path, logger)
+	if err != nil {
+		return err
+	}
+
+	updated, err := reverseSplice(hunk, wholeHunk(hunk)).apply(worktreeContent, endsWithNewline(indexContent))
+	if err != nil {
+		return fmt.Errorf("discard hunk in %s: %w", path, err)
+	}
+	return writeWorktreeContent(worktree, path, updated, entry.Mode)
+}
+
+// discardInputs loads the worktree and the index entry and content of path.
+// Untracked files have no index version to restore and are rejected.
+func (gs *GitService) discardInputs(path string, logger *Logger) (*git.Worktree, *index.Entry, []byte, error) {
+	worktree, err := gs.repo.Worktree()
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to get worktree: %w", err)
+	}
+
+	idx, err := gs.repo.Storer.Index()
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to get index: %w", err)
+	}
+
+	entry, err := idx.Entry(path)
+	if err != nil {
+		if errors.Is(err, index.ErrEntryNotFound) {
+			return nil, nil, nil, fmt.Errorf("discard %s: %w", path, errDiscardUntracked)
+		}
+		return nil, nil, nil, fmt.Errorf("failed to look up %s in index: %w", path, err)
+	}
+	if entry.Mode == filemode.Symlink {
+		return nil, nil, nil, fmt.Errorf("discard %s: %w", path, errDiscardSymlink)
+	}
+
+	content, err := gs.readIndexContentIfPresent(path, idx, logger, "failed to read file %s from index: %w")
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return worktree, entry, content, nil
+}
+
+// writeWorktreeContent replaces a worktree file, creating it and its parent
+// directories when it was deleted
+func writeWorktreeContent(worktree *git.Worktree, path string, content []byte, mode filemode.FileMode) error {
+	perm := os.FileMode(0o644)
+	if mode == filemode.Executable {
+		perm = 0o755
+	}
+
+	file, err := worktree.Filesystem.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return fmt.Errorf("failed to open %s in worktree: %w", path, err)
+	}
+	if _, err := file.Write(content); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write %s to worktree: %w", path, err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write %s to worktree: %w", path, err)
+	}
+	return nil
+}
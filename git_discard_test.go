@@ -0,0 +1,71 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func readWorktreeFile(t *testing.T, root, path string) string {
+	t.Helper()
+	content, err := os.ReadFile(filepath.Join(root, path))
+	if err != nil {
+		t.Fatalf("read %s: %v", path, err)
+	}
+	return string(content)
+}
+
+func TestDiscardHunkKeepsOtherChanges(t *testing.T) {
+	original := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\nm\nn\n"
+	gitService, root := setupTempGitService(t, map[string]string{"file.txt": original})
+	writeWorktreeFile(t, root, "file.txt", "A\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\nm\nN\n")
+
+	unstaged := diffForPath(t, gitService, Unstaged, "file.txt")
+	if len(unstaged.Hunks) != 2 {
+		t.Fatalf("expected 2 unstaged hunks, got %d", len(unstaged.Hunks))
+	}
+	if err := gitService.DiscardHunk("file.txt", unstaged.Hunks[0], newDefaultLogger(ERROR)); err != nil {
+		t.Fatalf("DiscardHunk: %v", err)
+	}
+	if got, want := readWorktreeFile(t, root, "file.txt"), "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\nm\nN\n"; got != want {
+		t.Fatalf("worktree after discarding first hunk = %q, want %q", got, want)
+	}
+}
+
+func TestDiscardFileRestoresIndexVersion(t *testing.T) {
+	gitService, root := setupTempGitService(t, map[string]string{"dir/file.txt": "one\ntwo\n"})
+	logger := newDefaultLogger(ERROR)
+
+	writeWorktreeFile(t, root, "dir/file.txt", "changed\n")
+	if err := gitService.DiscardFile("dir/file.txt", logger); err != nil {
+		t.Fatalf("DiscardFile: %v", err)
+	}
+	if got := readWorktreeFile(t, root, "dir/file.txt"); got != "one\ntwo\n" {
+		t.Fatalf("worktree after discard = %q", got)
+	}
+
+	// Deleted files are recreated, including their directory
+	if err := os.RemoveAll(filepath.Join(root, "dir")); err != nil {
+		t.Fatalf("remove dir: %v", err)
+	}
+	if err := gitService.DiscardFile("dir/file.txt", logger); err != nil {
+		t.Fatalf("DiscardFile deleted: %v", err)
+	}
+	if got := readWorktreeFile(t, root, "dir/file.txt"); got != "one\ntwo\n" {
+		t.Fatalf("worktree after restoring deleted file = %q", got)
+	}
+}
+
+func TestDiscardRejectsUntrackedFile(t *testing.T) {
+	gitService, root := setupTempGitService(t, map[string]string{"keep.txt": "keep\n"})
+	writeWorktreeFile(t, root, "new.txt", "new\n")
+
+	err := gitService.DiscardFile("new.txt", newDefaultLogger(ERROR))
+	if !errors.Is(err, errDiscardUntracked) {
+		t.Fatalf("expected errDiscardUntracked, got %v", err)
+	}
+	if got := readWorktreeFile(t, root, "new.txt"); got != "new\n" {
+		t.Fatalf("untracked file should be left alone, got %q", got)
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+// histogramMaxChain is the most occurrences a line may have in the old range
+// to anchor a histogram diff, as in git
+const histogramMaxChain = 64
+
+// histogramDiffer is git's histogram diff, an extension of patience diff: the
+// longest common region around the rarest line shared by both sides anchors
+// the diff, and the ranges before and after it are diffed the same way. When
+// every shared line is too common, the range falls back to Myers.
+type histogramDiffer struct{}
+
+func (histogramDiffer) match(lm *lineMatcher) {
+	lm.histogram(0, len(lm.a), 0, len(lm.b))
+}
+
+// histogram matches a[aLo:aHi] against b[bLo:bHi]
+func (lm *lineMatcher) histogram(aLo, aHi, bLo, bHi int) {
+	for aLo < aHi && bLo < bHi {
+		region, found, tooCommon := lm.rarestRegion(aLo, aHi, bLo, bHi)
+		if tooCommon {
+			lm.myers(aLo, aHi, bLo, bHi)
+			return
+		}
+		if !found {
+			return
+		}
+		lm.histogram(aLo, region.aStart, bLo, region.bStart)
+		lm.add(region.aStart, region.bStart, region.length)
+		aLo, bLo = region.aStart+region.length, region.bStart+region.length
+	}
+}
+
+// commonRegion is a run of equal lines on both sides
+type commonRegion struct {
+	aStart, bStart, length int
+}
+
+// rarestRegion finds the common region to split on. For every line of b, the
+// equal run around each of its occurrences in a is a candidate; the run whose
+// rarest line is rarest wins, or a longer run as rare. tooCommon reports that
+// the ranges share lines but all of them occur too often to anchor on.
+func (lm *lineMatcher) rarestRegion(aLo, aHi, bLo, bHi int) (region commonRegion, found, tooCommon bool) {
+	positions := make(map[int][]int)
+	for i := aLo; i < aHi; i++ {
+		positions[lm.a[i]] = append(positions[lm.a[i]], i)
+	}
+	count := func(i int) int { return len(positions[lm.a[i]]) }
+
+	hasCommon := false
+	lowest := histogramMaxChain + 1
+	for j := bLo; j < bHi; {
+		occurrences := positions[lm.b[j]]
+		next := j + 1
+		if len(occurrences) > 0 {
+			hasCommon = true
+		}
+		if len(occurrences) > lowest {
+			j = next
+			continue
+		}
+		for k := 0; k < len(occurrences); {
+			as, bs := occurrences[k], j
+			ae, be := as+1, bs+1
+			rarest := len(occurrences)
+			for as > aLo && bs > bLo && lm.a[as-1] == lm.b[bs-1] {
+				as--
+				bs--
+				if rarest > 1 {
+					rarest = min(rarest, count(as))
+				}
+			}
+			for ae < aHi && be < bHi && lm.a[ae] == lm.b[be] {
+				if rarest > 1 {
+					rarest = min(rarest, count(ae))
+				}
+				ae++
+				be++
+			}
+
+			next = max(next, be)
+			if ae-as > region.length || rarest < lowest {
+				region = commonRegion{aStart: as, bStart: bs, length: ae - as}
+				lowest = rarest
+			}
+			// Occurrences inside the run just found are not tried again
+			for k < len(occurrences) && occurrences[k] < ae {
+				k++
+			}
+		}
+		j = next
+	}
+	if hasCommon && lowest > histogramMaxChain {
+		return commonRegion{}, false, true
+	}
+	return region, region.length > 0, false
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// GetCommitLog lists the history of ref, newest first, like git log: limit
+// commits after skipping the first skip of them. hasMore reports whether
+// older commits follow. An empty ref lists the history of HEAD.
+func (gs *GitService) GetCommitLog(ref string, skip, limit int) (commits []Commit, hasMore bool, err error) {
+	start, err := gs.resolveCommit(defaultRevision(ref))
+	if err != nil {
+		return nil, false, err
+	}
+
+	commitIter, err := gs.repo.Log(&git.LogOptions{
+		From:  start.Hash,
+		Order: git.LogOrderCommitterTime,
+	})
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get commit log: %w", err)
+	}
+
+	seen := 0
+	iterateErr := commitIter.ForEach(func(c *object.Commit) error {
+		seen++
+		switch {
+		case seen <= skip:
+			return nil
+		case len(commits) == limit:
+			hasMore = true
+			return errStopCommitIteration
+		}
+		commits = append(commits, commitToSummary(c))
+		return nil
+	})
+	if iterateErr != nil && !errors.Is(iterateErr, errStopCommitIteration) {
+		return nil, false, fmt.Errorf("failed to read commit log: %w", iterateErr)
+	}
+	return commits, hasMore, nil
+}
+
+// GetFileHistory lists the commits of ref that changed path, newest first,
+// like git log --follow: when a commit turns out to have renamed or copied the
+// file, older commits are searched for the path it came from. Each commit
+// carries the file's path in it, and the old path when it renamed the file.
+// A merge is only listed when the file differs from every parent, and a
+// commit that deleted the file is listed too.
+func (gs *GitService) GetFileHistory(ref, path string, renames RenameOptions, logger *Logger) ([]Commit, error) {
+	if logger == nil {
+		return nil, fmt.Errorf("logger is required")
+	}
+
+	start, err := gs.resolveCommit(defaultRevision(ref))
+	if err != nil {
+		return nil, err
+	}
+	commitIter, err := gs.repo.Log(&git.LogOptions{
+		From:  start.Hash,
+		Order: git.LogOrderCommitterTime,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get commit log: %w", err)
+	}
+
+	var commits []Commit
+	followed := path
+	iterateErr := commitIter.ForEach(func(c *object.Commit) error {
+		hash, exists, err := fileHashAt(c, followed)
+		if err != nil {
+			return err
+		}
+		inParent, unchanged, err := compareWithParents(c, followed, hash, exists)
+		if err != nil || unchanged || (!exists && !inParent) {
+			return err
+		}
+
+		summary := commitToSummary(c)
+		summary.Path = followed
+		if exists && !inParent && c.NumParents() > 0 && renames.Detect {
+			oldPath, err := gs.renameSource(c, followed, renames, logger)
+			if err != nil {
+				return err
+			}
+			summary.OldPath = oldPath
+		}
+		commits = append(commits, summary)
+		if summary.OldPath != "" {
+			followed = summary.OldPath
+		}
+		return nil
+	})
+	if iterateErr != nil {
+		return nil, fmt.Errorf("failed to read history of %s: %w", path, iterateErr)
+	}
+
+	logger.Info("loaded file history", map[string]any{
+		"file":         path,
+		"commit_count": len(commits),
+	})
+	return commits, nil
+}
+
+// GetFileCommitDiff gets the diff of the file a commit of GetFileHistory
+// changed against the commit's parent, paired with its old path when renamed
+func (gs *GitService) GetFileCommitDiff(commit Commit, viewMode DiffViewMode, diffOpts DiffOptions, logger *Logger) ([]FileDiff, error) {
+	paths := []string{commit.Path}
+	if commit.OldPath != "" {
+		paths = append(paths, commit.OldPath)
+	}
+	return gs.GetCommitDiff(commit.Hash, viewMode, diffOpts, literalPathspec(paths...), logger)
+}
+
+// fileHashAt returns the blob of a file in a commit, and whether it exists there
+func fileHashAt(commit *object.Commit, path string) (plumbing.Hash, bool, error) {
+	tree, err := commit.Tree()
+	if err != nil {
+		return plumbing.ZeroHash, false, fmt.Errorf("failed to get tree of %s: %w", commit.Hash, err)
+	}
+	entry, err := tree.FindEntry(path)
+	if errors.Is(err, object.ErrEntryNotFound) || errors.Is(err, object.ErrDirectoryNotFound) {
+		return plumbing.ZeroHash, false, nil
+	}
+	if err != nil {
+		return plumbing.ZeroHash, false, fmt.Errorf("failed to find %s in %s: %w", path, commit.Hash, err)
+	}
+	if !entry.Mode.IsFile() {
+		return plumbing.ZeroHash, false, nil
+	}
+	return entry.Hash, true, nil
+}
+
+// compareWithParents reports whether any parent of a commit has the file, and
+// whether one of them has it just as the commit does (blob hash, or absent),
+// so the commit did not change it
+func compareWithParents(commit *object.Commit, path string, hash plumbing.Hash, exists bool) (inParent, unchanged bool, err error) {
+	err = commit.Parents().ForEach(func(parent *object.Commit) error {
+		parentHash, parentExists, err := fileHashAt(parent, path)
+		if err != nil {
+			return err
+		}
+		inParent = inParent || parentExists
+		unchanged = unchanged || (parentExists == exists && parentHash == hash)
+		return nil
+	})
+	return inParent, unchanged, err
+}
+
+// renameSource returns the path a file added by a commit was renamed or
+// copied from, found the way the commit's diff pairs files, or "" when the
+// file is new
+func (gs *GitService) renameSource(commit *object.Commit, path string, renames RenameOptions, logger *Logger) (string, error) {
+	parent, err := commit.Parent(0)
+	if err != nil {
+		return "", fmt.Errorf("failed to get parent commit: %w", err)
+	}
+	files, err := gs.diffCommits(parent, commit, DiffOptions{Renames: renames}, Pathspec{}, logger)
+	if err != nil {
+		return "", err
+	}
+	for _, file := range files {
+		if file.Path == path && (file.ChangeType == Renamed || file.ChangeType == Copied) {
+			return file.OldPath, nil
+		}
+	}
+	return "", nil
+}
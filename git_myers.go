@@ -0,0 +1,102 @@
+package main
+
+// myersDiffer is Myers' O(ND) algorithm in linear space: the middle snake
+// splits the files and both halves are diffed recursively. The result is a
+// minimal diff.
+type myersDiffer struct{}
+
+func (myersDiffer) match(lm *lineMatcher) {
+	lm.myers(0, len(lm.a), 0, len(lm.b))
+}
+
+// myers matches a[aLo:aHi] against b[bLo:bHi]
+func (lm *lineMatcher) myers(aLo, aHi, bLo, bHi int) {
+	aLo, aHi, bLo, bHi, suffix := lm.trimCommon(aLo, aHi, bLo, bHi)
+	if aLo < aHi && bLo < bHi {
+		if x, y, ok := lm.middleSnake(aLo, aHi, bLo, bHi); ok {
+			lm.myers(aLo, x, bLo, y)
+			lm.myers(x, aHi, y, bHi)
+		}
+	}
+	lm.add(aHi, bHi, suffix)
+}
+
+// middleSnake runs the forward and reverse searches until their paths
+// overlap and returns the point where they meet. ok is false when the ranges
+// have no line in common.
+func (lm *lineMatcher) middleSnake(aLo, aHi, bLo, bHi int) (int, int, bool) {
+	n, m := aHi-aLo, bHi-bLo
+	maxD := (n + m + 1) / 2
+	offset := maxD
+	forward := make([]int, 2*maxD+2)
+	reverse := make([]int, 2*maxD+2)
+	for i := range forward {
+		forward[i], reverse[i] = -1, -1
+	}
+	forward[offset+1], reverse[offset+1] = 0, 0
+
+	delta := n - m
+	// With an odd delta the paths meet during a forward step
+	oddDelta := delta%2 != 0
+	// Diagonals that ran off the edit graph are skipped from then on
+	kStartF, kEndF, kStartR, kEndR := 0, 0, 0, 0
+
+	for d := 0; d < maxD; d++ {
+		for k := -d + kStartF; k <= d-kEndF; k += 2 {
+			var x int
+			if k == -d || (k != d && forward[offset+k-1] < forward[offset+k+1]) {
+				x = forward[offset+k+1]
+			} else {
+				x = forward[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && lm.a[aLo+x] == lm.b[bLo+y] {
+				x++
+				y++
+			}
+			forward[offset+k] = x
+			switch {
+			case x > n:
+				kEndF += 2
+			case y > m:
+				kStartF += 2
+			case oddDelta:
+				rk := offset + delta - k
+				if rk >= 0 && rk < len(reverse) && reverse[rk] != -1 && x >= n-reverse[rk] {
+					return aLo + x, bLo + y, true
+				}
+			}
+		}
+
+		for k := -d + kStartR; k <= d-kEndR; k += 2 {
+			var x int
+			if k == -d || (k != d && reverse[offset+k-1] < reverse[offset+k+1]) {
+				x = reverse[offset+k+1]
+			} else {
+				x = reverse[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && lm.a[aHi-1-x] == lm.b[bHi-1-y] {
+				x++
+				y++
+			}
+			reverse[offset+k] = x
+			switch {
+			case x > n:
+				kEndR += 2
+			case y > m:
+				kStartR += 2
+			case !oddDelta:
+				fk := offset + delta - k
+				if fk >= 0 && fk < len(forward) && forward[fk] != -1 {
+					fx := forward[fk]
+					fy := fx - (fk - offset)
+					if fx >= n-x {
+						return aLo + fx, bLo + fy, true
+					}
+				}
+			}
+		}
+	}
+	return 0, 0, false
+}
@@ -0,0 +1,118 @@
+package main
+
+import "sort"
+
+// patienceDiffer is Bram Cohen's patience diff: lines that occur exactly once
+// on both sides anchor the diff, in the longest order both sides agree on, and
+// the gaps between anchors are diffed the same way. Gaps without unique lines
+// fall back to Myers.
+type patienceDiffer struct{}
+
+func (patienceDiffer) match(lm *lineMatcher) {
+	lm.patience(0, len(lm.a), 0, len(lm.b))
+}
+
+// patience matches a[aLo:aHi] against b[bLo:bHi]. As in git, the equal lines
+// next to each anchor are matched with it before the gap is diffed.
+func (lm *lineMatcher) patience(aLo, aHi, bLo, bHi int) {
+	if aLo == aHi || bLo == bHi {
+		return
+	}
+	anchors, common := lm.uniqueAnchors(aLo, aHi, bLo, bHi)
+	if !common {
+		return
+	}
+	if len(anchors) == 0 {
+		lm.myers(aLo, aHi, bLo, bHi)
+		return
+	}
+
+	for k := 0; ; k++ {
+		nextA, nextB := aHi, bHi
+		if k < len(anchors) {
+			nextA, nextB = anchors[k].old, anchors[k].new
+			for nextA > aLo && nextB > bLo && lm.a[nextA-1] == lm.b[nextB-1] {
+				nextA--
+				nextB--
+			}
+		}
+		grown := 0
+		for aLo+grown < nextA && bLo+grown < nextB && lm.a[aLo+grown] == lm.b[bLo+grown] {
+			grown++
+		}
+		lm.add(aLo, bLo, grown)
+		aLo, bLo = aLo+grown, bLo+grown
+		if aLo < nextA || bLo < nextB {
+			lm.patience(aLo, nextA, bLo, nextB)
+		}
+		if k == len(anchors) {
+			return
+		}
+		lm.add(nextA, nextB, anchors[k].old-nextA+1)
+		aLo, bLo = anchors[k].old+1, anchors[k].new+1
+	}
+}
+
+// uniqueAnchors pairs the lines that occur once in each range and returns the
+// longest subsequence of pairs increasing on both sides. common reports
+// whether the ranges share any line at all.
+func (lm *lineMatcher) uniqueAnchors(aLo, aHi, bLo, bHi int) (anchors []lineMatch, common bool) {
+	type occurrence struct{ oldCount, newCount, old, new int }
+	occurrences := make(map[int]*occurrence)
+	for i := aLo; i < aHi; i++ {
+		o := occurrences[lm.a[i]]
+		if o == nil {
+			o = &occurrence{}
+			occurrences[lm.a[i]] = o
+		}
+		o.oldCount++
+		o.old = i
+	}
+	for j := bLo; j < bHi; j++ {
+		if o := occurrences[lm.b[j]]; o != nil {
+			o.newCount++
+			o.new = j
+			common = true
+		}
+	}
+
+	var pairs []lineMatch
+	for _, o := range occurrences {
+		if o.oldCount == 1 && o.newCount == 1 {
+			pairs = append(pairs, lineMatch{old: o.old, new: o.new})
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].old < pairs[j].old })
+	return longestIncreasingMatches(pairs), common
+}
+
+// longestIncreasingMatches returns the longest subsequence of pairs, sorted by
+// old index, whose new indexes increase too (patience sorting)
+func longestIncreasingMatches(pairs []lineMatch) []lineMatch {
+	if len(pairs) == 0 {
+		return nil
+	}
+	// tails[k] is the index of the pair ending the best subsequence of length
+	// k+1; prev links each pair to the one before it in its subsequence
+	tails := make([]int, 0, len(pairs))
+	prev := make([]int, len(pairs))
+	for i, pair := range pairs {
+		k := sort.Search(len(tails), func(k int) bool { return pairs[tails[k]].new >= pair.new })
+		if k > 0 {
+			prev[i] = tails[k-1]
+		} else {
+			prev[i] = -1
+		}
+		if k == len(tails) {
+			tails = append(tails, i)
+		} else {
+			tails[k] = i
+		}
+	}
+
+	result := make([]lineMatch, len(tails))
+	for i, k := tails[len(tails)-1], len(tails)-1; k >= 0; i, k = prev[i], k-1 {
+		result[k] = pairs[i]
+	}
+	return result
+}
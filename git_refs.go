@@ -0,0 +1,257 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+var errEmptyRevision = errors.New("empty revision")
+
+// RevisionRange describes the two sides compared in RefCompare mode
+type RevisionRange struct {
+	From      string // old side revision
+	To        string // new side revision; empty compares From against the working tree
+	MergeBase bool   // diff from the merge base of From and To, like git diff A...B
+}
+
+// parseRevisionRange parses <rev>, <rev1>..<rev2> and <rev1>...<rev2>.
+// An omitted side of a range defaults to HEAD, as in git.
+func parseRevisionRange(arg string) (RevisionRange, error) {
+	separator := ""
+	switch {
+	case strings.Contains(arg, "..."):
+		separator = "..."
+	case strings.Contains(arg, ".."):
+		separator = ".."
+	default:
+		if arg == "" {
+			return RevisionRange{}, errEmptyRevision
+		}
+		return RevisionRange{From: arg}, nil
+	}
+
+	from, to, _ := strings.Cut(arg, separator)
+	if from == "" && to == "" {
+		return RevisionRange{}, fmt.Errorf("invalid revision range %q", arg)
+	}
+	return RevisionRange{
+		From:      defaultRevision(from),
+		To:        defaultRevision(to),
+		MergeBase: separator == "...",
+	}, nil
+}
+
+func defaultRevision(rev string) string {
+	if rev == "" {
+		return "HEAD"
+	}
+	return rev
+}
+
+// String renders the range the way it would be typed on the command line
+func (r RevisionRange) String() string {
+	switch {
+	case r.To == "":
+		return r.From
+	case r.MergeBase:
+		return r.From + "..." + r.To
+	default:
+		return r.From + ".." + r.To
+	}
+}
+
+// Description explains what the range compares, for headers
+func (r RevisionRange) Description() string {
+	switch {
+	case r.To == "":
+		return r.From + " vs current working tree"
+	case r.MergeBase:
+		return r.To + " vs merge base with " + r.From
+	default:
+		return r.From + " vs " + r.To
+	}
+}
+
+// ValidateRevisionRange checks that every revision in the range resolves to a commit
+func (gs *GitService) ValidateRevisionRange(revisions RevisionRange) error {
+	_, _, err := gs.resolveRevisionRange(revisions)
+	return err
+}
+
+// checkPatchFileArgument rejects a patch file argument that also resolves as
+// a revision, as git rejects an ambiguous argument, rather than silently
+// taking the file
+func (gs *GitService) checkPatchFileArgument(name string) error {
+	revisions, err := parseRevisionRange(name)
+	if err != nil || gs.ValidateRevisionRange(revisions) != nil {
+		return nil
+	}
+	return fmt.Errorf("ambiguous argument %q: both a revision and a patch file; use ./%s for the file or %s^0 for the revision", name, name, name)
+}
+
+// GetRefCompareDiff diffs the two sides of a revision range for paths matching
+// pathspec. Without a To revision, the From commit is compared against the
+// working tree.
+func (gs *GitService) GetRefCompareDiff(revisions RevisionRange, viewMode DiffViewMode, diffOpts DiffOptions, pathspec Pathspec, logger *Logger) ([]FileDiff, error) {
+	if logger == nil {
+		return nil, fmt.Errorf("logger is required")
+	}
+
+	fromCommit, toCommit, err := gs.resolveRevisionRange(revisions)
+	if err != nil {
+		return nil, err
+	}
+
+	viewOpts := diffOpts.forView(viewMode)
+	if toCommit == nil {
+		return gs.diffCommitAgainstWorktree(fromCommit, viewOpts, pathspec, logger)
+	}
+	return gs.diffCommits(fromCommit, toCommit, viewOpts, pathspec, logger)
+}
+
+// resolveRevisionRange resolves both sides of a range to commits. The second
+// commit is nil when the range compares against the working tree.
+func (gs *GitService) resolveRevisionRange(revisions RevisionRange) (*object.Commit, *object.Commit, error) {
+	fromCommit, err := gs.resolveCommit(revisions.From)
+	if err != nil {
+		return nil, nil, err
+	}
+	if revisions.To == "" {
+		return fromCommit, nil, nil
+	}
+
+	toCommit, err := gs.resolveCommit(revisions.To)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !revisions.MergeBase {
+		return fromCommit, toCommit, nil
+	}
+
+	mergeBase, err := getMergeBase(fromCommit, toCommit)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get merge base of %s and %s: %w", revisions.From, revisions.To, err)
+	}
+	return mergeBase, toCommit, nil
+}
+
+// resolveCommit resolves a revision (branch, tag, hash, HEAD~n, ...) to a commit
+func (gs *GitService) resolveCommit(rev string) (*object.Commit, error) {
+	if rev == "" {
+		return nil, errEmptyRevision
+	}
+
+	hash, err := gs.repo.ResolveRevision(plumbing.Revision(rev))
+	if err != nil {
+		return nil, fmt.Errorf("unknown revision %q: %w", rev, err)
+	}
+
+	commit, err := gs.repo.CommitObject(*hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get commit for %q: %w", rev, err)
+	}
+	return commit, nil
+}
+
+// diffCommitAgainstWorktree diffs a commit against the current working tree
+func (gs *GitService) diffCommitAgainstWorktree(baseCommit *object.Commit, diffOpts DiffOptions, pathspec Pathspec, logger *Logger) ([]FileDiff, error) {
+	worktree, err := gs.repo.Worktree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get worktree: %w", err)
+	}
+
+	headCommit, shouldSkip, err := gs.getHeadCommitForBranchCompare(logger)
+	if err != nil {
+		return nil, err
+	}
+	if shouldSkip {
+		return []FileDiff{}, nil
+	}
+
+	paths, err := gs.collectBranchComparePaths(baseCommit, headCommit, worktree)
+	if err != nil {
+		return nil, err
+	}
+	return gs.buildWorktreeCompareFileDiffs(pathspec.filter(paths), baseCommit, worktree, diffOpts, logger)
+}
+
+// diffCommits diffs the trees of two commits. A nil fromCommit stands for an
+// empty tree, so every file of toCommit shows as added.
+func (gs *GitService) diffCommits(fromCommit, toCommit *object.Commit, diffOpts DiffOptions, pathspec Pathspec, logger *Logger) ([]FileDiff, error) {
+	paths, err := collectCommitDiffPaths(fromCommit, toCommit)
+	if err != nil {
+		return nil, err
+	}
+	paths = pathspec.filter(paths)
+
+	files := make([]FileDiff, 0, len(paths))
+	renames := newRenameDetector(diffOpts.Renames)
+	attrs := gs.worktreeBinaryAttributes()
+	for _, path := range paths {
+		oldContent, oldExists, err := gs.readFileFromCommit(fromCommit, path, logger)
+		if err != nil {
+			logger.Error("skip file in ref compare: read old content", err, map[string]any{
+				"file": path,
+			})
+			continue
+		}
+
+		newContent, newExists, err := gs.readFileFromCommit(toCommit, path, logger)
+		if err != nil {
+			logger.Error("skip file in ref compare: read new content", err, map[string]any{
+				"file": path,
+			})
+			continue
+		}
+
+		binary := attrs.isBinary(path)
+		renames.add(path, resolveBranchCompareChangeType(oldExists, newExists), oldContent, newContent, binary)
+		fileDiff, err := newFileDiffFromContents(path, oldContent, newContent, oldExists, newExists, binary, diffOpts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compute diff for %s: %w", path, err)
+		}
+		if fileDiff == nil {
+			continue
+		}
+		fileDiff.OldMode, fileDiff.NewMode = commitFileMode(fromCommit, path), commitFileMode(toCommit, path)
+		files = append(files, *fileDiff)
+	}
+	return renames.apply(files, diffOpts)
+}
+
+// collectCommitDiffPaths collects the paths that differ between two commit
+// trees; a nil fromCommit stands for an empty tree
+func collectCommitDiffPaths(fromCommit, toCommit *object.Commit) ([]string, error) {
+	var fromTree *object.Tree
+	if fromCommit != nil {
+		tree, err := fromCommit.Tree()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get tree of %s: %w", fromCommit.Hash, err)
+		}
+		fromTree = tree
+	}
+	toTree, err := toCommit.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tree of %s: %w", toCommit.Hash, err)
+	}
+
+	changes, err := object.DiffTree(fromTree, toTree)
+	if err != nil {
+		return nil, fmt.Errorf("failed to diff trees: %w", err)
+	}
+
+	pathSet := make(map[string]struct{}, len(changes))
+	for _, change := range changes {
+		if change.From.Name != "" {
+			pathSet[change.From.Name] = struct{}{}
+		}
+		if change.To.Name != "" {
+			pathSet[change.To.Name] = struct{}{}
+		}
+	}
+	return sortedPathsFromSet(pathSet), nil
+}
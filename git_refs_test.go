@@ -0,0 +1,141 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+func TestParseRevisionRange(t *testing.T) {
+	tests := []struct {
+		arg  string
+		want RevisionRange
+	}{
+		{"v1.0", RevisionRange{From: "v1.0"}},
+		{"main..feature", RevisionRange{From: "main", To: "feature"}},
+		{"main...feature", RevisionRange{From: "main", To: "feature", MergeBase: true}},
+		{"main..", RevisionRange{From: "main", To: "HEAD"}},
+		{"...feature", RevisionRange{From: "HEAD", To: "feature", MergeBase: true}},
+	}
+	for _, tt := range tests {
+		got, err := parseRevisionRange(tt.arg)
+		if err != nil {
+			t.Errorf("parseRevisionRange(%q) error: %v", tt.arg, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseRevisionRange(%q) = %+v, want %+v", tt.arg, got, tt.want)
+		}
+	}
+
+	if got := (RevisionRange{From: "main", To: "feature", MergeBase: true}).String(); got != "main...feature" {
+		t.Errorf("String() = %q, want main...feature", got)
+	}
+
+	for _, arg := range []string{"", "..", "..."} {
+		if _, err := parseRevisionRange(arg); err == nil {
+			t.Errorf("parseRevisionRange(%q) should fail", arg)
+		}
+	}
+}
+
+func headHash(t *testing.T, gitService *GitService) plumbing.Hash {
+	t.Helper()
+	head, err := gitService.repo.Head()
+	if err != nil {
+		t.Fatalf("get HEAD: %v", err)
+	}
+	return head.Hash()
+}
+
+// setupDivergedRepo creates master and feature branches that diverge after the first commit
+func setupDivergedRepo(t *testing.T) (*GitService, string) {
+	t.Helper()
+	gitService, root := setupTempGitService(t, map[string]string{"shared.txt": "one\n"})
+	base := headHash(t, gitService)
+
+	writeWorktreeFile(t, root, "shared.txt", "two\n")
+	commitAll(t, gitService, "master change")
+	if err := gitService.repo.Storer.SetReference(plumbing.NewHashReference("refs/tags/v2", headHash(t, gitService))); err != nil {
+		t.Fatalf("tag v2: %v", err)
+	}
+
+	worktree, err := gitService.repo.Worktree()
+	if err != nil {
+		t.Fatalf("get worktree: %v", err)
+	}
+	if err := worktree.Checkout(&git.CheckoutOptions{Hash: base, Branch: "refs/heads/feature", Create: true}); err != nil {
+		t.Fatalf("checkout feature: %v", err)
+	}
+	writeWorktreeFile(t, root, "feature.txt", "feature\n")
+	commitAll(t, gitService, "feature change")
+	return gitService, root
+}
+
+func refCompareChanges(t *testing.T, gitService *GitService, arg string) map[string]ChangeType {
+	t.Helper()
+	revisions, err := parseRevisionRange(arg)
+	if err != nil {
+		t.Fatalf("parseRevisionRange(%q): %v", arg, err)
+	}
+	diffs, err := gitService.GetRefCompareDiff(revisions, DiffOnly, DiffOptions{Context: DefaultDiffContext}, Pathspec{}, newDefaultLogger(ERROR))
+	if err != nil {
+		t.Fatalf("GetRefCompareDiff(%q): %v", arg, err)
+	}
+	changes := make(map[string]ChangeType, len(diffs))
+	for _, file := range diffs {
+		changes[file.Path] = file.ChangeType
+	}
+	return changes
+}
+
+func TestGetRefCompareDiff(t *testing.T) {
+	gitService, root := setupDivergedRepo(t)
+
+	twoDot := refCompareChanges(t, gitService, "v2..feature")
+	if len(twoDot) != 2 || twoDot["shared.txt"] != Modified || twoDot["feature.txt"] != Added {
+		t.Errorf("v2..feature = %v, want shared.txt modified and feature.txt added", twoDot)
+	}
+
+	// Three dots diff from the merge base, so master's change to shared.txt is not shown
+	threeDot := refCompareChanges(t, gitService, "v2...feature")
+	if len(threeDot) != 1 || threeDot["feature.txt"] != Added {
+		t.Errorf("v2...feature = %v, want only feature.txt added", threeDot)
+	}
+
+	writeWorktreeFile(t, root, "feature.txt", "feature\nmore\n")
+	worktreeCompare := refCompareChanges(t, gitService, "v2")
+	if len(worktreeCompare) != 2 || worktreeCompare["feature.txt"] != Added {
+		t.Errorf("v2 vs worktree = %v, want shared.txt and feature.txt", worktreeCompare)
+	}
+}
+
+func TestCheckPatchFileArgument(t *testing.T) {
+	gitService, _ := setupTempGitService(t, map[string]string{"a.txt": "a\n"})
+	for _, name := range []string{"HEAD", "master", "HEAD~0.."} {
+		if err := gitService.checkPatchFileArgument(name); err == nil || !strings.Contains(err.Error(), "ambiguous argument") {
+			t.Errorf("%s: err = %v, want an ambiguous argument error", name, err)
+		}
+	}
+	for _, name := range []string{"fix.patch", "./HEAD", "no-such-branch"} {
+		if err := gitService.checkPatchFileArgument(name); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+	// The revision the error suggests is not a file name
+	if err := gitService.ValidateRevisionRange(RevisionRange{From: "HEAD^0"}); err != nil {
+		t.Errorf("HEAD^0 should resolve: %v", err)
+	}
+}
+
+func TestValidateRevisionRangeRejectsUnknownRevision(t *testing.T) {
+	gitService, _ := setupTempGitService(t, map[string]string{"a.txt": "a\n"})
+	if err := gitService.ValidateRevisionRange(RevisionRange{From: "HEAD", To: "no-such-branch"}); err == nil {
+		t.Fatal("expected an error for an unknown revision")
+	}
+	if err := gitService.ValidateRevisionRange(RevisionRange{From: "HEAD"}); err != nil {
+		t.Fatalf("HEAD should resolve: %v", err)
+	}
+}
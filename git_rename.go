@@ -0,0 +1,257 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+)
+
+// RenameOptions selects how deleted and added files are paired into renames
+// and copies, as git diff -M and -C do
+type RenameOptions struct {
+	Detect    bool // pair deleted and added files with similar contents
+	Copies    bool // also pair added files with the modified or deleted files they were copied from
+	Threshold int  // minimum similarity in percent
+}
+
+const (
+	// DefaultRenameThreshold is git's default similarity for renames and copies
+	DefaultRenameThreshold = 50
+	// renameLimit caps the sources and targets compared for inexact renames,
+	// as git's diff.renameLimit; beyond it only identical files are paired
+	renameLimit = 1000
+)
+
+// DefaultRenameOptions detects renames but not copies, as git diff does
+var DefaultRenameOptions = RenameOptions{Detect: true, Threshold: DefaultRenameThreshold}
+
+// parseRenameScore parses the similarity given with -M, -C and their long
+// forms the way git does: digits are a fraction ("5" and "50" are 50%)
+// unless they end with "%"
+func parseRenameScore(value string) (int, error) {
+	if value == "" {
+		return 0, fmt.Errorf("invalid similarity %q: want a number or a percentage", value)
+	}
+	num, scale, dot := 0, 1, false
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case c == '.' && !dot:
+			scale, dot = 1, true
+		case c == '%' && i == len(value)-1:
+			if dot {
+				scale *= 100
+			} else {
+				scale = 100
+			}
+		case c >= '0' && c <= '9':
+			if scale < 100000 {
+				scale *= 10
+				num = num*10 + int(c-'0')
+			}
+		default:
+			return 0, fmt.Errorf("invalid similarity %q: want a number or a percentage", value)
+		}
+	}
+	if num >= scale {
+		return 100, nil
+	}
+	return 100 * num / scale, nil
+}
+
+// renameFile is a file that can take part in a rename or copy
+type renameFile struct {
+	path    string
+	content []byte
+	binary  bool           // marked binary by gitattributes
+	deleted bool           // a source that no longer exists, so it can be renamed
+	lines   map[string]int // occurrences of each line, filled on first use
+	renamed bool           // a deleted source already paired as a rename
+	paired  bool           // a target already paired with a source
+}
+
+// lineCounts counts the lines of the file's content
+func (f *renameFile) lineCounts() map[string]int {
+	if f.lines == nil {
+		f.lines = make(map[string]int)
+		for _, line := range splitLines(string(f.content)) {
+			f.lines[line]++
+		}
+	}
+	return f.lines
+}
+
+// renameDetector collects the files a diff deletes and adds, and the old
+// contents of modified files when copies are detected, then pairs them
+type renameDetector struct {
+	opts    RenameOptions
+	sources []*renameFile
+	targets []*renameFile
+}
+
+func newRenameDetector(opts RenameOptions) *renameDetector {
+	return &renameDetector{opts: opts}
+}
+
+// add records a file of the diff; binary is set when gitattributes mark it
+// binary. Empty files are never paired, as in git.
+func (d *renameDetector) add(path string, changeType ChangeType, oldContent, newContent []byte, binary bool) {
+	if !d.opts.Detect {
+		return
+	}
+	switch {
+	case changeType == Added && len(newContent) > 0:
+		d.targets = append(d.targets, &renameFile{path: path, content: newContent, binary: binary})
+	case changeType == Deleted && len(oldContent) > 0:
+		d.sources = append(d.sources, &renameFile{path: path, content: oldContent, binary: binary, deleted: true})
+	case changeType == Modified && d.opts.Copies && len(oldContent) > 0:
+		d.sources = append(d.sources, &renameFile{path: path, content: oldContent, binary: binary})
+	}
+}
+
+// renamePair is a candidate source and target with their similarity
+type renamePair struct {
+	source, target *renameFile
+	score          int
+}
+
+// apply replaces the added file of each pair found in files with a diff
+// from its source, and drops the deleted files that were renamed
+func (d *renameDetector) apply(files []FileDiff, diffOpts DiffOptions) ([]FileDiff, error) {
+	if len(d.sources) == 0 || len(d.targets) == 0 {
+		return files, nil
+	}
+
+	paired := make(map[string]FileDiff)
+	renamedFrom := make(map[string]bool)
+	for _, pair := range d.candidates() {
+		source, target := pair.source, pair.target
+		if target.paired {
+			continue
+		}
+		changeType := Copied
+		switch {
+		case source.deleted && !source.renamed:
+			changeType = Renamed
+			source.renamed = true
+			renamedFrom[source.path] = true
+		case !d.opts.Copies:
+			continue
+		}
+		target.paired = true
+
+		file, err := newPairedFileDiff(source, target, changeType, pair.score, diffOpts)
+		if err != nil {
+			return nil, err
+		}
+		paired[target.path] = file
+	}
+
+	// A pair keeps the mode its source had and its target has
+	oldModes := make(map[string]filemode.FileMode, len(files))
+	for _, file := range files {
+		oldModes[file.Path] = file.OldMode
+	}
+	result := make([]FileDiff, 0, len(files))
+	for _, file := range files {
+		if pair, ok := paired[file.Path]; ok && file.ChangeType == Added {
+			pair.OldMode, pair.NewMode = oldModes[pair.OldPath], file.NewMode
+			result = append(result, pair)
+			continue
+		}
+		if renamedFrom[file.Path] && file.ChangeType == Deleted {
+			continue
+		}
+		result = append(result, file)
+	}
+	return result, nil
+}
+
+// candidates returns the pairs similar enough to be renames or copies, the
+// most similar first. Too many files to compare leaves only identical ones.
+func (d *renameDetector) candidates() []renamePair {
+	exactOnly := len(d.sources)*len(d.targets) > renameLimit*renameLimit
+
+	var pairs []renamePair
+	for _, target := range d.targets {
+		for _, source := range d.sources {
+			score := 0
+			switch {
+			case bytes.Equal(source.content, target.content):
+				score = 100
+			case !exactOnly:
+				score = similarity(source, target, d.opts.Threshold)
+			}
+			if score >= d.opts.Threshold && score > 0 {
+				pairs = append(pairs, renamePair{source: source, target: target, score: score})
+			}
+		}
+	}
+
+	// Renames win over copies at the same similarity
+	sort.SliceStable(pairs, func(i, j int) bool {
+		a, b := pairs[i], pairs[j]
+		if a.score != b.score {
+			return a.score > b.score
+		}
+		if a.source.deleted != b.source.deleted {
+			return a.source.deleted
+		}
+		if a.target.path != b.target.path {
+			return a.target.path < b.target.path
+		}
+		return a.source.path < b.source.path
+	})
+	return pairs
+}
+
+// similarity returns the size of the lines two files share as a percentage
+// of the larger file, or 0 when the sizes alone rule out threshold
+func similarity(a, b *renameFile, threshold int) int {
+	larger := max(len(a.content), len(b.content))
+	if min(len(a.content), len(b.content))*100 < threshold*larger {
+		return 0
+	}
+
+	small, big := a.lineCounts(), b.lineCounts()
+	if len(small) > len(big) {
+		small, big = big, small
+	}
+	shared := 0
+	for line, count := range small {
+		shared += min(count, big[line]) * (len(line) + 1)
+	}
+	// A missing final newline is not a byte of either file
+	return min(99, 100*min(shared, larger)/larger)
+}
+
+// newPairedFileDiff diffs a renamed or copied file against its source, or
+// summarizes the pair when either side is binary
+func newPairedFileDiff(source, target *renameFile, changeType ChangeType, score int, diffOpts DiffOptions) (FileDiff, error) {
+	if source.binary || target.binary || isBinaryContent(source.content) || isBinaryContent(target.content) {
+		file := newBinaryFileDiff(target.path, changeType, source.content, target.content, true, true)
+		file.OldPath, file.Similarity = source.path, score
+		return *file, nil
+	}
+	hunks, err := computeFileHunks(target.path, source.content, target.content, diffOpts)
+	if err != nil {
+		return FileDiff{}, fmt.Errorf("failed to compute diff for %s: %w", target.path, err)
+	}
+
+	linesAdded, linesRemoved := countHunkLineStats(hunks)
+	return FileDiff{
+		Path:             target.path,
+		OldPath:          source.path,
+		ChangeType:       changeType,
+		Similarity:       score,
+		Hunks:            hunks,
+		LinesAdded:       linesAdded,
+		LinesRemoved:     linesRemoved,
+		OldNoNewlineLine: missingNewlineLine(source.content),
+		NewNoNewlineLine: missingNewlineLine(target.content),
+		Decls:            goDeclChanges(target.path, source.content, target.content, diffOpts),
+		Structure:        structuralDiff(target.path, source.content, target.content, diffOpts),
+	}, nil
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseRenameScore(t *testing.T) {
+	tests := []struct {
+		value string
+		want  int
+	}{
+		{"5", 50},
+		{"50", 50},
+		{"90%", 90},
+		{"5%", 5},
+		{"0.75", 75},
+		{"100%", 100},
+		{"100", 10},
+		{"2", 20},
+	}
+	for _, tt := range tests {
+		if got, err := parseRenameScore(tt.value); err != nil || got != tt.want {
+			t.Errorf("parseRenameScore(%q) = %d, %v; want %d", tt.value, got, err, tt.want)
+		}
+	}
+	for _, value := range []string{"", "abc", "5%0"} {
+		if _, err := parseRenameScore(value); err == nil {
+			t.Errorf("parseRenameScore(%q) should fail", value)
+		}
+	}
+}
+
+// numberedContent returns the content of a file of count numberedLines
+func numberedContent(count int) string {
+	return strings.Join(numberedLines(count), "\n") + "\n"
+}
+
+func TestRenameDetectorPairsFiles(t *testing.T) {
+	original := numberedContent(20)
+	edited := strings.Replace(original, "line j\n", "line ten\n", 1)
+	files := []FileDiff{
+		{Path: "a.txt", ChangeType: Deleted},
+		{Path: "b.txt", ChangeType: Added},
+		{Path: "c.txt", ChangeType: Added},
+		{Path: "m.txt", ChangeType: Modified},
+		{Path: "other.txt", ChangeType: Added},
+	}
+	add := func(d *renameDetector) {
+		d.add("a.txt", Deleted, []byte(original), nil, false)
+		d.add("b.txt", Added, nil, []byte(edited), false)
+		d.add("c.txt", Added, nil, []byte(original), false)
+		d.add("m.txt", Modified, []byte(numberedContent(30)), []byte(numberedContent(31)), false)
+		d.add("other.txt", Added, nil, []byte("unrelated\n"), false)
+	}
+
+	// The identical copy wins the rename; the edited one stays added
+	renames := newRenameDetector(DefaultRenameOptions)
+	add(renames)
+	got, err := renames.apply(files, DiffOptions{Context: 3})
+	if err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	var summary []string
+	for _, file := range got {
+		summary = append(summary, fmt.Sprintf("%s %s<-%q %d%%", GetStatusSymbol(file.ChangeType), file.Path, file.OldPath, file.Similarity))
+	}
+	want := []string{`A b.txt<-"" 0%`, `R c.txt<-"a.txt" 100%`, `M m.txt<-"" 0%`, `A other.txt<-"" 0%`}
+	if strings.Join(summary, ", ") != strings.Join(want, ", ") {
+		t.Errorf("files = %q, want %q", summary, want)
+	}
+
+	// With copies, the edited b.txt is a copy of a.txt, renamed to c.txt
+	renames = newRenameDetector(RenameOptions{Detect: true, Copies: true, Threshold: DefaultRenameThreshold})
+	add(renames)
+	got, _ = renames.apply(files, DiffOptions{Context: 3})
+	if len(got) != 4 || got[0].ChangeType != Copied || got[0].OldPath != "a.txt" || got[0].LinesAdded != 1 || got[0].LinesRemoved != 1 {
+		t.Errorf("b.txt should be copied from a.txt with one line changed: %+v", got)
+	}
+
+	// Above the similarity of the edit, only the identical file is paired
+	renames = newRenameDetector(RenameOptions{Detect: true, Copies: true, Threshold: 99})
+	add(renames)
+	got, _ = renames.apply(files, DiffOptions{Context: 3})
+	if got[0].ChangeType != Added || got[1].ChangeType != Renamed {
+		t.Errorf("b.txt should stay added at 99%%: %+v", got)
+	}
+
+	renames = newRenameDetector(RenameOptions{})
+	add(renames)
+	if got, _ := renames.apply(files, DiffOptions{Context: 3}); len(got) != len(files) {
+		t.Errorf("no files should be paired with detection off: %+v", got)
+	}
+}
+
+func TestGetDiffDetectsRenames(t *testing.T) {
+	content := numberedContent(20)
+	gitService, root := setupTempGitService(t, map[string]string{"old/name.txt": content})
+	logger := newDefaultLogger(ERROR)
+	if err := os.Remove(filepath.Join(root, "old/name.txt")); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	writeWorktreeFile(t, root, "new/name.txt", strings.Replace(content, "line c\n", "line three\n", 1))
+
+	opts := DiffOptions{Context: 1, Renames: DefaultRenameOptions}
+	files, err := gitService.GetDiffWithContext(Unstaged, DiffOnly, opts, Pathspec{}, logger)
+	if err != nil {
+		t.Fatalf("unstaged diff: %v", err)
+	}
+	if len(files) != 1 || files[0].ChangeType != Renamed || files[0].OldPath != "old/name.txt" || files[0].Path != "new/name.txt" {
+		t.Fatalf("files = %+v, want old/name.txt renamed to new/name.txt", files)
+	}
+	if len(files[0].Hunks) != 1 || files[0].LinesAdded != 1 || files[0].LinesRemoved != 1 {
+		t.Errorf("the rename should show only the edited line: %+v", files[0].Hunks)
+	}
+
+	commitAll(t, gitService, "rename")
+	head, err := gitService.repo.Head()
+	if err != nil {
+		t.Fatalf("head: %v", err)
+	}
+	files, err = gitService.GetCommitDiff(head.Hash().String(), DiffOnly, opts, Pathspec{}, logger)
+	if err != nil || len(files) != 1 || files[0].ChangeType != Renamed {
+		t.Errorf("commit diff = %+v, %v; want one rename", files, err)
+	}
+
+	opts.Renames = RenameOptions{}
+	files, err = gitService.GetCommitDiff(head.Hash().String(), DiffOnly, opts, Pathspec{}, logger)
+	if err != nil || len(files) != 2 {
+		t.Errorf("commit diff without rename detection = %+v, %v; want a delete and an add", files, err)
+	}
+}
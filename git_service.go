@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/go-git/go-git/v5"
@@ -45,6 +46,38 @@ func (gs *GitService) GetRootPath() (string, error) {
 	return worktree.Filesystem.Root(), nil
 }
 
+// WorkingDirPrefix returns the working directory relative to the repository
+// root, with forward slashes; empty at the root
+func (gs *GitService) WorkingDirPrefix() (string, error) {
+	root, err := gs.GetRootPath()
+	if err != nil {
+		return "", err
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("failed to get working directory: %w", err)
+	}
+	return repoRelativePrefix(root, cwd)
+}
+
+func repoRelativePrefix(root, dir string) (string, error) {
+	if resolved, err := filepath.EvalSymlinks(root); err == nil {
+		root = resolved
+	}
+	if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+		dir = resolved
+	}
+
+	rel, err := filepath.Rel(root, dir)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s is outside repository %s", dir, root)
+	}
+	if rel == "." {
+		return "", nil
+	}
+	return filepath.ToSlash(rel), nil
+}
+
 // GetCurrentBranch gets the current git branch
 func (gs *GitService) GetCurrentBranch() (string, error) {
 	ref, err := gs.repo.Head()
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/format/index"
+)
+
+var errHunkDoesNotApply = errors.New("hunk does not apply to current content")
+
+// hunkSplice describes replaying a hunk onto one side of a diff: the lines the
+// base must contain at start are replaced by the lines of the other side.
+type hunkSplice struct {
+	start       int // 1-based line where the hunk begins on the base side
+	expected    []string
+	replacement []string
+}
+
+// lineRange is an inclusive range of indices into Hunk.Lines
+type lineRange struct {
+	first int
+	last  int
+}
+
+// wholeHunk selects every line of a hunk
+func wholeHunk(hunk Hunk) lineRange {
+	return lineRange{first: 0, last: len(hunk.Lines) - 1}
+}
+
+func (r lineRange) contains(idx int) bool {
+	return idx >= r.first && idx <= r.last
+}
+
+// forwardSplice applies the selected lines of a hunk to its pre-image (the diff's old side)
+func forwardSplice(hunk Hunk, lines lineRange) hunkSplice {
+	return buildSplice(hunk, hunk.OldStart, LineRemoved, lines)
+}
+
+// reverseSplice removes the selected lines of a hunk from its post-image (the diff's new side)
+func reverseSplice(hunk Hunk, lines lineRange) hunkSplice {
+	return buildSplice(hunk, hunk.NewStart, LineAdded, lines)
+}
+
+// buildSplice turns a hunk into a splice against the side holding baseType lines.
+// Selected changes are applied; unselected base lines stay as context and
+// unselected lines of the other side are dropped, like editing a hunk in git add -p.
+func buildSplice(hunk Hunk, start int, baseType LineType, lines lineRange) hunkSplice {
+	splice := hunkSplice{start: start}
+	for i, line := range hunk.Lines {
+		inBase := line.Type == LineContext || line.Type == baseType
+		if inBase {
+			splice.expected = append(splice.expected, line.Content)
+		}
+		if line.Type == LineContext || inBase != lines.contains(i) {
+			splice.replacement = append(splice.replacement, line.Content)
+		}
+	}
+	return splice
+}
+
+// apply splices the hunk into base. targetEndsWithNewline decides the final
+// newline when the splice reaches the end of base; otherwise base keeps its own.
+func (s hunkSplice) apply(base []byte, targetEndsWithNewline bool) ([]byte, error) {
+	baseLines := splitLines(string(base))
+	begin := max(0, s.start-1)
+	if len(s.expected) == 0 && len(baseLines) == 0 {
+		begin = 0
+	}
+	end := begin + len(s.expected)
+	if end > len(baseLines) {
+		return nil, errHunkDoesNotApply
+	}
+	for i, line := range s.expected {
+		if baseLines[begin+i] != line {
+			return nil, errHunkDoesNotApply
+		}
+	}
+
+	result := make([]string, 0, len(baseLines)-len(s.expected)+len(s.replacement))
+	result = append(result, baseLines[:begin]...)
+	result = append(result, s.replacement...)
+	result = append(result, baseLines[end:]...)
+
+	endsWithNewline := endsWithNewline(base)
+	if end == len(baseLines) {
+		endsWithNewline = targetEndsWithNewline
+	}
+	return joinContentLines(result, endsWithNewline), nil
+}
+
+// joinContentLines is the inverse of splitLines
+func joinContentLines(lines []string, trailingNewline bool) []byte {
+	if len(lines) == 0 {
+		return nil
+	}
+	content := strings.Join(lines, "\n")
+	if trailingNewline {
+		content += "\n"
+	}
+	return []byte(content)
+}
+
+func endsWithNewline(content []byte) bool {
+	return len(content) == 0 || content[len(content)-1] == '\n'
+}
+
+// indexRewrite is the outcome of recomputing an index entry's content
+type indexRewrite struct {
+	content []byte
+	remove  bool // drop the entry instead of writing content
+}
+
+// StageHunk applies a hunk of the unstaged (index → worktree) diff to the index
+func (gs *GitService) StageHunk(path string, hunk Hunk, logger *Logger) error {
+	return gs.StageHunkLines(path, hunk, wholeHunk(hunk), logger)
+}
+
+// StageHunkLines applies only the selected lines of an unstaged hunk to the index
+func (gs *GitService) StageHunkLines(path string, hunk Hunk, lines lineRange, logger *Logger) error {
+	worktreeContent, worktreeExists, err := gs.readWorktreeFileForStaging(path, logger)
+	if err != nil {
+		return err
+	}
+
+	return gs.rewriteIndexEntry(path, logger, func(indexContent []byte) (indexRewrite, error) {
+		updated, err := forwardSplice(hunk, lines).apply(indexContent, endsWithNewline(worktreeContent))
+		if err != nil {
+			return indexRewrite{}, err
+		}
+		return indexRewrite{content: updated, remove: len(updated) == 0 && !worktreeExists}, nil
+	})
+}
+
+// UnstageHunk reverts a hunk of the staged (HEAD → index) diff in the index
+func (gs *GitService) UnstageHunk(path string, hunk Hunk, logger *Logger) error {
+	return gs.UnstageHunkLines(path, hunk, wholeHunk(hunk), logger)
+}
+
+// UnstageHunkLines reverts only the selected lines of a staged hunk in the index
+func (gs *GitService) UnstageHunkLines(path string, hunk Hunk, lines lineRange, logger *Logger) error {
+	headContent, headExists, err := gs.readHeadFileForStaging(path, logger)
+	if err != nil {
+		return err
+	}
+
+	return gs.rewriteIndexEntry(path, logger, func(indexContent []byte) (indexRewrite, error) {
+		updated, err := reverseSplice(hunk, lines).apply(indexContent, endsWithNewline(headContent))
+		if err != nil {
+			return indexRewrite{}, err
+		}
+		return indexRewrite{content: updated, remove: len(updated) == 0 && !headExists}, nil
+	})
+}
+
+// rewriteIndexEntry loads the index content of path, lets rewrite compute the new
+// content, and stores the result as a new blob referenced by the index entry.
+func (gs *GitService) rewriteIndexEntry(path string, logger *Logger, rewrite func([]byte) (indexRewrite, error)) error {
+	idx, err := gs.repo.Storer.Index()
+	if err != nil {
+		return fmt.Errorf("failed to get index: %w", err)
+	}
+
+	current, err := gs.readIndexContentIfPresent(path, idx, logger, "failed to read file %s from index: %w")
+	if err != nil {
+		return err
+	}
+
+	outcome, err := rewrite(current)
+	if err != nil {
+		return fmt.Errorf("update index for %s: %w", path, err)
+	}
+
+	if outcome.remove {
+		if _, err := idx.Remove(path); err != nil && !errors.Is(err, index.ErrEntryNotFound) {
+			return fmt.Errorf("failed to remove %s from index: %w", path, err)
+		}
+		return gs.writeIndex(idx)
+	}
+
+	blobHash, err := gs.writeBlob(outcome.content)
+	if err != nil {
+		return fmt.Errorf("failed to write blob for %s: %w", path, err)
+	}
+
+	entry, err := gs.indexEntryForUpdate(idx, path)
+	if err != nil {
+		return err
+	}
+	entry.Hash = blobHash
+	entry.Size = uint32(len(outcome.content))
+	// Clear stat data so git re-hashes the worktree file instead of trusting a
+	// cached match: the index no longer mirrors the worktree after partial staging.
+	entry.CreatedAt = time.Time{}
+	entry.ModifiedAt = time.Time{}
+
+	return gs.writeIndex(idx)
+}
+
+// indexEntryForUpdate returns the entry for path, creating one for untracked files
+func (gs *GitService) indexEntryForUpdate(idx *index.Index, path string) (*index.Entry, error) {
+	entry, err := idx.Entry(path)
+	if err == nil {
+		return entry, nil
+	}
+	if !errors.Is(err, index.ErrEntryNotFound) {
+		return nil, fmt.Errorf("failed to look up %s in index: %w", path, err)
+	}
+
+	entry = idx.Add(path)
+	entry.Mode = filemode.Regular
+	if worktree, err := gs.repo.Worktree(); err == nil {
+		if info, err := worktree.Filesystem.Lstat(path); err == nil {
+			if mode, err := filemode.NewFromOSFileMode(info.Mode()); err == nil {
+				entry.Mode = mode
+			}
+		}
+	}
+	return entry, nil
+}
+
+func (gs *GitService) writeBlob(content []byte) (plumbing.Hash, error) {
+	obj := gs.repo.Storer.NewEncodedObject()
+	obj.SetType(plumbing.BlobObject)
+	obj.SetSize(int64(len(content)))
+
+	writer, err := obj.Writer()
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	if _, err := writer.Write(content); err != nil {
+		writer.Close()
+		return plumbing.ZeroHash, err
+	}
+	if err := writer.Close(); err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	return gs.repo.Storer.SetEncodedObject(obj)
+}
+
+func (gs *GitService) writeIndex(idx *index.Index) error {
+	if err := gs.repo.Storer.SetIndex(idx); err != nil {
+		return fmt.Errorf("failed to write index: %w", err)
+	}
+	return nil
+}
+
+// readWorktreeFileForStaging reads the worktree side of a file being staged
+func (gs *GitService) readWorktreeFileForStaging(path string, logger *Logger) ([]byte, bool, error) {
+	worktree, err := gs.repo.Worktree()
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get worktree: %w", err)
+	}
+	return gs.readFileFromWorktree(worktree, path, logger)
+}
+
+// readHeadFileForStaging reads the HEAD side of a file being unstaged
+func (gs *GitService) readHeadFileForStaging(path string, logger *Logger) ([]byte, bool, error) {
+	headCommit, shouldSkip, err := gs.getHeadCommitForDiffMode(Staged, logger)
+	if err != nil {
+		return nil, false, err
+	}
+	if shouldSkip {
+		return nil, false, nil
+	}
+	return gs.readFileFromCommit(headCommit, path, logger)
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// setupTempGitService creates a repository in a temp dir with files committed on HEAD
+func setupTempGitService(t *testing.T, files map[string]string) (*GitService, string) {
+	t.Helper()
+
+	root := t.TempDir()
+	repo, err := git.PlainInit(root, false)
+	if err != nil {
+		t.Fatalf("init repo: %v", err)
+	}
+	gitService := &GitService{repo: repo}
+
+	for path, content := range files {
+		writeWorktreeFile(t, root, path, content)
+	}
+	commitAll(t, gitService, "initial")
+	return gitService, root
+}
+
+func writeWorktreeFile(t *testing.T, root, path, content string) {
+	t.Helper()
+	fullPath := filepath.Join(root, path)
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0o755); err != nil {
+		t.Fatalf("create dir for %s: %v", path, err)
+	}
+	if err := os.WriteFile(fullPath, []byte(content), 0o644); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+}
+
+func commitAll(t *testing.T, gitService *GitService, message string) {
+	t.Helper()
+	worktree, err := gitService.repo.Worktree()
+	if err != nil {
+		t.Fatalf("get worktree: %v", err)
+	}
+	if err := worktree.AddGlob("."); err != nil {
+		t.Fatalf("stage files: %v", err)
+	}
+	_, err = worktree.Commit(message, &git.CommitOptions{
+		Author: &object.Signature{Name: "Test", Email: "test@example.com", When: time.Now()},
+	})
+	if err != nil {
+		t.Fatalf("commit: %v", err)
+	}
+}
+
+func readIndexFile(t *testing.T, gitService *GitService, path string) string {
+	t.Helper()
+	idx, err := gitService.repo.Storer.Index()
+	if err != nil {
+		t.Fatalf("get index: %v", err)
+	}
+	content, err := gitService.readIndexContentIfPresent(path, idx, newDefaultLogger(ERROR), "read %s: %w")
+	if err != nil {
+		t.Fatalf("read %s from index: %v", path, err)
+	}
+	return string(content)
+}
+
+func numberedLines(count int) []string {
+	lines := make([]string, count)
+	for i := range lines {
+		lines[i] = "line " + string(rune('a'+i))
+	}
+	return lines
+}
+
+func diffForPath(t *testing.T, gitService *GitService, mode DiffMode, path string) FileDiff {
+	t.Helper()
+	diffs, err := gitService.GetDiffWithContext(mode, DiffOnly, DiffOptions{Context: DefaultDiffContext}, Pathspec{}, newDefaultLogger(ERROR))
+	if err != nil {
+		t.Fatalf("get diff: %v", err)
+	}
+	file, found := findFileDiffByPath(diffs, path)
+	if !found {
+		t.Fatalf("no %v diff for %s", mode, path)
+	}
+	return file
+}
+
+func TestStageAndUnstageHunk(t *testing.T) {
+	original := strings.Join(numberedLines(20), "\n") + "\n"
+	gitService, root := setupTempGitService(t, map[string]string{"file.txt": original})
+
+	lines := numberedLines(20)
+	lines[1] = "changed b"
+	lines[17] = "changed r"
+	modified := strings.Join(lines, "\n") + "\n"
+	writeWorktreeFile(t, root, "file.txt", modified)
+
+	unstaged := diffForPath(t, gitService, Unstaged, "file.txt")
+	if len(unstaged.Hunks) != 2 {
+		t.Fatalf("expected 2 unstaged hunks, got %d", len(unstaged.Hunks))
+	}
+
+	logger := newDefaultLogger(ERROR)
+	if err := gitService.StageHunk("file.txt", unstaged.Hunks[1], logger); err != nil {
+		t.Fatalf("StageHunk: %v", err)
+	}
+
+	staged := numberedLines(20)
+	staged[17] = "changed r"
+	if got, want := readIndexFile(t, gitService, "file.txt"), strings.Join(staged, "\n")+"\n"; got != want {
+		t.Fatalf("index after staging = %q, want %q", got, want)
+	}
+	if remaining := diffForPath(t, gitService, Unstaged, "file.txt"); len(remaining.Hunks) != 1 {
+		t.Fatalf("expected 1 unstaged hunk after staging, got %d", len(remaining.Hunks))
+	}
+
+	stagedDiff := diffForPath(t, gitService, Staged, "file.txt")
+	if len(stagedDiff.Hunks) != 1 {
+		t.Fatalf("expected 1 staged hunk, got %d", len(stagedDiff.Hunks))
+	}
+	if err := gitService.UnstageHunk("file.txt", stagedDiff.Hunks[0], logger); err != nil {
+		t.Fatalf("UnstageHunk: %v", err)
+	}
+	if got := readIndexFile(t, gitService, "file.txt"); got != original {
+		t.Fatalf("index after unstaging = %q, want %q", got, original)
+	}
+}
+
+func TestStageHunkForNewAndDeletedFiles(t *testing.T) {
+	gitService, root := setupTempGitService(t, map[string]string{"keep.txt": "keep\n", "gone.txt": "gone\n"})
+	logger := newDefaultLogger(ERROR)
+
+	writeWorktreeFile(t, root, "new.txt", "first\nsecond")
+	added := diffForPath(t, gitService, Unstaged, "new.txt")
+	if err := gitService.StageHunk("new.txt", added.Hunks[0], logger); err != nil {
+		t.Fatalf("StageHunk new file: %v", err)
+	}
+	if got := readIndexFile(t, gitService, "new.txt"); got != "first\nsecond" {
+		t.Fatalf("new file index content = %q", got)
+	}
+
+	stagedAdd := diffForPath(t, gitService, Staged, "new.txt")
+	if err := gitService.UnstageHunk("new.txt", stagedAdd.Hunks[0], logger); err != nil {
+		t.Fatalf("UnstageHunk new file: %v", err)
+	}
+	idx, err := gitService.repo.Storer.Index()
+	if err != nil {
+		t.Fatalf("get index: %v", err)
+	}
+	if _, err := idx.Entry("new.txt"); err == nil {
+		t.Fatal("expected new.txt to be removed from the index after unstaging its only hunk")
+	}
+
+	if err := os.Remove(filepath.Join(root, "gone.txt")); err != nil {
+		t.Fatalf("remove gone.txt: %v", err)
+	}
+	deleted := diffForPath(t, gitService, Unstaged, "gone.txt")
+	if err := gitService.StageHunk("gone.txt", deleted.Hunks[0], logger); err != nil {
+		t.Fatalf("StageHunk deleted file: %v", err)
+	}
+	if got := readIndexFile(t, gitService, "gone.txt"); got != "" {
+		t.Fatalf("expected gone.txt to leave the index, still has %q", got)
+	}
+}
+
+func TestStageHunkRejectsStaleHunk(t *testing.T) {
+	gitService, root := setupTempGitService(t, map[string]string{"file.txt": "a\nb\nc\n"})
+	writeWorktreeFile(t, root, "file.txt", "a\nB\nc\n")
+	hunk := diffForPath(t, gitService, Unstaged, "file.txt").Hunks[0]
+
+	// Stage it once so the hunk no longer matches the index
+	logger := newDefaultLogger(ERROR)
+	if err := gitService.StageHunk("file.txt", hunk, logger); err != nil {
+		t.Fatalf("StageHunk: %v", err)
+	}
+	err := gitService.StageHunk("file.txt", hunk, logger)
+	if !errors.Is(err, errHunkDoesNotApply) {
+		t.Fatalf("expected errHunkDoesNotApply, got %v", err)
+	}
+}
+
+func TestHunkSpliceFinalNewline(t *testing.T) {
+	hunk := Hunk{OldStart: 1, NewStart: 1, Lines: []DiffLine{
+		{Type: LineContext, Content: "a"},
+		{Type: LineRemoved, Content: "b"},
+		{Type: LineAdded, Content: "c"},
+	}}
+
+	got, err := forwardSplice(hunk, wholeHunk(hunk)).apply([]byte("a\nb\n"), false)
+	if err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	if string(got) != "a\nc" {
+		t.Fatalf("splice at EOF should take target newline state, got %q", got)
+	}
+
+	got, err = forwardSplice(hunk, wholeHunk(hunk)).apply([]byte("a\nb\nz"), true)
+	if err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	if string(got) != "a\nc\nz" {
+		t.Fatalf("splice before EOF should keep base newline state, got %q", got)
+	}
+}
+
+func TestStageAndUnstageSelectedLines(t *testing.T) {
+	gitService, root := setupTempGitService(t, map[string]string{"file.txt": "a\nb\nc\nd\n"})
+	writeWorktreeFile(t, root, "file.txt", "a\nB\nC\nd\ne\n")
+	logger := newDefaultLogger(ERROR)
+
+	// Lines: a, -b, -c, +B, +C, d, +e
+	hunk := diffForPath(t, gitService, Unstaged, "file.txt").Hunks[0]
+	if len(hunk.Lines) != 7 {
+		t.Fatalf("unexpected hunk shape: %+v", hunk.Lines)
+	}
+
+	// Select "-c" and "+B": c is removed and B is inserted where the unselected b stays
+	if err := gitService.StageHunkLines("file.txt", hunk, lineRange{first: 2, last: 3}, logger); err != nil {
+		t.Fatalf("StageHunkLines: %v", err)
+	}
+	if got := readIndexFile(t, gitService, "file.txt"); got != "a\nb\nB\nd\n" {
+		t.Fatalf("index after partial staging = %q", got)
+	}
+
+	// Unstage only the added "B" again
+	staged := diffForPath(t, gitService, Staged, "file.txt").Hunks[0]
+	addedIdx := -1
+	for i, line := range staged.Lines {
+		if line.Type == LineAdded && line.Content == "B" {
+			addedIdx = i
+		}
+	}
+	if err := gitService.UnstageHunkLines("file.txt", staged, lineRange{first: addedIdx, last: addedIdx}, logger); err != nil {
+		t.Fatalf("UnstageHunkLines: %v", err)
+	}
+	if got := readIndexFile(t, gitService, "file.txt"); got != "a\nb\nd\n" {
+		t.Fatalf("index after partial unstaging = %q", got)
+	}
+}
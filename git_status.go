@@ -72,8 +72,8 @@ func changeTypeForMode(mode DiffMode, status git.Status, path string, fileStatus
 	return statusCodeToChangeType(statusCodeForMode(mode, *fileStatus))
 }
 
-// GetChangedFiles gets a list of changed files (for tree view)
-func (gs *GitService) GetChangedFiles(mode DiffMode) ([]FileDiff, error) {
+// GetChangedFiles gets a list of changed files matching pathspec (for tree view)
+func (gs *GitService) GetChangedFiles(mode DiffMode, pathspec Pathspec) ([]FileDiff, error) {
 	worktree, err := gs.repo.Worktree()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get worktree: %w", err)
@@ -87,7 +87,7 @@ func (gs *GitService) GetChangedFiles(mode DiffMode) ([]FileDiff, error) {
 		return nil, fmt.Errorf("failed to get git status: %w", err)
 	}
 
-	paths := sortedStatusPaths(status)
+	paths := pathspec.filter(sortedStatusPaths(status))
 
 	var files []FileDiff
 	for _, path := range paths {
@@ -263,7 +263,7 @@ func TestComputeHunksWithContextIncludesLeadingLines(t *testing.T) {
 	oldLines := []string{"a", "b", "c", "d", "e"}
 	newLines := []string{"a", "b", "X", "d", "e"}
 
-	hunks, err := computeHunksWithContext(oldLines, newLines, 1)
+	hunks, err := computeHunksWithContext(oldLines, newLines, DiffOptions{Context: 1})
 	if err != nil {
 		t.Fatalf("computeHunksWithContext() error = %v", err)
 	}
@@ -286,11 +286,28 @@ func TestComputeHunksWithContextIncludesLeadingLines(t *testing.T) {
 	}
 }
 
+func TestComputeHunksMergesCloseChanges(t *testing.T) {
+	oldLines := []string{"a", "b", "c", "d", "e", "f", "g"}
+	newLines := []string{"A", "b", "c", "d", "e", "f", "G"}
+
+	// Five equal lines between the changes are less than twice the context:
+	// one hunk, as git shows it, rather than two overlapping ones
+	hunks, _ := computeHunksWithContext(oldLines, newLines, DiffOptions{Context: 3})
+	if len(hunks) != 1 || hunks[0].OldCount != 7 {
+		t.Fatalf("context 3: hunks = %+v, want one hunk over 7 lines", hunks)
+	}
+
+	hunks, _ = computeHunksWithContext(oldLines, newLines, DiffOptions{Context: 2})
+	if len(hunks) != 2 || hunks[0].OldCount != 3 || hunks[1].OldStart != 5 {
+		t.Fatalf("context 2: hunks = %+v, want -1,3 and -5,3", hunks)
+	}
+}
+
 func TestComputeHunksWithLargeContextBehavesLikeWholeFile(t *testing.T) {
 	oldLines := []string{"a", "b", "c", "d"}
 	newLines := []string{"a", "B", "c", "d"}
 
-	hunks, err := computeHunksWithContext(oldLines, newLines, WholeFileContext)
+	hunks, err := computeHunksWithContext(oldLines, newLines, DiffOptions{Context: WholeFileContext})
 	if err != nil {
 		t.Fatalf("computeHunksWithContext() error = %v", err)
 	}
@@ -376,7 +393,7 @@ func TestGetChangedFiles(t *testing.T) {
 
 	for _, mode := range modes {
 		t.Run(mode.String(), func(t *testing.T) {
-			files, err := gitService.GetChangedFiles(mode)
+			files, err := gitService.GetChangedFiles(mode, Pathspec{})
 			if err != nil {
 				t.Fatalf("GetChangedFiles(%v) error = %v", mode, err)
 			}
@@ -468,7 +485,7 @@ func TestGetUnifiedBranchCompareDiffSkipsLargeUntrackedFile(t *testing.T) {
 		t.Fatalf("failed to create large test file: %v", err)
 	}
 
-	diffs, err := gitService.GetUnifiedBranchCompareDiff(DiffOnly, DefaultDiffContext, logger)
+	diffs, err := gitService.GetUnifiedBranchCompareDiff("", DiffOnly, DiffOptions{Context: DefaultDiffContext}, Pathspec{}, logger)
 	if err != nil {
 		t.Fatalf("GetUnifiedBranchCompareDiff should skip large files, got error: %v", err)
 	}
@@ -0,0 +1,157 @@
+package main
+
+import "strings"
+
+// WhitespaceMode selects which whitespace differences are ignored when lines
+// are compared. Each mode ignores everything the one before it does.
+type WhitespaceMode int
+
+const (
+	WhitespaceExact   WhitespaceMode = iota // every difference counts
+	IgnoreCRAtEOL                           // --ignore-cr-at-eol: a carriage return at the end of a line
+	IgnoreSpaceChange                       // -b: the amount of whitespace, and whitespace at the end of a line
+	IgnoreAllSpace                          // -w: all whitespace
+)
+
+var whitespaceModeLabels = []string{"Exact whitespace", "Ignore CR at EOL", "Ignore space change", "Ignore all space"}
+
+// Label returns the name shown in the UI
+func (w WhitespaceMode) Label() string {
+	if w < 0 || int(w) >= len(whitespaceModeLabels) {
+		return whitespaceModeLabels[WhitespaceExact]
+	}
+	return whitespaceModeLabels[w]
+}
+
+// Next returns the mode after w, wrapping around
+func (w WhitespaceMode) Next() WhitespaceMode {
+	return (w + 1) % WhitespaceMode(len(whitespaceModeLabels))
+}
+
+// isDiffSpace reports whether c is whitespace as git counts it
+func isDiffSpace(c byte) bool {
+	switch c {
+	case ' ', '\t', '\r', '\f', '\v':
+		return true
+	}
+	return false
+}
+
+// normalize returns the form of line that is compared in mode w
+func (w WhitespaceMode) normalize(line string) string {
+	switch w {
+	case IgnoreCRAtEOL:
+		return strings.TrimSuffix(line, "\r")
+	case IgnoreSpaceChange:
+		var b strings.Builder
+		b.Grow(len(line))
+		for i := 0; i < len(line); {
+			if !isDiffSpace(line[i]) {
+				b.WriteByte(line[i])
+				i++
+				continue
+			}
+			for i < len(line) && isDiffSpace(line[i]) {
+				i++
+			}
+			if i < len(line) {
+				b.WriteByte(' ')
+			}
+		}
+		return b.String()
+	case IgnoreAllSpace:
+		var b strings.Builder
+		b.Grow(len(line))
+		for i := 0; i < len(line); i++ {
+			if !isDiffSpace(line[i]) {
+				b.WriteByte(line[i])
+			}
+		}
+		return b.String()
+	default:
+		return line
+	}
+}
+
+// normalizeLines returns the compared form of lines, or lines itself when
+// every difference counts
+func (w WhitespaceMode) normalizeLines(lines []string) []string {
+	if w == WhitespaceExact {
+		return lines
+	}
+	keys := make([]string, len(lines))
+	for i, line := range lines {
+		keys[i] = w.normalize(line)
+	}
+	return keys
+}
+
+// isBlank reports whether --ignore-blank-lines counts line as blank: empty,
+// or only whitespace when whitespace is ignored, as in git
+func (w WhitespaceMode) isBlank(line string) bool {
+	if w == WhitespaceExact {
+		return line == ""
+	}
+	for i := 0; i < len(line); i++ {
+		if !isDiffSpace(line[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// hideBlankLineChanges marks the changes that only add or remove blank lines
+// as hidden, unless they are fewer than contextLines lines away from a shown
+// change: there they stay in its hunk, as with git diff --ignore-blank-lines.
+func hideBlankLineChanges(lineDiffs []lineDiff, contextLines int, whitespace WhitespaceMode) []lineDiff {
+	// A change is a run of removed and added lines between equal runs
+	type change struct {
+		first, last int // indexes into lineDiffs
+		shown       bool
+	}
+	var changes []change
+	for i := 0; i < len(lineDiffs); i++ {
+		if lineDiffs[i].Type == diffEqual {
+			continue
+		}
+		c := change{first: i, last: i}
+		for c.last+1 < len(lineDiffs) && lineDiffs[c.last+1].Type != diffEqual {
+			c.last++
+		}
+		for j := c.first; j <= c.last && !c.shown; j++ {
+			for _, line := range lineDiffs[j].Lines {
+				if !whitespace.isBlank(line) {
+					c.shown = true
+					break
+				}
+			}
+		}
+		changes = append(changes, c)
+		i = c.last
+	}
+
+	// Consecutive changes are separated by one run of equal lines
+	gapAfter := func(k int) int {
+		return len(lineDiffs[changes[k].last+1].Lines)
+	}
+	for k := 1; k < len(changes); k++ {
+		if !changes[k].shown && changes[k-1].shown && gapAfter(k-1) < contextLines {
+			changes[k].shown = true
+		}
+	}
+	for k := len(changes) - 2; k >= 0; k-- {
+		if !changes[k].shown && changes[k+1].shown && gapAfter(k) < contextLines {
+			changes[k].shown = true
+		}
+	}
+
+	for _, c := range changes {
+		if c.shown {
+			continue
+		}
+		for j := c.first; j <= c.last; j++ {
+			lineDiffs[j].hidden = true
+		}
+	}
+	return lineDiffs
+}
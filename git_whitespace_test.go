@@ -0,0 +1,82 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestWhitespaceNormalize(t *testing.T) {
+	tests := []struct {
+		mode WhitespaceMode
+		a, b string
+		same bool
+	}{
+		{WhitespaceExact, "x := 1", "x  := 1", false},
+		{IgnoreCRAtEOL, "x := 1\r", "x := 1", true},
+		{IgnoreCRAtEOL, "x :=\r 1", "x := 1", false},
+		{IgnoreSpaceChange, "\tx  :=  1  ", "  x := 1", true},
+		{IgnoreSpaceChange, "x:=1", "x := 1", false},
+		{IgnoreSpaceChange, "x", " x", false},
+		{IgnoreAllSpace, "\tx:=1", "x := 1 \r", true},
+		{IgnoreAllSpace, "x := 1", "x := 2", false},
+	}
+	for _, tt := range tests {
+		if same := tt.mode.normalize(tt.a) == tt.mode.normalize(tt.b); same != tt.same {
+			t.Errorf("%s: %q and %q equal = %v, want %v", tt.mode.Label(), tt.a, tt.b, same, tt.same)
+		}
+	}
+	if IgnoreAllSpace.Next() != WhitespaceExact {
+		t.Error("whitespace modes should cycle back to exact")
+	}
+}
+
+func TestComputeHunksIgnoringWhitespace(t *testing.T) {
+	// Reindented with tabs, and one real edit
+	oldLines := []string{"func f() {", "    x := 1", "    return x", "}"}
+	newLines := []string{"func f() {", "\tx := 1", "\treturn x + 1", "}"}
+
+	hunks, _ := computeHunksWithContext(oldLines, newLines, DiffOptions{Context: 3, Whitespace: IgnoreAllSpace})
+	if len(hunks) != 1 {
+		t.Fatalf("hunks = %+v, want 1", hunks)
+	}
+	var got []string
+	for _, line := range hunks[0].Lines {
+		got = append(got, line.Content)
+	}
+	// Context lines show the new file's text, as in git diff -w
+	want := []string{"func f() {", "\tx := 1", "    return x", "\treturn x + 1", "}"}
+	if !slices.Equal(got, want) {
+		t.Errorf("lines = %q, want %q", got, want)
+	}
+
+	if hunks, _ := computeHunksWithContext(oldLines[:2], newLines[:2], DiffOptions{Context: 3, Whitespace: IgnoreSpaceChange}); len(hunks) != 0 {
+		t.Errorf("reindented lines should not differ with -b: %+v", hunks)
+	}
+}
+
+func TestComputeHunksIgnoringBlankLines(t *testing.T) {
+	oldLines := []string{"a", "", "b", "c", "d", "e", "f", "g"}
+	newLines := []string{"a", "b", "c", "d", "e", "f", "G", "", ""}
+	opts := DiffOptions{Context: 1, IgnoreBlankLines: true}
+
+	// The removed blank line is far from the edit and left out; the blank
+	// lines added with G are part of a real change
+	hunks, _ := computeHunksWithContext(oldLines, newLines, opts)
+	if len(hunks) != 1 || hunks[0].OldStart != 7 || hunks[0].NewStart != 6 || hunks[0].NewCount != 4 {
+		t.Fatalf("hunks = %+v, want @@ -7,2 +6,4 @@", hunks)
+	}
+	if added, removed := countHunkLineStats(hunks); added != 3 || removed != 1 {
+		t.Errorf("stats = +%d/-%d, want +3/-1", added, removed)
+	}
+
+	// Closer to the edit than the context size, the blank line is shown
+	opts.Context = 6
+	hunks, _ = computeHunksWithContext(oldLines, newLines, opts)
+	if len(hunks) != 1 || hunks[0].OldStart != 1 {
+		t.Errorf("hunks = %+v, want one hunk from line 1", hunks)
+	}
+
+	if hunks, _ := computeHunksWithContext([]string{"a", "b"}, []string{"a", "", "b"}, opts); len(hunks) != 0 {
+		t.Errorf("an added blank line alone should be hidden: %+v", hunks)
+	}
+}
@@ -0,0 +1,293 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/draw"
+	"image/png"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// imageProtocol is how images are drawn in the terminal
+type imageProtocol int
+
+const (
+	imageBlocks imageProtocol = iota // half-block characters, any color terminal
+	imageKitty                       // kitty graphics protocol (kitty, Ghostty)
+	imageITerm                       // iTerm2 inline images (iTerm2, WezTerm)
+	imageSixel                       // DEC sixel graphics (foot, mlterm, xterm -ti vt340)
+)
+
+var imageProtocolNames = []string{"blocks", "kitty", "iterm", "sixel"}
+
+func (p imageProtocol) String() string {
+	return imageProtocolNames[p]
+}
+
+// parseImageProtocol parses an --image-protocol value; "auto" returns nil so
+// the protocol is detected from the terminal
+func parseImageProtocol(name string) (*imageProtocol, error) {
+	if name == "auto" {
+		return nil, nil
+	}
+	for i, known := range imageProtocolNames {
+		if name == known {
+			protocol := imageProtocol(i)
+			return &protocol, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid image protocol %q: want auto, kitty, iterm, sixel or blocks", name)
+}
+
+// detectImageProtocol guesses the graphics the terminal supports from the
+// environment. Inside tmux or screen, which do not pass graphics through by
+// default, it falls back to half-blocks.
+func detectImageProtocol(getenv func(string) string) imageProtocol {
+	term, program := getenv("TERM"), getenv("TERM_PROGRAM")
+	switch {
+	case getenv("TMUX") != "" || strings.HasPrefix(term, "screen") || strings.HasPrefix(term, "tmux"):
+		return imageBlocks
+	case getenv("KITTY_WINDOW_ID") != "" || term == "xterm-kitty" || term == "xterm-ghostty" || program == "ghostty":
+		return imageKitty
+	case program == "iTerm.app" || program == "WezTerm":
+		return imageITerm
+	case strings.HasPrefix(term, "foot") || strings.HasPrefix(term, "mlterm") || strings.Contains(term, "sixel"):
+		return imageSixel
+	default:
+		return imageBlocks
+	}
+}
+
+// fitCells returns the columns and rows an image of width by height pixels
+// takes when scaled to fit cols by rows cells. A cell is taken to be twice as
+// tall as it is wide, as in most terminal fonts.
+func fitCells(width, height, cols, rows int) (int, int) {
+	if width <= 0 || height <= 0 || cols <= 0 || rows <= 0 {
+		return 0, 0
+	}
+	scale := math.Min(float64(cols)/float64(width), float64(2*rows)/float64(height))
+	fitCols := min(cols, max(1, int(math.Round(float64(width)*scale))))
+	fitRows := min(rows, max(1, int(math.Ceil(float64(height)*scale/2))))
+	return fitCols, fitRows
+}
+
+// resample scales img to width by height pixels, averaging a grid of samples
+// under each pixel so that downscaled screenshots keep their detail
+func resample(img image.Image, width, height int) *image.NRGBA {
+	const samples = 3
+	bounds := img.Bounds()
+	out := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			var r, g, b, a uint32
+			for sy := 0; sy < samples; sy++ {
+				for sx := 0; sx < samples; sx++ {
+					px := bounds.Min.X + (x*samples+sx)*bounds.Dx()/(width*samples)
+					py := bounds.Min.Y + (y*samples+sy)*bounds.Dy()/(height*samples)
+					pr, pg, pb, pa := img.At(px, py).RGBA()
+					r, g, b, a = r+pr, g+pg, b+pb, a+pa
+				}
+			}
+			const n = samples * samples
+			premultiplied := color.RGBA64{R: uint16(r / n), G: uint16(g / n), B: uint16(b / n), A: uint16(a / n)}
+			out.SetNRGBA(x, y, color.NRGBAModel.Convert(premultiplied).(color.NRGBA))
+		}
+	}
+	return out
+}
+
+// checkerColor is the background shown through transparent pixels: a
+// checkerboard of 4 pixel squares, as image editors draw it
+func checkerColor(x, y int) uint32 {
+	if (x/4+y/4)%2 == 0 {
+		return 0xcc
+	}
+	return 0x99
+}
+
+// flatten draws img over the transparency checkerboard
+func flatten(img *image.NRGBA) *image.RGBA {
+	bounds := img.Bounds()
+	out := image.NewRGBA(bounds)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := img.NRGBAAt(x, y)
+			bg, alpha := checkerColor(x, y), uint32(c.A)
+			blend := func(v uint8) uint8 {
+				return uint8((uint32(v)*alpha + bg*(255-alpha)) / 255)
+			}
+			out.SetRGBA(x, y, color.RGBA{R: blend(c.R), G: blend(c.G), B: blend(c.B), A: 0xff})
+		}
+	}
+	return out
+}
+
+// halfBlockRows draws img in cols by rows cells of "▀", whose foreground is
+// the upper pixel and background the lower one. Rows are padded to width.
+func halfBlockRows(img image.Image, cols, rows, width int) []string {
+	pixels := flatten(resample(img, cols, 2*rows))
+	lines := make([]string, rows)
+	for row := range lines {
+		var line strings.Builder
+		for col := 0; col < cols; col++ {
+			top, bottom := pixels.RGBAAt(col, 2*row), pixels.RGBAAt(col, 2*row+1)
+			line.WriteString(lipgloss.NewStyle().Foreground(hexColor(top)).Background(hexColor(bottom)).Render("▀"))
+		}
+		line.WriteString(strings.Repeat(" ", max(0, width-cols)))
+		lines[row] = line.String()
+	}
+	return lines
+}
+
+func hexColor(c color.RGBA) lipgloss.Color {
+	return lipgloss.Color(fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B))
+}
+
+// Sixel images are sized in pixels, so cells are assumed to be this large
+const (
+	sixelCellWidth  = 10
+	sixelCellHeight = 20
+)
+
+// writeImageAt draws img at row and col (1-based), scaled to cols by rows
+// cells, with protocol
+func writeImageAt(w io.Writer, protocol imageProtocol, img image.Image, row, col, cols, rows int) error {
+	if protocol == imageBlocks {
+		for i, line := range halfBlockRows(img, cols, rows, cols) {
+			if _, err := fmt.Fprintf(w, "\x1b[%d;%dH%s", row+i, col, line); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if _, err := fmt.Fprintf(w, "\x1b[%d;%dH", row, col); err != nil {
+		return err
+	}
+	switch protocol {
+	case imageKitty:
+		return writeKittyImage(w, img, cols, rows)
+	case imageITerm:
+		return writeITermImage(w, img, cols, rows)
+	default:
+		return writeSixel(w, flatten(resample(img, cols*sixelCellWidth, rows*sixelCellHeight)))
+	}
+}
+
+func encodePNG(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("encode image: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// kittyChunkSize is the most base64 data one kitty graphics command carries
+const kittyChunkSize = 4096
+
+// writeKittyImage transmits img as PNG and displays it over cols by rows cells
+func writeKittyImage(w io.Writer, img image.Image, cols, rows int) error {
+	data, err := encodePNG(img)
+	if err != nil {
+		return err
+	}
+	payload := base64.StdEncoding.EncodeToString(data)
+	for first := true; first || payload != ""; first = false {
+		chunk := payload[:min(len(payload), kittyChunkSize)]
+		payload = payload[len(chunk):]
+		more := 0
+		if payload != "" {
+			more = 1
+		}
+		control := fmt.Sprintf("m=%d", more)
+		if first {
+			control = fmt.Sprintf("a=T,f=100,q=2,c=%d,r=%d,%s", cols, rows, control)
+		}
+		if _, err := fmt.Fprintf(w, "\x1b_G%s;%s\x1b\\", control, chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeITermImage sends img as an inline image file cols by rows cells large
+func writeITermImage(w io.Writer, img image.Image, cols, rows int) error {
+	data, err := encodePNG(img)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "\x1b]1337;File=inline=1;size=%d;width=%d;height=%d;preserveAspectRatio=1:%s\a",
+		len(data), cols, rows, base64.StdEncoding.EncodeToString(data))
+	return err
+}
+
+// writeSixel encodes img as sixel graphics in the 216 web-safe colors, each
+// band of six pixel rows drawn once per color it uses
+func writeSixel(w io.Writer, img *image.RGBA) error {
+	bounds := img.Bounds()
+	paletted := image.NewPaletted(image.Rect(0, 0, bounds.Dx(), bounds.Dy()), palette.WebSafe)
+	draw.FloydSteinberg.Draw(paletted, paletted.Bounds(), img, bounds.Min)
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "\x1bPq\"1;1;%d;%d", bounds.Dx(), bounds.Dy())
+	for i, c := range palette.WebSafe {
+		r, g, b, _ := c.RGBA()
+		fmt.Fprintf(&out, "#%d;2;%d;%d;%d", i, r*100/0xffff, g*100/0xffff, b*100/0xffff)
+	}
+
+	width, height := paletted.Rect.Dx(), paletted.Rect.Dy()
+	for band := 0; band < height; band += 6 {
+		var used [256]bool
+		for y := band; y < min(band+6, height); y++ {
+			for _, index := range paletted.Pix[y*paletted.Stride : y*paletted.Stride+width] {
+				used[index] = true
+			}
+		}
+		for index := range used {
+			if !used[index] {
+				continue
+			}
+			fmt.Fprintf(&out, "#%d", index)
+			run, last := 0, byte(0)
+			for x := 0; x < width; x++ {
+				bits := byte(0)
+				for dy := 0; dy < 6 && band+dy < height; dy++ {
+					if int(paletted.Pix[(band+dy)*paletted.Stride+x]) == index {
+						bits |= 1 << dy
+					}
+				}
+				if char := '?' + bits; run > 0 && char != last {
+					writeSixelRun(&out, last, run)
+					run = 0
+				}
+				last = '?' + bits
+				run++
+			}
+			writeSixelRun(&out, last, run)
+			out.WriteByte('$')
+		}
+		out.WriteByte('-')
+	}
+	out.WriteString("\x1b\\")
+	_, err := w.Write(out.Bytes())
+	return err
+}
+
+// writeSixelRun writes count repeats of a sixel, run-length encoded when long
+func writeSixelRun(out *bytes.Buffer, char byte, count int) {
+	if count > 3 {
+		fmt.Fprintf(out, "!%d%c", count, char)
+		return
+	}
+	for range count {
+		out.WriteByte(char)
+	}
+}
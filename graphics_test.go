@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+)
+
+// gradientImage is a width by height image whose color varies in both directions
+func gradientImage(width, height int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{R: uint8(x * 255 / width), G: uint8(y * 255 / height), B: 80, A: 0xff})
+		}
+	}
+	return img
+}
+
+func TestDetectImageProtocol(t *testing.T) {
+	tests := []struct {
+		env  map[string]string
+		want imageProtocol
+	}{
+		{map[string]string{"TERM": "xterm-kitty"}, imageKitty},
+		{map[string]string{"TERM": "xterm-256color", "KITTY_WINDOW_ID": "1"}, imageKitty},
+		{map[string]string{"TERM_PROGRAM": "ghostty"}, imageKitty},
+		{map[string]string{"TERM_PROGRAM": "iTerm.app"}, imageITerm},
+		{map[string]string{"TERM_PROGRAM": "WezTerm"}, imageITerm},
+		{map[string]string{"TERM": "foot"}, imageSixel},
+		{map[string]string{"TERM": "xterm-256color"}, imageBlocks},
+		{map[string]string{"TERM": "xterm-kitty", "TMUX": "/tmp/tmux-1000/default,1,0"}, imageBlocks},
+	}
+	for _, tt := range tests {
+		getenv := func(name string) string { return tt.env[name] }
+		if got := detectImageProtocol(getenv); got != tt.want {
+			t.Errorf("detectImageProtocol(%v) = %v, want %v", tt.env, got, tt.want)
+		}
+	}
+
+	if protocol, err := parseImageProtocol("sixel"); err != nil || protocol == nil || *protocol != imageSixel {
+		t.Errorf("parseImageProtocol(sixel) = %v, %v", protocol, err)
+	}
+	if protocol, err := parseImageProtocol("auto"); err != nil || protocol != nil {
+		t.Errorf("auto should detect the protocol, got %v, %v", protocol, err)
+	}
+	if _, err := parseImageProtocol("png"); err == nil {
+		t.Error("parseImageProtocol(png) should fail")
+	}
+}
+
+func TestFitCells(t *testing.T) {
+	tests := []struct {
+		width, height, cols, rows int
+		wantCols, wantRows        int
+	}{
+		{64, 48, 38, 12, 32, 12}, // limited by height: 24 half-block pixels
+		{96, 48, 38, 12, 38, 10}, // limited by width
+		{16, 16, 40, 12, 24, 12}, // small icons are enlarged
+		{1000, 1, 20, 12, 20, 1}, // never less than one row
+		{0, 10, 20, 12, 0, 0},    // nothing to draw
+		{100, 100, 0, 12, 0, 0},  // no room
+		{1, 1000, 20, 12, 1, 12}, // never less than one column
+		{300, 200, 300, 200, 300, 100},
+	}
+	for _, tt := range tests {
+		cols, rows := fitCells(tt.width, tt.height, tt.cols, tt.rows)
+		if cols != tt.wantCols || rows != tt.wantRows {
+			t.Errorf("fitCells(%d, %d, %d, %d) = %d, %d; want %d, %d", tt.width, tt.height, tt.cols, tt.rows, cols, rows, tt.wantCols, tt.wantRows)
+		}
+	}
+}
+
+func TestFlattenShowsCheckerboardThroughTransparency(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 8, 1))
+	img.SetNRGBA(0, 0, color.NRGBA{R: 0xff, A: 0xff})
+	flat := flatten(img)
+	if got := flat.RGBAAt(0, 0); got != (color.RGBA{R: 0xff, A: 0xff}) {
+		t.Errorf("opaque pixel = %v", got)
+	}
+	if light, dark := flat.RGBAAt(1, 0), flat.RGBAAt(4, 0); light.R != 0xcc || dark.R != 0x99 {
+		t.Errorf("transparent pixels = %v, %v; want the checkerboard", light, dark)
+	}
+}
+
+func TestWriteImageProtocols(t *testing.T) {
+	img := gradientImage(40, 30)
+
+	var kitty bytes.Buffer
+	if err := writeImageAt(&kitty, imageKitty, img, 3, 5, 20, 8); err != nil {
+		t.Fatalf("kitty: %v", err)
+	}
+	if !strings.HasPrefix(kitty.String(), "\x1b[3;5H\x1b_Ga=T,f=100,q=2,c=20,r=8,m=0;iVBORw0KGgo") || !strings.HasSuffix(kitty.String(), "\x1b\\") {
+		t.Errorf("kitty output = %q", kitty.String()[:min(80, kitty.Len())])
+	}
+
+	var iterm bytes.Buffer
+	if err := writeImageAt(&iterm, imageITerm, img, 1, 1, 20, 8); err != nil {
+		t.Fatalf("iterm: %v", err)
+	}
+	if !strings.Contains(iterm.String(), "\x1b]1337;File=inline=1;") || !strings.Contains(iterm.String(), ";width=20;height=8;preserveAspectRatio=1:") {
+		t.Errorf("iterm output = %q", iterm.String()[:min(80, iterm.Len())])
+	}
+
+	var sixel bytes.Buffer
+	if err := writeImageAt(&sixel, imageSixel, img, 1, 1, 2, 1); err != nil {
+		t.Fatalf("sixel: %v", err)
+	}
+	// Two cells are 20 pixels wide and 20 high: four bands, the last of two rows
+	if got := sixel.String(); !strings.HasPrefix(got, "\x1b[1;1H\x1bPq\"1;1;20;20#0;2;0;0;0") || strings.Count(got, "-") != 4 || !strings.HasSuffix(got, "-\x1b\\") {
+		t.Errorf("sixel output = %q", got)
+	}
+
+	var blocks bytes.Buffer
+	if err := writeImageAt(&blocks, imageBlocks, img, 2, 3, 4, 2); err != nil {
+		t.Fatalf("blocks: %v", err)
+	}
+	if got := blocks.String(); !strings.Contains(got, "\x1b[3;3H") || strings.Count(got, "▀") != 8 {
+		t.Errorf("blocks output = %q", blocks.String())
+	}
+}
+
+func TestWriteSixelRun(t *testing.T) {
+	var out bytes.Buffer
+	writeSixelRun(&out, '~', 3)
+	writeSixelRun(&out, '?', 12)
+	if out.String() != "~~~!12?" {
+		t.Errorf("runs = %q", out.String())
+	}
+}
@@ -27,14 +27,40 @@ var keyBindings = []KeyBinding{
 	{"G", "Jump to bottom (diff/whole file)", "Navigation"},
 	{"o", "Expand surrounding context (Diff Only)", "Navigation"},
 	{"O", "Reset surrounding context (Diff Only)", "Navigation"},
+	{"m", "Jump to the other copy of a moved block (diff panel)", "Navigation"},
+	{"D", "Jump to the next changed Go declaration (diff panel)", "Navigation"},
 
 	// Actions
-	{"enter/space", "Select file / Expand directory", "Actions"},
-	{"s", "Toggle unstaged/staged/branch compare", "Actions"},
+	{"enter/space", "Select file or commit / Expand directory", "Actions"},
+	{"s", "Cycle unstaged/staged/branch or revision compare", "Actions"},
+	{"b", "Choose base branch for branch compare", "Actions"},
+	{"L", "Browse the commit log of HEAD or another ref", "Actions"},
+	{"H", "Show the commits that changed the selected file (file tree)", "Actions"},
+	{"esc", "Leave the file history", "Actions"},
 	{"f", "Toggle diff/whole file view", "Actions"},
+	{"|", "Toggle side-by-side split view", "Actions"},
+	{"A", "Cycle diff algorithm (myers/patience/histogram/difflib)", "Actions"},
+	{"w", "Cycle ignored whitespace (CR at EOL/space change/all space)", "Actions"},
+	{"W", "Hide/show changes to blank lines", "Actions"},
+	{"S", "Show/hide the changed Go declarations", "Actions"},
+	{"K", "Toggle changed key paths/hunks for JSON and YAML", "Actions"},
+	{"e", "Export loaded diffs as a patch file", "Actions"},
+	{"i", "Preview the selected image full size", "Actions"},
+
+	// Staging
+	{"a", "Stage hunk under cursor (Unstaged, diff panel)", "Staging"},
+	{"u", "Unstage hunk under cursor (Staged, diff panel)", "Staging"},
+	{"v", "Start line selection at first change of hunk", "Staging"},
+	{"V", "Select all changed lines of hunk", "Staging"},
+	{"j/k", "Extend selection (visual mode)", "Staging"},
+	{"o", "Swap selection ends (visual mode)", "Staging"},
+	{"a/u", "Stage/unstage selected lines (visual mode)", "Staging"},
+	{"esc", "Cancel selection", "Staging"},
+	{"d", "Discard hunk (diff panel) or file (file tree), Unstaged", "Staging"},
 
 	// Search
 	{"/", "Search/filter files in file tree", "Search"},
+	{"P", "Restrict diff to a pathspec (globs, :!exclude)", "Search"},
 	{"enter", "Confirm search", "Search"},
 	{"esc", "Cancel search", "Search"},
 	{"backspace", "Delete character in search", "Search"},
@@ -116,28 +142,31 @@ func (m Model) renderHelp() string {
 	footer := subtleStyle.Render("Press ? to close")
 	content.WriteString(footer)
 
-	// Center the modal
-	helpContent := modalStyle.Render(content.String())
-	helpLines := strings.Split(helpContent, "\n")
+	return centerModal(modalStyle.Render(content.String()), modalWidth, m.width, m.height)
+}
+
+// centerModal pads a rendered modal so it sits in the middle of the screen
+func centerModal(modal string, modalWidth, screenWidth, screenHeight int) string {
+	modalLines := strings.Split(modal, "\n")
 
 	// Center vertically and horizontally
-	verticalPadding := (m.height - len(helpLines)) / 2
+	verticalPadding := (screenHeight - len(modalLines)) / 2
 	if verticalPadding < 0 {
 		verticalPadding = 0
 	}
 
-	horizontalPadding := (m.width - modalWidth) / 2
+	horizontalPadding := (screenWidth - modalWidth) / 2
 	if horizontalPadding < 0 {
 		horizontalPadding = 0
 	}
 
-	// Build centered help
+	// Build centered modal
 	var result strings.Builder
 	for i := 0; i < verticalPadding; i++ {
 		result.WriteString("\n")
 	}
 
-	for _, line := range helpLines {
+	for _, line := range modalLines {
 		for i := 0; i < horizontalPadding; i++ {
 			result.WriteString(" ")
 		}
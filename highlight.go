@@ -48,6 +48,101 @@ func (h *SyntaxHighlighter) Highlight(line, filePath string) string {
 	return result.String()
 }
 
+// HighlightWithEmphasis highlights a line like Highlight and additionally layers the
+// emphasis style over the byte ranges in spans, keeping each token's own colors.
+func (h *SyntaxHighlighter) HighlightWithEmphasis(line, filePath string, spans []textSpan, emphasis lipgloss.Style) string {
+	if len(spans) == 0 {
+		return h.Highlight(line, filePath)
+	}
+
+	tokens, highlighted := h.tokensCoveringLine(line, filePath)
+
+	var result strings.Builder
+	offset := 0
+	for _, token := range tokens {
+		tokenEnd := offset + len(token.Value)
+		baseStyle := lipgloss.NewStyle()
+		if highlighted {
+			baseStyle, _ = h.tokenStyle(token.Type)
+		}
+
+		for offset < tokenEnd {
+			pieceEnd, emphasized := nextSpanBoundary(spans, offset, tokenEnd)
+			style := baseStyle
+			if emphasized {
+				style = baseStyle.Inherit(emphasis)
+			}
+			result.WriteString(style.Render(line[offset:pieceEnd]))
+			offset = pieceEnd
+		}
+	}
+
+	return result.String()
+}
+
+// tokensCoveringLine tokenises a line and reports whether the tokens carry syntax
+// highlighting. It falls back to a single plain token when no lexer applies or the
+// lexer's tokens do not reproduce the line byte for byte.
+func (h *SyntaxHighlighter) tokensCoveringLine(line, filePath string) ([]chroma.Token, bool) {
+	plain := []chroma.Token{{Type: chroma.Text, Value: line}}
+
+	lexer := h.getLexer(filePath)
+	if lexer == nil {
+		return plain, false
+	}
+	iterator, err := lexer.Tokenise(nil, line)
+	if err != nil {
+		return plain, false
+	}
+
+	tokens := iterator.Tokens()
+	covered := 0
+	for _, token := range tokens {
+		if !strings.HasPrefix(line[covered:], token.Value) {
+			return plain, false
+		}
+		covered += len(token.Value)
+	}
+	if covered != len(line) {
+		return plain, false
+	}
+	return tokens, true
+}
+
+// nextSpanBoundary returns where the piece starting at offset ends (no later than
+// limit) and whether that piece lies inside one of the spans.
+func nextSpanBoundary(spans []textSpan, offset, limit int) (int, bool) {
+	end := limit
+	for _, span := range spans {
+		if offset >= span.start && offset < span.end {
+			return min(span.end, limit), true
+		}
+		if span.start > offset && span.start < end {
+			end = span.start
+		}
+	}
+	return end, false
+}
+
+// emphasizeSpans layers the emphasis style over the byte ranges in spans of a
+// line shown without syntax highlighting
+func emphasizeSpans(line string, spans []textSpan, emphasis lipgloss.Style) string {
+	if len(spans) == 0 {
+		return line
+	}
+	var result strings.Builder
+	for offset := 0; offset < len(line); {
+		end, emphasized := nextSpanBoundary(spans, offset, len(line))
+		if emphasized {
+			result.WriteString(emphasis.Render(line[offset:end]))
+		} else {
+			result.WriteString(line[offset:end])
+		}
+		offset = end
+	}
+	return result.String()
+}
+
 // getLexer returns the appropriate lexer for a file path
 func (h *SyntaxHighlighter) getLexer(filePath string) chroma.Lexer {
 	if filePath == "" {
@@ -144,12 +239,21 @@ func (h *SyntaxHighlighter) getLexer(filePath string) chroma.Lexer {
 
 // styleToken applies lipgloss styling to a chroma token
 func (h *SyntaxHighlighter) styleToken(token chroma.Token) string {
-	content := token.Value
-	entry := h.style.Get(token.Type)
+	style, ok := h.tokenStyle(token.Type)
+	if !ok {
+		return token.Value
+	}
+	return style.Render(token.Value)
+}
+
+// tokenStyle converts the chroma style entry for a token type to a lipgloss style.
+// It reports false when the entry carries no styling.
+func (h *SyntaxHighlighter) tokenStyle(tokenType chroma.TokenType) (lipgloss.Style, bool) {
+	entry := h.style.Get(tokenType)
 
 	// Check if entry is empty (no styling)
 	if entry == (chroma.StyleEntry{}) {
-		return content
+		return lipgloss.NewStyle(), false
 	}
 
 	style := lipgloss.NewStyle()
@@ -177,5 +281,5 @@ func (h *SyntaxHighlighter) styleToken(token chroma.Token) string {
 		style = style.Underline(true)
 	}
 
-	return style.Render(content)
+	return style, true
 }
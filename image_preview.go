@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif" // registers the decoders of the previewed formats
+	_ "image/jpeg"
+	"io"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// previewableImageTypes are the images decoded for a preview
+var previewableImageTypes = map[string]bool{"image/png": true, "image/jpeg": true, "image/gif": true}
+
+const (
+	// thumbnailSize bounds the stored thumbnail, larger than any inline preview
+	thumbnailSize = 256
+	// maxPreviewPixels bounds the images decoded for a preview; a small file
+	// can hold an image that takes gigabytes once decoded
+	maxPreviewPixels = 40_000_000
+	// inlinePreviewRows is the height of the preview under a binary summary
+	inlinePreviewRows = 12
+	// minPreviewWidth is the narrowest panel that shows a preview
+	minPreviewWidth = 16
+	// previewGap separates the old and new image
+	previewGap = 2
+)
+
+// newThumbnail scales img down to fit thumbnailSize pixels
+func newThumbnail(img image.Image) *image.NRGBA {
+	bounds := img.Bounds()
+	scale := min(1, float64(thumbnailSize)/float64(max(bounds.Dx(), bounds.Dy())))
+	return resample(img, max(1, int(float64(bounds.Dx())*scale)), max(1, int(float64(bounds.Dy())*scale)))
+}
+
+// imageSide is one version of an image in a preview
+type imageSide struct {
+	label string
+	blob  BinaryBlob
+}
+
+// caption describes the side above its image
+func (side imageSide) caption() string {
+	return fmt.Sprintf("%s  %d×%d  %s", side.label, side.blob.Width, side.blob.Height, formatByteSize(side.blob.Size))
+}
+
+// imageSides returns the versions of a binary file that are images, old first
+func imageSides(info *BinaryInfo) []imageSide {
+	if info == nil {
+		return nil
+	}
+	var sides []imageSide
+	for _, side := range []imageSide{{"old", info.Old}, {"new", info.New}} {
+		if side.blob.isImage() {
+			sides = append(sides, side)
+		}
+	}
+	return sides
+}
+
+// previewColumnWidth splits width between the sides of a preview
+func previewColumnWidth(width, sides int) int {
+	return (width - previewGap*(sides-1)) / sides
+}
+
+// imagePreviewLines draws the old and new image of a binary file side by side
+// in half-block characters within width columns. It returns nil when neither
+// version is an image or the panel is too narrow.
+func imagePreviewLines(info *BinaryInfo, width int) []string {
+	sides := imageSides(info)
+	if len(sides) == 0 || width < minPreviewWidth {
+		return nil
+	}
+
+	colWidth := previewColumnWidth(width, len(sides))
+	columns := make([][]string, len(sides))
+	height := 0
+	for i, side := range sides {
+		caption := truncateToWidth(side.caption(), colWidth)
+		columns[i] = []string{panelInfoStyle.Render(caption) + strings.Repeat(" ", colWidth-len([]rune(caption)))}
+		cols, rows := fitCells(side.blob.Width, side.blob.Height, colWidth, inlinePreviewRows)
+		columns[i] = append(columns[i], halfBlockRows(side.blob.thumbnail, cols, rows, colWidth)...)
+		height = max(height, len(columns[i]))
+	}
+
+	lines := []string{""}
+	gap, blank := strings.Repeat(" ", previewGap), strings.Repeat(" ", colWidth)
+	for row := 0; row < height; row++ {
+		parts := make([]string, len(columns))
+		for i, column := range columns {
+			parts[i] = blank
+			if row < len(column) {
+				parts[i] = column[row]
+			}
+		}
+		lines = append(lines, strings.TrimRight(strings.Join(parts, gap), " "))
+	}
+	return lines
+}
+
+// imagePreviewHeight returns the number of lines imagePreviewLines draws,
+// worked out from the image sizes so the diff layout does not draw them
+func imagePreviewHeight(info *BinaryInfo, width int) int {
+	sides := imageSides(info)
+	if len(sides) == 0 || width < minPreviewWidth {
+		return 0
+	}
+	colWidth := previewColumnWidth(width, len(sides))
+	rows := 0
+	for _, side := range sides {
+		_, sideRows := fitCells(side.blob.Width, side.blob.Height, colWidth, inlinePreviewRows)
+		rows = max(rows, sideRows)
+	}
+	return 2 + rows // blank line and captions above the images
+}
+
+// truncateToWidth cuts s to at most width runes
+func truncateToWidth(s string, width int) string {
+	runes := []rune(s)
+	return string(runes[:min(len(runes), max(0, width))])
+}
+
+// imagePreviewCommand shows the images of a binary file full screen with a
+// terminal graphics protocol. It runs through tea.Exec, which hands it the
+// terminal, because graphics cannot be drawn through Bubble Tea's renderer.
+type imagePreviewCommand struct {
+	file          FileDiff
+	protocol      imageProtocol
+	width, height int
+	stdin         io.Reader
+	stdout        io.Writer
+}
+
+func (c *imagePreviewCommand) SetStdin(r io.Reader)  { c.stdin = r }
+func (c *imagePreviewCommand) SetStdout(w io.Writer) { c.stdout = w }
+func (c *imagePreviewCommand) SetStderr(io.Writer)   {}
+
+// Run draws the preview on the alternate screen and waits for Enter
+func (c *imagePreviewCommand) Run() error {
+	var out bytes.Buffer
+	out.WriteString("\x1b[?1049h\x1b[2J\x1b[H")
+	out.WriteString(truncateToWidth(c.file.displayPath(), c.width))
+
+	sides := imageSides(c.file.Binary)
+	colWidth := previewColumnWidth(c.width, len(sides))
+	for i, side := range sides {
+		col := 1 + i*(colWidth+previewGap)
+		fmt.Fprintf(&out, "\x1b[2;%dH%s", col, truncateToWidth(side.caption(), colWidth))
+
+		img, _, err := image.Decode(bytes.NewReader(side.blob.content))
+		if err != nil {
+			return fmt.Errorf("decode %s image: %w", side.label, err)
+		}
+		cols, rows := fitCells(side.blob.Width, side.blob.Height, colWidth, c.height-4)
+		if err := writeImageAt(&out, c.protocol, img, 4, col, cols, rows); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(&out, "\x1b[%d;1HPress Enter to return", c.height)
+	if _, err := c.stdout.Write(out.Bytes()); err != nil {
+		return err
+	}
+
+	_, err := bufio.NewReader(c.stdin).ReadString('\n')
+	if errors.Is(err, io.EOF) {
+		err = nil
+	}
+	if c.protocol == imageKitty {
+		// Delete the images so they do not stay over the diff
+		io.WriteString(c.stdout, "\x1b_Ga=d,q=2\x1b\\")
+	}
+	io.WriteString(c.stdout, "\x1b[?1049l")
+	return err
+}
+
+// openImagePreview shows the images of the selected file full screen
+func (m *Model) openImagePreview() tea.Cmd {
+	for _, file := range m.getSelectedDiffFiles() {
+		if len(imageSides(file.Binary)) == 0 {
+			continue
+		}
+		preview := &imagePreviewCommand{file: *file, protocol: m.imageProtocol, width: m.width, height: m.height}
+		return tea.Exec(preview, func(err error) tea.Msg {
+			if err != nil {
+				return errMsg{fmt.Errorf("preview image: %w", err)}
+			}
+			return clearErrorMsg{}
+		})
+	}
+	m.notice = "No image to preview"
+	return nil
+}
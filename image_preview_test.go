@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image/gif"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// imageFileDiff is a PNG changed from 40×30 to 60×30 pixels
+func imageFileDiff(t *testing.T) FileDiff {
+	t.Helper()
+	oldPNG, err := encodePNG(gradientImage(40, 30))
+	if err != nil {
+		t.Fatal(err)
+	}
+	newPNG, err := encodePNG(gradientImage(60, 30))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return *newBinaryFileDiff("icon.png", Modified, oldPNG, newPNG, true, true)
+}
+
+func TestNewBinaryBlobDecodesImages(t *testing.T) {
+	file := imageFileDiff(t)
+	if old := file.Binary.Old; !old.isImage() || old.Width != 40 || old.Height != 30 {
+		t.Errorf("old image = %dx%d, decoded %v", old.Width, old.Height, old.isImage())
+	}
+	if summary := binarySummaryLines(file.Binary); !strings.Contains(summary[2], "image/png 60×30") {
+		t.Errorf("summary should give the dimensions: %q", summary)
+	}
+	if thumbnail := file.Binary.Old.thumbnail; thumbnail == nil || thumbnail.Bounds().Dx() != 40 {
+		t.Error("the thumbnail should be made when the diff is loaded")
+	}
+
+	// An image too large to decode keeps its dimensions but gets no preview
+	var huge bytes.Buffer
+	if err := gif.Encode(&huge, gradientImage(2, 2), nil); err != nil {
+		t.Fatal(err)
+	}
+	hugeGIF := huge.Bytes()
+	binary.LittleEndian.PutUint16(hugeGIF[6:], 20000)
+	binary.LittleEndian.PutUint16(hugeGIF[8:], 20000)
+	if blob := newBinaryBlob(hugeGIF, true); blob.isImage() || blob.Width != 20000 {
+		t.Errorf("huge GIF = %dx%d, previewed %v", blob.Width, blob.Height, blob.isImage())
+	}
+
+	// A PNG signature without an image is not previewed
+	if blob := newBinaryBlob([]byte(pngHeader), true); blob.isImage() || blob.Width != 0 {
+		t.Errorf("corrupt PNG should not be an image: %+v", blob)
+	}
+	if sides := imageSides(newBinaryFileDiff("logo.png", Added, nil, []byte(pngHeader), false, true).Binary); len(sides) != 0 {
+		t.Errorf("sides = %+v, want none", sides)
+	}
+}
+
+func TestModelShowsImagePreview(t *testing.T) {
+	model := setupModel(t)
+	model.width = 100
+	model.height = 30
+	newModel, _ := model.Update(allDiffsLoadedMsg{files: []FileDiff{imageFileDiff(t)}})
+	model = newModel.(Model)
+
+	lines := model.buildDiffPanelLines()
+	if got := model.computeDiffLayout(model.getSelectedDiffFiles()).totalLines; got != len(lines) {
+		t.Errorf("layout has %d lines, the panel renders %d", got, len(lines))
+	}
+	for _, width := range []int{minPreviewWidth - 1, minPreviewWidth, 30, 100, 300} {
+		if got, want := imagePreviewHeight(imageFileDiff(t).Binary, width), len(imagePreviewLines(imageFileDiff(t).Binary, width)); got != want {
+			t.Errorf("width %d: preview height %d, drawn %d lines", width, got, want)
+		}
+	}
+	view := stripAnsi(strings.Join(lines, "\n"))
+	if !strings.Contains(view, "old  40×30  ") || !strings.Contains(view, "new  60×30  ") {
+		t.Fatalf("preview should caption both images:\n%s", view)
+	}
+	// Both images fit 12 rows and sit side by side
+	width := diffContentWidth(model.width, model.diffViewMode)
+	rows := 0
+	for _, line := range lines {
+		if strings.Contains(line, "▀") {
+			rows++
+			if got := len([]rune(stripAnsi(line))); got > width {
+				t.Errorf("preview row is %d columns, wider than the panel's %d", got, width)
+			}
+		}
+	}
+	if rows != inlinePreviewRows {
+		t.Errorf("preview has %d rows, want %d", rows, inlinePreviewRows)
+	}
+
+	// Too narrow to draw
+	if got := imagePreviewLines(imageFileDiff(t).Binary, minPreviewWidth-1); got != nil {
+		t.Errorf("narrow preview = %q, want none", got)
+	}
+}
+
+func TestImagePreviewCommand(t *testing.T) {
+	for _, protocol := range []imageProtocol{imageBlocks, imageKitty} {
+		var out bytes.Buffer
+		preview := &imagePreviewCommand{file: imageFileDiff(t), protocol: protocol, width: 80, height: 24}
+		preview.SetStdin(strings.NewReader("\n"))
+		preview.SetStdout(&out)
+		if err := preview.Run(); err != nil {
+			t.Fatalf("%v: Run: %v", protocol, err)
+		}
+
+		got := out.String()
+		for _, want := range []string{"\x1b[?1049h", "icon.png", "\x1b[2;1Hold  40×30", "\x1b[2;42Hnew  60×30", "\x1b[24;1HPress Enter to return"} {
+			if !strings.Contains(got, want) {
+				t.Errorf("%v: output missing %q", protocol, want)
+			}
+		}
+		if !strings.HasSuffix(got, "\x1b[?1049l") {
+			t.Errorf("%v: the alternate screen should be left", protocol)
+		}
+		if deleted := strings.Contains(got, "\x1b_Ga=d"); deleted != (protocol == imageKitty) {
+			t.Errorf("%v: kitty images deleted = %v", protocol, deleted)
+		}
+	}
+}
+
+func TestModelOpenImagePreview(t *testing.T) {
+	model := setupModel(t)
+	model.width = 100
+	model.height = 30
+	text := FileDiff{Path: "a.txt", Hunks: []Hunk{{OldStart: 1, OldCount: 1, NewStart: 1, NewCount: 1, Lines: []DiffLine{{Type: LineAdded, Content: "a", NewLineNum: 1}}}}}
+	newModel, _ := model.Update(allDiffsLoadedMsg{files: []FileDiff{text, imageFileDiff(t)}})
+	model = newModel.(Model)
+
+	newModel, cmd := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'i'}})
+	model = newModel.(Model)
+	if cmd != nil || model.notice != "No image to preview" {
+		t.Errorf("a.txt has no image: cmd %v, notice %q", cmd != nil, model.notice)
+	}
+
+	if !model.selectTreePath("icon.png") {
+		t.Fatal("icon.png not in the tree")
+	}
+	if _, cmd := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'i'}}); cmd == nil {
+		t.Error("i should open the preview of icon.png")
+	}
+}
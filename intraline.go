@@ -0,0 +1,134 @@
+package main
+
+import (
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// minIntralineSimilarity is the token similarity ratio below which a removed/added
+// pair is treated as a rewrite rather than an edit, and no spans are emphasised.
+const minIntralineSimilarity = 0.4
+
+// textSpan is a byte range [start, end) within a line's content
+type textSpan struct {
+	start int
+	end   int
+}
+
+// computeHunkEmphasis pairs removed and added lines the same way the side-by-side
+// layout does and returns the changed spans for each paired line, keyed by its
+// index in lines.
+func computeHunkEmphasis(lines []DiffLine) map[int][]textSpan {
+	emphasis := make(map[int][]textSpan)
+	for _, row := range pairHunkRows(lines) {
+		if row.oldIdx < 0 || row.newIdx < 0 || row.oldIdx == row.newIdx {
+			continue
+		}
+
+		oldSpans, newSpans := computeIntralineSpans(lines[row.oldIdx].Content, lines[row.newIdx].Content)
+		if len(oldSpans) > 0 {
+			emphasis[row.oldIdx] = oldSpans
+		}
+		if len(newSpans) > 0 {
+			emphasis[row.newIdx] = newSpans
+		}
+	}
+	return emphasis
+}
+
+// computeIntralineSpans diffs two lines word by word and returns the spans that
+// differ on each side. Lines that share too little are reported without spans so
+// the whole-line colouring stays the only signal.
+func computeIntralineSpans(oldContent, newContent string) ([]textSpan, []textSpan) {
+	oldTokens, oldOffsets := tokenizeForIntraline(oldContent)
+	newTokens, newOffsets := tokenizeForIntraline(newContent)
+	if len(oldTokens) == 0 || len(newTokens) == 0 {
+		return nil, nil
+	}
+
+	matcher := difflib.NewMatcher(oldTokens, newTokens)
+	if matcher.Ratio() < minIntralineSimilarity {
+		return nil, nil
+	}
+
+	var oldSpans, newSpans []textSpan
+	for _, op := range matcher.GetOpCodes() {
+		if op.Tag == 'e' {
+			continue
+		}
+		oldSpans = appendTokenSpan(oldSpans, oldOffsets, op.I1, op.I2)
+		newSpans = appendTokenSpan(newSpans, newOffsets, op.J1, op.J2)
+	}
+	return oldSpans, newSpans
+}
+
+// tokenizeForIntraline splits content into words, whitespace runs and single
+// punctuation characters. offsets has one more entry than tokens so that token i
+// covers content[offsets[i]:offsets[i+1]].
+func tokenizeForIntraline(content string) ([]string, []int) {
+	var tokens []string
+	offsets := []int{0}
+
+	for start := 0; start < len(content); {
+		end := start + intralineTokenLength(content[start:])
+		tokens = append(tokens, content[start:end])
+		offsets = append(offsets, end)
+		start = end
+	}
+	return tokens, offsets
+}
+
+// intralineTokenLength returns the byte length of the token at the start of content
+func intralineTokenLength(content string) int {
+	first, size := utf8.DecodeRuneInString(content)
+	class := intralineRuneClass(first)
+	if class == runeClassPunctuation {
+		return size
+	}
+
+	length := size
+	for length < len(content) {
+		r, runeSize := utf8.DecodeRuneInString(content[length:])
+		if intralineRuneClass(r) != class {
+			break
+		}
+		length += runeSize
+	}
+	return length
+}
+
+type runeClass int
+
+const (
+	runeClassWord runeClass = iota
+	runeClassSpace
+	runeClassPunctuation
+)
+
+func intralineRuneClass(r rune) runeClass {
+	switch {
+	case r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r):
+		return runeClassWord
+	case unicode.IsSpace(r):
+		return runeClassSpace
+	default:
+		return runeClassPunctuation
+	}
+}
+
+// appendTokenSpan converts the token range [from, to) to a byte span, merging it
+// with the previous span when they touch.
+func appendTokenSpan(spans []textSpan, offsets []int, from, to int) []textSpan {
+	if from >= to {
+		return spans
+	}
+
+	span := textSpan{start: offsets[from], end: offsets[to]}
+	if len(spans) > 0 && spans[len(spans)-1].end == span.start {
+		spans[len(spans)-1].end = span.end
+		return spans
+	}
+	return append(spans, span)
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+func TestComputeIntralineSpans(t *testing.T) {
+	testCases := []struct {
+		name     string
+		old      string
+		new      string
+		oldSpans []string
+		newSpans []string
+	}{
+		{
+			name:     "renamed identifier",
+			old:      "total := price * qty",
+			new:      "total := price * quantity",
+			oldSpans: []string{"qty"},
+			newSpans: []string{"quantity"},
+		},
+		{
+			name:     "single character change",
+			old:      "if a < b {",
+			new:      "if a <= b {",
+			oldSpans: nil,
+			newSpans: []string{"="},
+		},
+		{
+			name:     "complete rewrite has no emphasis",
+			old:      "return nil",
+			new:      "fmt.Println(\"something else entirely\")",
+			oldSpans: nil,
+			newSpans: nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			oldSpans, newSpans := computeIntralineSpans(tc.old, tc.new)
+			assertSpanTexts(t, "old", tc.old, oldSpans, tc.oldSpans)
+			assertSpanTexts(t, "new", tc.new, newSpans, tc.newSpans)
+		})
+	}
+}
+
+func TestComputeHunkEmphasisPairsOnlyChangedRuns(t *testing.T) {
+	lines := []DiffLine{
+		{Type: LineContext, Content: "func main() {"},
+		{Type: LineRemoved, Content: "\tx := compute(1)"},
+		{Type: LineAdded, Content: "\tx := compute(2)"},
+		{Type: LineAdded, Content: "\tlog(x)"},
+	}
+
+	emphasis := computeHunkEmphasis(lines)
+	if _, ok := emphasis[0]; ok {
+		t.Error("context line should not be emphasised")
+	}
+	if _, ok := emphasis[3]; ok {
+		t.Error("unpaired added line should not be emphasised")
+	}
+	assertSpanTexts(t, "removed", lines[1].Content, emphasis[1], []string{"1"})
+	assertSpanTexts(t, "added", lines[2].Content, emphasis[2], []string{"2"})
+}
+
+func TestHighlightWithEmphasisPreservesText(t *testing.T) {
+	highlighter := NewSyntaxHighlighter()
+	line := "total := price * qty"
+	spans := []textSpan{{start: 17, end: 20}}
+
+	for _, path := range []string{"main.go", "notes.unknownext"} {
+		rendered := highlighter.HighlightWithEmphasis(line, path, spans, lipgloss.NewStyle().Bold(true))
+		if got := stripAnsi(rendered); got != line {
+			t.Errorf("HighlightWithEmphasis(%s) text = %q, want %q", path, got, line)
+		}
+	}
+}
+
+func TestRenderDiffLineContentEmphasisWithoutHighlighter(t *testing.T) {
+	profile := lipgloss.ColorProfile()
+	t.Cleanup(func() { lipgloss.SetColorProfile(profile) })
+	lipgloss.SetColorProfile(termenv.ANSI256)
+
+	model := Model{}
+	line := DiffLine{Type: LineRemoved, Content: "total := price * qty"}
+	spans := []textSpan{{start: 17, end: 20}}
+	plain := model.renderDiffLineContent(line, "main.go", nil)
+	rendered := model.renderDiffLineContent(line, "main.go", spans)
+	if got := stripAnsi(rendered); got != line.Content {
+		t.Errorf("text = %q, want %q", got, line.Content)
+	}
+	if rendered == plain || !strings.Contains(rendered, diffRemovedEmphasisStyle.Render("qty")) {
+		t.Errorf("changed span should be emphasised without a highlighter: %q", rendered)
+	}
+}
+
+func TestNextSpanBoundary(t *testing.T) {
+	spans := []textSpan{{start: 2, end: 4}, {start: 6, end: 9}}
+
+	testCases := []struct {
+		offset, limit  int
+		wantEnd        int
+		wantEmphasised bool
+	}{
+		{offset: 0, limit: 10, wantEnd: 2, wantEmphasised: false},
+		{offset: 2, limit: 10, wantEnd: 4, wantEmphasised: true},
+		{offset: 3, limit: 3, wantEnd: 3, wantEmphasised: true},
+		{offset: 4, limit: 10, wantEnd: 6, wantEmphasised: false},
+		{offset: 7, limit: 8, wantEnd: 8, wantEmphasised: true},
+		{offset: 9, limit: 12, wantEnd: 12, wantEmphasised: false},
+	}
+
+	for _, tc := range testCases {
+		end, emphasised := nextSpanBoundary(spans, tc.offset, tc.limit)
+		if end != tc.wantEnd || emphasised != tc.wantEmphasised {
+			t.Errorf("nextSpanBoundary(%d, %d) = (%d, %v), want (%d, %v)",
+				tc.offset, tc.limit, end, emphasised, tc.wantEnd, tc.wantEmphasised)
+		}
+	}
+}
+
+func assertSpanTexts(t *testing.T, side, content string, spans []textSpan, want []string) {
+	t.Helper()
+
+	if len(spans) != len(want) {
+		t.Fatalf("%s spans = %+v, want texts %q", side, spans, want)
+	}
+	for i, span := range spans {
+		if got := content[span.start:span.end]; got != want[i] {
+			t.Errorf("%s span %d = %q, want %q", side, i, got, want[i])
+		}
+	}
+}
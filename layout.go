@@ -8,7 +8,8 @@ const (
 	footerRows       = 1 // Number of rows for footer
 
 	// Panel layout
-	panelBorderRows   = 2 // Rows consumed by panel borders (top + bottom)
+	panelBorderRows    = 2 // Rows consumed by panel borders (top + bottom)
+	panelBorderColumns = 2 // Columns consumed by panel borders (left + right)
 	fileTreeWidthRatio = 3 // File tree gets 1/fileTreeWidthRatio of total width
 
 	// Line number formatting
@@ -19,8 +20,18 @@ const (
 	helpModalMaxHeight = 30 // Maximum height of help modal
 	helpModalPadding   = 4  // Padding around help modal (2 on each side)
 
+	// Confirmation modal dimensions
+	confirmModalMaxWidth = 60 // Maximum width of the discard confirmation modal
+
+	// Branch picker dimensions
+	branchPickerMaxWidth = 60 // Maximum width of the branch picker modal
+	branchPickerMaxRows  = 15 // Maximum number of branches listed at once
+
 	// Branch compare limits
-	maxCommitsAhead = 50 // Maximum number of commits to show ahead of main
+	maxCommitsAhead = 50 // Maximum number of commits to show ahead of the base branch
+
+	// Commit log
+	commitLogPageSize = maxCommitsAhead // Commits loaded at a time, more as the cursor reaches the end
 )
 
 // contentHeight calculates the available content height given total height and search mode
@@ -47,6 +58,15 @@ func diffPanelWidth(totalWidth int) int {
 	return totalWidth - fileTreeWidth(totalWidth)
 }
 
+// diffContentWidth returns the usable width inside the diff panel borders
+func diffContentWidth(totalWidth int, viewMode DiffViewMode) int {
+	width := diffPanelWidth(totalWidth)
+	if viewMode == WholeFile {
+		width = totalWidth
+	}
+	return max(0, width-panelBorderColumns)
+}
+
 // helpModalDimensions calculates the dimensions for the help modal
 func helpModalDimensions(screenWidth, screenHeight int) (width, height int) {
 	width = min(helpModalMaxWidth, screenWidth-helpModalPadding)
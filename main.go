@@ -11,37 +11,208 @@ import (
 const appVersion = "1.0.0"
 
 func main() {
-	if handled := handleCLIArgs(os.Args[1:]); handled {
+	args := os.Args[1:]
+	if handled := handleCLIArgs(args); handled {
 		return
 	}
 
-	if err := run(); err != nil {
+	opts, err := parseCLIArgs(args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "better_diff: %v\n", err)
+		os.Exit(2)
+	}
+
+	if err := run(opts); err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
 }
 
-func run() error {
+func run(opts cliOptions) error {
+	if opts.external != nil {
+		return runExternalDiff(opts)
+	}
+	if opts.noIndex != nil {
+		return runInput(opts, opts.noIndex.diffInput())
+	}
+	if opts.patchFile == "" && readsPagerInput(opts) {
+		opts.patchFile = "-"
+	}
+	if opts.patchFile != "" {
+		if err := checkPatchFileArgument(opts.patchFile); err != nil {
+			return err
+		}
+		return runPatchInput(opts)
+	}
+
 	gitService, err := NewGitService()
 	if err != nil {
 		return fmt.Errorf("initialize git service: %w", err)
 	}
 
-	logger := initLogger(gitService)
-	defer func() {
-		if closeErr := logger.Close(); closeErr != nil {
-			fmt.Fprintf(os.Stderr, "warning: close logger: %v\n", closeErr)
+	if opts.revisions != nil {
+		if err := gitService.ValidateRevisionRange(*opts.revisions); err != nil {
+			return fmt.Errorf("resolve %s: %w", opts.revisions, err)
+		}
+	}
+	if opts.log {
+		if err := gitService.ValidateRevisionRange(RevisionRange{From: defaultRevision(opts.logRef)}); err != nil {
+			return fmt.Errorf("resolve log ref: %w", err)
 		}
-	}()
+	}
+	if opts.baseBranch != "" {
+		if _, err := gitService.ResolveBaseBranch(opts.baseBranch); err != nil {
+			return fmt.Errorf("resolve base branch: %w", err)
+		}
+	}
+
+	pathspec, err := cliPathspec(gitService, opts.pathspecs)
+	if err != nil {
+		return err
+	}
+
+	logger := initLogger(gitService)
+	defer closeLogger(logger)
 
 	logger.Info("better_diff starting", map[string]any{
 		"version": appVersion,
 	})
 
+	if opts.output != outputTUI {
+		return runOutput(gitService, opts, pathspec, logger)
+	}
+
+	model := NewModel(gitService, logger)
+	if opts.staged || opts.branch {
+		model = model.WithDiffMode(opts.diffMode())
+	}
+	if opts.revisions != nil {
+		model = model.WithRevisions(*opts.revisions)
+	}
+	if opts.baseBranch != "" {
+		model = model.WithBaseBranch(opts.baseBranch)
+	}
+	if opts.log {
+		model = model.WithLog(opts.logRef)
+	}
+	model = model.WithPathspec(pathspec).WithDiffContext(opts.diffContext()).WithDiffAlgorithm(opts.algorithm).WithWhitespace(opts.whitespace, opts.blankLines).WithRenames(opts.renames).WithSemantic(opts.semantic).WithStructural(opts.structural).WithImageProtocol(opts.imageProtocol())
+
+	return runTUI(model, logger)
+}
+
+// readsPagerInput reports whether better_diff runs as a pager: the TUI was
+// asked for without selecting a diff, a diff is piped to stdin and stdout is
+// a terminal
+func readsPagerInput(opts cliOptions) bool {
+	return opts.output == outputTUI && opts.diffMode() == Unstaged &&
+		hasPipedInput(os.Stdin) && isTerminal(os.Stdout)
+}
+
+// hasPipedInput reports whether f is a pipe, or a regular file with data in
+// it. Stdin redirected from /dev/null, closed, or left to a terminal does not
+// count, so better_diff started without a terminal still shows the repository.
+func hasPipedInput(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	mode := info.Mode()
+	return mode&os.ModeNamedPipe != 0 || (mode.IsRegular() && info.Size() > 0)
+}
+
+// checkPatchFileArgument rejects a patch file named like a revision of the
+// repository in the working directory. Stdin, and any file outside a
+// repository, cannot be mistaken for one.
+func checkPatchFileArgument(name string) error {
+	if name == "-" {
+		return nil
+	}
+	gitService, err := NewGitService()
+	if err != nil {
+		return nil
+	}
+	return gitService.checkPatchFileArgument(name)
+}
+
+// runPatchInput shows a patch file or a diff piped to stdin. No repository is
+// needed, so patches received by mail can be read anywhere and better_diff
+// can page git diff output.
+func runPatchInput(opts cliOptions) error {
+	pathspec, err := ParsePathspec(opts.pathspecs, "")
+	if err != nil {
+		return fmt.Errorf("parse pathspec: %w", err)
+	}
+
+	logger := initLogger(nil)
+	defer closeLogger(logger)
+
+	if opts.output != outputTUI {
+		return runOutput(nil, opts, pathspec, logger)
+	}
+
+	content, err := readPatchInput(opts.patchFile)
+	if err != nil {
+		return fmt.Errorf("read patch: %w", err)
+	}
+	files, err := parsePatchInput(opts.patchFile, content)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		if opts.patchFile != "-" {
+			return fmt.Errorf("no diff found in %s", opts.patchFile)
+		}
+		// Not a diff, or an empty one: pass it through as a pager would
+		_, err := os.Stdout.Write(content)
+		return err
+	}
+
+	model := NewModel(nil, logger).WithInput(patchDiffInput(opts.patchFile, files)).WithPathspec(pathspec)
+	if opts.patchFile == "-" {
+		// stdin carried the patch, so keys are read from the terminal
+		return runTUI(model, logger, tea.WithInputTTY())
+	}
+	return runTUI(model, logger)
+}
+
+// runExternalDiff shows the file pair git passed. Inside git's pager (git
+// diff with diff.external set) the diff is printed instead of starting the TUI.
+func runExternalDiff(opts cliOptions) error {
+	external := *opts.external
+	if external.unmerged {
+		fmt.Printf("* Unmerged path %s\n", external.path)
+		return nil
+	}
+
+	if opts.output == outputTUI && !isTerminal(os.Stdout) {
+		opts.output = outputText
+	}
+	return runInput(opts, external.diffInput())
+}
+
+// runInput shows a diff computed from files outside a repository
+func runInput(opts cliOptions, input diffInput) error {
+	pathspec, err := ParsePathspec(opts.pathspecs, "")
+	if err != nil {
+		return fmt.Errorf("parse pathspec: %w", err)
+	}
+
+	logger := initLogger(nil)
+	defer closeLogger(logger)
+
+	if opts.output != outputTUI {
+		return runOutput(nil, opts, pathspec, logger)
+	}
+
+	model := NewModel(nil, logger).WithInput(input).WithPathspec(pathspec).WithDiffContext(opts.diffContext()).WithDiffAlgorithm(opts.algorithm).WithWhitespace(opts.whitespace, opts.blankLines).WithSemantic(opts.semantic).WithStructural(opts.structural).WithImageProtocol(opts.imageProtocol())
+	return runTUI(model, logger)
+}
+
+// runTUI runs the full-screen app until it quits
+func runTUI(model Model, logger *Logger, options ...tea.ProgramOption) error {
 	program := tea.NewProgram(
-		NewModel(gitService, logger),
-		tea.WithAltScreen(),
-		tea.WithMouseCellMotion(),
+		model,
+		append([]tea.ProgramOption{tea.WithAltScreen(), tea.WithMouseCellMotion()}, options...)...,
 	)
 
 	if _, err := program.Run(); err != nil {
@@ -53,11 +224,55 @@ func run() error {
 	return nil
 }
 
-func initLogger(gitService *GitService) *Logger {
-	gitRootPath, err := gitService.GetRootPath()
+// cliPathspec parses command line pathspecs, which are relative to the
+// working directory as in git
+func cliPathspec(gitService *GitService, patterns []string) (Pathspec, error) {
+	if len(patterns) == 0 {
+		return Pathspec{}, nil
+	}
+
+	prefix, err := gitService.WorkingDirPrefix()
+	if err != nil {
+		return Pathspec{}, fmt.Errorf("resolve pathspec: %w", err)
+	}
+	pathspec, err := ParsePathspec(patterns, prefix)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "warning: get git root path: %v\n", err)
-		gitRootPath = ""
+		return Pathspec{}, fmt.Errorf("parse pathspec: %w", err)
+	}
+	return pathspec, nil
+}
+
+// runOutput writes the stdout format opts select; gitService is nil when the
+// diff comes from a patch or from git's external diff arguments
+func runOutput(gitService *GitService, opts cliOptions, pathspec Pathspec, logger *Logger) error {
+	switch opts.output {
+	case outputJSON:
+		return runStdout(logger, func() error { return runJSON(os.Stdout, gitService, opts, pathspec, logger) })
+	case outputPatch:
+		return runStdout(logger, func() error { return runPatch(os.Stdout, gitService, opts, pathspec, logger) })
+	default:
+		return runStdout(logger, func() error { return runPrint(gitService, opts, pathspec, logger) })
+	}
+}
+
+// runStdout runs a non-interactive output mode and reports logged errors
+func runStdout(logger *Logger, write func() error) error {
+	if err := write(); err != nil {
+		return err
+	}
+	reportLoggerStats(logger)
+	return nil
+}
+
+// initLogger opens the log file; gitService is nil outside a repository
+func initLogger(gitService *GitService) *Logger {
+	gitRootPath := ""
+	if gitService != nil {
+		rootPath, err := gitService.GetRootPath()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warning: get git root path: %v\n", err)
+		}
+		gitRootPath = rootPath
 	}
 
 	logger, err := NewLogger(INFO, gitRootPath)
@@ -67,6 +282,12 @@ func initLogger(gitService *GitService) *Logger {
 	return logger
 }
 
+func closeLogger(logger *Logger) {
+	if err := logger.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: close logger: %v\n", err)
+	}
+}
+
 func reportLoggerStats(logger *Logger) {
 	if !logger.HasErrors() {
 		return
@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"io"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -41,6 +43,162 @@ func TestHandleCLIArgs_NoArgsNotHandled(t *testing.T) {
 	}
 }
 
+func TestHasPipedInput(t *testing.T) {
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	defer writer.Close()
+	if !hasPipedInput(reader) {
+		t.Error("a pipe should be read")
+	}
+
+	devNull, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer devNull.Close()
+	if hasPipedInput(devNull) {
+		t.Error("/dev/null should not be read as a patch")
+	}
+
+	dir := t.TempDir()
+	for name, content := range map[string]string{"empty.patch": "", "change.patch": "diff --git a/a b/a\n"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := hasPipedInput(f); got != (content != "") {
+			t.Errorf("%s: hasPipedInput = %v", name, got)
+		}
+		f.Close()
+	}
+}
+
+func TestParseCLIArgs(t *testing.T) {
+	opts, err := parseCLIArgs(nil)
+	if err != nil || opts.revisions != nil {
+		t.Fatalf("no args should parse to defaults, got %+v, %v", opts, err)
+	}
+
+	opts, err = parseCLIArgs([]string{"v1.0...v2.0"})
+	if err != nil {
+		t.Fatalf("parseCLIArgs(range) error: %v", err)
+	}
+	want := RevisionRange{From: "v1.0", To: "v2.0", MergeBase: true}
+	if opts.revisions == nil || *opts.revisions != want {
+		t.Fatalf("parseCLIArgs(range) revisions = %+v, want %+v", opts.revisions, want)
+	}
+
+	for _, args := range [][]string{{"--base", "trunk"}, {"--base=trunk"}} {
+		opts, err = parseCLIArgs(args)
+		if err != nil || opts.baseBranch != "trunk" {
+			t.Errorf("parseCLIArgs(%q) = %+v, %v; want base trunk", args, opts, err)
+		}
+	}
+
+	opts, err = parseCLIArgs([]string{"main..", "--", "services/billing", ":!*.md"})
+	if err != nil || opts.revisions == nil || len(opts.pathspecs) != 2 || opts.pathspecs[1] != ":!*.md" {
+		t.Errorf("parseCLIArgs with pathspecs = %+v, %v", opts, err)
+	}
+
+	for _, args := range [][]string{{"-U1"}, {"--unified=1"}, {"-U", "1"}} {
+		opts, err = parseCLIArgs(args)
+		if err != nil || opts.diffContext() != 1 {
+			t.Errorf("parseCLIArgs(%q) = %+v, %v; want 1 context line", args, opts, err)
+		}
+	}
+	for _, args := range [][]string{{"--histogram"}, {"--diff-algorithm=histogram"}, {"--diff-algorithm", "histogram"}} {
+		opts, err = parseCLIArgs(args)
+		if err != nil || opts.diffOptions().Algorithm != AlgorithmHistogram {
+			t.Errorf("parseCLIArgs(%q) = %+v, %v; want histogram", args, opts, err)
+		}
+	}
+	opts, err = parseCLIArgs([]string{"--ignore-cr-at-eol", "-w", "-b", "--ignore-blank-lines"})
+	if diffOpts := opts.diffOptions(); err != nil || diffOpts.Whitespace != IgnoreAllSpace || !diffOpts.IgnoreBlankLines {
+		t.Errorf("whitespace flags = %+v, %v; want -w and blank lines ignored", opts, err)
+	}
+	if opts, _ := parseCLIArgs(nil); opts.diffOptions().Renames != DefaultRenameOptions {
+		t.Errorf("renames should be detected by default, got %+v", opts.renames)
+	}
+	for _, args := range [][]string{{"-C90%"}, {"-M", "--find-copies=9"}, {"--no-renames", "-C9"}} {
+		opts, err = parseCLIArgs(args)
+		if want := (RenameOptions{Detect: true, Copies: true, Threshold: 90}); err != nil || opts.renames != want {
+			t.Errorf("parseCLIArgs(%q) renames = %+v, %v; want %+v", args, opts.renames, err, want)
+		}
+	}
+	if opts, err := parseCLIArgs([]string{"-M", "--no-renames"}); err != nil || opts.renames.Detect {
+		t.Errorf("--no-renames should turn detection off, got %+v, %v", opts.renames, err)
+	}
+	if opts, err := parseCLIArgs([]string{"--image-protocol", "iterm"}); err != nil || opts.imageProtocol() != imageITerm {
+		t.Errorf("--image-protocol iterm = %v, %v", opts.imageProtocol(), err)
+	}
+	if _, err := parseCLIArgs([]string{"--image-protocol=png"}); err == nil {
+		t.Error("an unknown image protocol should be rejected")
+	}
+	if opts, err := parseCLIArgs([]string{"--semantic"}); err != nil || !opts.diffOptions().Semantic {
+		t.Errorf("--semantic should summarize Go declarations, got %+v, %v", opts.diffOptions(), err)
+	}
+	if opts, err := parseCLIArgs([]string{"--structural"}); err != nil || !opts.diffOptions().Structural {
+		t.Errorf("--structural should compare JSON and YAML by value, got %+v, %v", opts.diffOptions(), err)
+	}
+	for args, wantRef := range map[string]string{"--log": "", "--log main": "main", "--log=v1.0": "v1.0"} {
+		opts, err := parseCLIArgs(strings.Fields(args))
+		if err != nil || opts.diffMode() != CommitLog || opts.logRef != wantRef || opts.revisions != nil {
+			t.Errorf("parseCLIArgs(%q) = %+v, %v; want the log of %q", args, opts, err, wantRef)
+		}
+	}
+	for _, args := range [][]string{{"--log", "main..dev"}, {"--log=main", "dev"}, {"--log", "--print"}, {"--log", "--staged"}} {
+		if _, err := parseCLIArgs(args); err == nil {
+			t.Errorf("parseCLIArgs(%q) should fail", args)
+		}
+	}
+	if _, err := parseCLIArgs([]string{"-Mx"}); err == nil {
+		t.Error("-Mx should be rejected")
+	}
+	if opts, _ := parseCLIArgs([]string{"--format=patch"}); opts.output != outputPatch || opts.diffContext() != DefaultDiffContext {
+		t.Errorf("parseCLIArgs(--format=patch) = %+v", opts)
+	}
+	for _, flag := range []string{"-w", "-b", "--ignore-cr-at-eol", "--ignore-blank-lines"} {
+		if _, err := parseCLIArgs([]string{"--format=patch", flag}); err == nil {
+			t.Errorf("--format=patch with %s should fail", flag)
+		}
+	}
+
+	dir := t.TempDir()
+	opts, err = parseCLIArgs([]string{"--print", dir, "--no-index", dir, "--", "*.conf"})
+	if err != nil || opts.noIndex == nil || opts.noIndex.oldPath != dir || len(opts.pathspecs) != 1 {
+		t.Errorf("parseCLIArgs(--no-index) = %+v, %v", opts, err)
+	}
+	for _, args := range [][]string{{"--no-index", dir}, {"--no-index", dir, dir, dir}, {"--staged", "--no-index", dir, dir}} {
+		if _, err := parseCLIArgs(args); err == nil {
+			t.Errorf("parseCLIArgs(%q) should fail", args)
+		}
+	}
+
+	patchPath := filepath.Join(t.TempDir(), "fix.patch")
+	if err := os.WriteFile(patchPath, []byte("diff --git a/a b/a\n"), 0o644); err != nil {
+		t.Fatalf("write patch: %v", err)
+	}
+	for _, args := range [][]string{{patchPath}, {"-"}} {
+		opts, err = parseCLIArgs(args)
+		if err != nil || opts.patchFile != args[0] || opts.revisions != nil {
+			t.Errorf("parseCLIArgs(%q) = %+v, %v; want patch file", args, opts, err)
+		}
+	}
+
+	for _, args := range [][]string{{"a", "b"}, {"--bogus"}, {"--base"}, {"--base="}, {"-Ux"}, {"--unified=-1"}, {"--diff-algorithm=fast"}, {"main..", patchPath}, {"-", "--staged"}} {
+		if _, err := parseCLIArgs(args); err == nil {
+			t.Errorf("parseCLIArgs(%q) should fail", args)
+		}
+	}
+}
+
 func captureStdout(t *testing.T, fn func()) string {
 	t.Helper()
 
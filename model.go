@@ -24,8 +24,8 @@ type Model struct {
 	files          []FileDiff
 	diffFiles      []FileDiff // Files with full diff content
 	fileTree       []TreeNode
-	commits        []Commit // Commits ahead of main branch
-	selectedCommit *Commit  // Currently selected commit in branch compare mode
+	commits        []Commit // Commits ahead of the base branch
+	selectedCommit *Commit  // Commit whose diff the commit log shows
 	selectedIndex  int
 	panel          Panel
 	diffMode       DiffMode
@@ -42,9 +42,60 @@ type Model struct {
 	lastFileHash   string // To detect changes in files
 	vimPendingG    bool   // Tracks first "g" for "gg" in whole-file navigation
 	diffContext    int    // Context lines in Diff Only mode
+	diffAlgorithm  DiffAlgorithm
+	whitespace     WhitespaceMode // Whitespace differences ignored when comparing lines
+	blankLines     bool           // Hide changes that only add or remove blank lines
+	renames        RenameOptions  // Pairing of deleted and added files into renames and copies
+	imageProtocol  imageProtocol  // How the full-size image preview is drawn
+	semantic       bool           // Summarize the changed declarations of Go files
+	structural     bool           // Compare JSON and YAML files by value instead of by line
 	// Search state
 	searchMode  bool   // Whether search input is active
 	searchQuery string // Current search query
+	// Pathspec restricting the loaded diffs, and its input prompt state
+	pathspec      Pathspec
+	pathspecMode  bool   // Whether the pathspec prompt is active
+	pathspecInput string // Pathspec being typed
+	// Patch export prompt state
+	exportMode  bool   // Whether the export prompt is active
+	exportInput string // Patch file path being typed
+	// Confirmation of the last action, shown in the footer until the next key
+	notice string
+	// Visual line selection for partial staging (nil when inactive)
+	selection *lineSelection
+	// Discard waiting for confirmation in a modal (nil when none)
+	pendingDiscard *discardRequest
+	// Revisions given on the command line for RefCompare mode (nil when none)
+	revisions *RevisionRange
+	// Base branch chosen with --base or the branch picker (empty resolves automatically)
+	baseBranch string
+	// Base branch Branch Compare currently diffs against (nil when unresolved)
+	resolvedBase *BaseBranch
+	// Branch picker modal (nil when closed)
+	branchPicker *branchPicker
+	// Diff shown in InputDiff mode (nil otherwise)
+	input *diffInput
+	// Ref the commit log lists the history of (empty for HEAD), and its prompt state
+	logRef      string
+	logHasMore  bool   // Whether older commits follow the loaded ones
+	logLoading  bool   // Whether the next page of the log is being loaded
+	logRefMode  bool   // Whether the log ref prompt is active
+	logRefInput string // Ref being typed
+	// File whose history the commit log lists (empty for every commit), and
+	// the mode Esc returns to
+	logPath     string
+	historyFrom DiffMode
+}
+
+// diffInput is a diff given on the command line instead of read from the
+// repository, before pathspec filtering
+type diffInput struct {
+	label string // mode label in the header
+	title string // description above the diff
+	files []FileDiff
+	// compute rebuilds files for another context size or algorithm; nil when
+	// the hunks are fixed, as in a patch
+	compute func(diffOpts DiffOptions) ([]FileDiff, error)
 }
 
 var errGitServiceNotInitialized = errors.New("git service not initialized")
@@ -60,6 +111,7 @@ type TreeNode struct {
 	depth        int
 	linesAdded   int
 	linesRemoved int
+	oldPath      string // path a renamed or copied file came from
 }
 
 // NewModel creates a new model with GitService and Logger
@@ -76,17 +128,122 @@ func NewModel(gitService *GitService, logger *Logger) Model {
 		diffMode:     Unstaged,
 		diffViewMode: DiffOnly,
 		diffContext:  DefaultDiffContext,
+		renames:      DefaultRenameOptions,
 		scrollOffset: 0,
 		diffScroll:   0,
 	}
 }
 
+// WithRevisions starts the model in RefCompare mode for the given revisions
+func (m Model) WithRevisions(revisions RevisionRange) Model {
+	m.revisions = &revisions
+	m.diffMode = RefCompare
+	return m
+}
+
+// WithDiffMode starts the model in the given mode
+func (m Model) WithDiffMode(mode DiffMode) Model {
+	m.diffMode = mode
+	return m
+}
+
+// WithInput shows a diff given on the command line instead of the repository
+func (m Model) WithInput(input diffInput) Model {
+	m.diffMode = InputDiff
+	m.input = &input
+	return m
+}
+
+// WithLog starts the model in the commit log of ref (empty for HEAD)
+func (m Model) WithLog(ref string) Model {
+	m.diffMode = CommitLog
+	m.logRef = ref
+	return m
+}
+
+// WithDiffContext sets the number of context lines around changes
+func (m Model) WithDiffContext(lines int) Model {
+	m.diffContext = lines
+	return m
+}
+
+// WithDiffAlgorithm sets the algorithm that matches old and new lines
+func (m Model) WithDiffAlgorithm(algorithm DiffAlgorithm) Model {
+	m.diffAlgorithm = algorithm
+	return m
+}
+
+// WithWhitespace sets the whitespace differences that are ignored and whether
+// changes to blank lines are hidden
+func (m Model) WithWhitespace(mode WhitespaceMode, ignoreBlankLines bool) Model {
+	m.whitespace = mode
+	m.blankLines = ignoreBlankLines
+	return m
+}
+
+// WithRenames sets how deleted and added files are paired into renames and copies
+func (m Model) WithRenames(renames RenameOptions) Model {
+	m.renames = renames
+	return m
+}
+
+// WithSemantic shows a summary of the changed declarations above the hunks of
+// Go files
+func (m Model) WithSemantic(semantic bool) Model {
+	m.semantic = semantic
+	return m
+}
+
+// WithStructural shows JSON and YAML files as changed values instead of hunks
+func (m Model) WithStructural(structural bool) Model {
+	m.structural = structural
+	return m
+}
+
+// WithImageProtocol sets how the full-size image preview is drawn
+func (m Model) WithImageProtocol(protocol imageProtocol) Model {
+	m.imageProtocol = protocol
+	return m
+}
+
+// diffOptions returns how the model computes hunks
+func (m Model) diffOptions() DiffOptions {
+	return DiffOptions{
+		Context:          m.diffContext,
+		Algorithm:        m.diffAlgorithm,
+		Whitespace:       m.whitespace,
+		IgnoreBlankLines: m.blankLines,
+		Renames:          m.renames,
+		Semantic:         m.semantic,
+		Structural:       m.structural,
+	}
+}
+
+// WithPathspec restricts the model to paths matching pathspec
+func (m Model) WithPathspec(pathspec Pathspec) Model {
+	m.pathspec = pathspec
+	return m
+}
+
+// WithBaseBranch starts the model in BranchCompare mode against the given base
+// branch, unless revisions were given
+func (m Model) WithBaseBranch(name string) Model {
+	m.baseBranch = name
+	if m.revisions == nil {
+		m.diffMode = BranchCompare
+	}
+	return m
+}
+
 // Init implements tea.Model
 func (m Model) Init() tea.Cmd {
+	if m.diffMode == InputDiff {
+		// A given diff needs no repository, so there is no git info or watcher
+		return m.reloadByDiffMode()
+	}
 	return tea.Batch(
 		m.LoadGitInfo(),
-		m.LoadFiles(),
-		m.LoadAllDiffs(),
+		m.reloadByDiffMode(),
 	)
 }
 
@@ -138,7 +295,7 @@ func (m Model) LoadGitInfo() tea.Cmd {
 // LoadFiles loads changed files
 func (m Model) LoadFiles() tea.Cmd {
 	return m.withGitService(func() tea.Msg {
-		files, err := m.git.GetChangedFiles(m.diffMode)
+		files, err := m.git.GetChangedFiles(m.diffMode, m.pathspec)
 		if err != nil {
 			return m.logAndWrapError("get changed files", err, map[string]any{
 				"mode": m.diffMode,
@@ -151,7 +308,7 @@ func (m Model) LoadFiles() tea.Cmd {
 // LoadDiff loads the diff for a specific file
 func (m Model) LoadDiff(path string) tea.Cmd {
 	return m.withGitService(func() tea.Msg {
-		files, err := m.git.GetDiffWithContext(m.diffMode, m.diffViewMode, m.diffContext, m.logger)
+		files, err := m.git.GetDiffWithContext(m.diffMode, m.diffViewMode, m.diffOptions(), m.pathspec, m.logger)
 		if err != nil {
 			return m.logAndWrapError("get diff", err, map[string]any{
 				"file": path,
@@ -169,7 +326,7 @@ func (m Model) LoadDiff(path string) tea.Cmd {
 // LoadAllDiffs loads diffs for all changed files at startup
 func (m Model) LoadAllDiffs() tea.Cmd {
 	return m.withGitService(func() tea.Msg {
-		files, err := m.git.GetDiffWithContext(m.diffMode, m.diffViewMode, m.diffContext, m.logger)
+		files, err := m.git.GetDiffWithContext(m.diffMode, m.diffViewMode, m.diffOptions(), m.pathspec, m.logger)
 		if err != nil {
 			return m.logAndWrapError("get all diffs", err, map[string]any{
 				"mode": m.diffMode,
@@ -179,10 +336,10 @@ func (m Model) LoadAllDiffs() tea.Cmd {
 	})
 }
 
-// LoadCommitsAhead loads commits ahead of main branch
+// LoadCommitsAhead loads commits ahead of the base branch
 func (m Model) LoadCommitsAhead() tea.Cmd {
 	return m.withGitService(func() tea.Msg {
-		commits, err := m.git.GetCommitsAheadOfMain()
+		commits, err := m.git.GetCommitsAheadOfBase(m.baseBranch)
 		if err != nil {
 			return m.logAndWrapError("get commits ahead", err, nil)
 		}
@@ -190,10 +347,39 @@ func (m Model) LoadCommitsAhead() tea.Cmd {
 	})
 }
 
+// LoadCommitLog loads a page of the commit log, starting offset commits
+// below the newest
+func (m Model) LoadCommitLog(offset int) tea.Cmd {
+	return m.withGitService(func() tea.Msg {
+		commits, hasMore, err := m.git.GetCommitLog(m.logRef, offset, commitLogPageSize)
+		if err != nil {
+			return m.logAndWrapError("get commit log", err, map[string]any{
+				"ref":    defaultRevision(m.logRef),
+				"offset": offset,
+			})
+		}
+		return commitLogLoadedMsg{offset: offset, commits: commits, hasMore: hasMore}
+	})
+}
+
+// LoadFileHistory loads every commit that changed the file the commit log
+// follows, as a single page
+func (m Model) LoadFileHistory() tea.Cmd {
+	return m.withGitService(func() tea.Msg {
+		commits, err := m.git.GetFileHistory(m.logRef, m.logPath, m.renames, m.logger)
+		if err != nil {
+			return m.logAndWrapError("get file history", err, map[string]any{
+				"file": m.logPath,
+			})
+		}
+		return commitLogLoadedMsg{commits: commits}
+	})
+}
+
 // LoadCommitDiff loads the diff for a specific commit
 func (m Model) LoadCommitDiff(commitHash string) tea.Cmd {
 	return m.withGitService(func() tea.Msg {
-		files, err := m.git.GetCommitDiff(commitHash, m.logger)
+		files, err := m.git.GetCommitDiff(commitHash, m.diffViewMode, m.diffOptions(), m.pathspec, m.logger)
 		if err != nil {
 			return m.logAndWrapError("get commit diff", err, map[string]any{
 				"commit": commitHash,
@@ -203,10 +389,81 @@ func (m Model) LoadCommitDiff(commitHash string) tea.Cmd {
 	})
 }
 
-// LoadBranchCompareDiff loads a unified diff against default branch.
+// LoadLogCommit loads the diff of a commit chosen in the commit log; in a
+// file history, only the diff of that file
+func (m Model) LoadLogCommit(commit Commit) tea.Cmd {
+	if commit.Path == "" {
+		load := m.LoadCommitDiff(commit.Hash)
+		return func() tea.Msg {
+			msg := load()
+			if loaded, ok := msg.(allDiffsLoadedMsg); ok {
+				return commitDiffLoadedMsg{commit: commit, files: loaded.files}
+			}
+			return msg
+		}
+	}
+	return m.withGitService(func() tea.Msg {
+		files, err := m.git.GetFileCommitDiff(commit, m.diffViewMode, m.diffOptions(), m.logger)
+		if err != nil {
+			return m.logAndWrapError("get file commit diff", err, map[string]any{
+				"commit": commit.ShortHash,
+				"file":   commit.Path,
+			})
+		}
+		return commitDiffLoadedMsg{commit: commit, files: files}
+	})
+}
+
+// LoadBaseBranch resolves the branch Branch Compare diffs against
+func (m Model) LoadBaseBranch() tea.Cmd {
+	return m.withGitService(func() tea.Msg {
+		base, err := m.git.ResolveBaseBranch(m.baseBranch)
+		if err != nil {
+			m.logger.Warn("resolve base branch", map[string]any{"error": err})
+			return baseBranchMsg{}
+		}
+		return baseBranchMsg{&base}
+	})
+}
+
+// LoadBranches loads the branches offered by the branch picker
+func (m Model) LoadBranches() tea.Cmd {
+	return m.withGitService(func() tea.Msg {
+		branches, err := m.git.ListBranches()
+		if err != nil {
+			return m.logAndWrapError("list branches", err, nil)
+		}
+		return branchesLoadedMsg{branches}
+	})
+}
+
+// LoadInputDiffs delivers the files of the given diff that match the pathspec
+func (m Model) LoadInputDiffs() tea.Cmd {
+	input, pathspec := m.input, m.pathspec
+	diffOpts := m.diffOptions().forView(m.diffViewMode)
+	return func() tea.Msg {
+		files := input.files
+		if input.compute != nil {
+			computed, err := input.compute(diffOpts)
+			if err != nil {
+				return m.logAndWrapError("compute diff", err, map[string]any{"input": input.title})
+			}
+			files = computed
+		}
+		return allDiffsLoadedMsg{filterFileDiffs(files, pathspec)}
+	}
+}
+
+// hasFixedHunks reports whether the shown diff cannot be recomputed with
+// another context size or algorithm
+func (m Model) hasFixedHunks() bool {
+	return m.diffMode == InputDiff && m.input.compute == nil
+}
+
+// LoadBranchCompareDiff loads a unified diff against the base branch.
 func (m Model) LoadBranchCompareDiff(commits []Commit) tea.Cmd {
 	return m.withGitService(func() tea.Msg {
-		files, err := m.git.GetUnifiedBranchCompareDiff(m.diffViewMode, m.diffContext, m.logger)
+		files, err := m.git.GetUnifiedBranchCompareDiff(m.baseBranch, m.diffViewMode, m.diffOptions(), m.pathspec, m.logger)
 		if err != nil {
 			return m.logAndWrapError("get unified branch compare diff", err, map[string]any{
 				"commit_count": len(commits),
@@ -217,6 +474,83 @@ func (m Model) LoadBranchCompareDiff(commits []Commit) tea.Cmd {
 	})
 }
 
+// LoadRefCompareDiff loads the diff between the command line revisions
+func (m Model) LoadRefCompareDiff() tea.Cmd {
+	return m.withGitService(func() tea.Msg {
+		if m.revisions == nil {
+			return allDiffsLoadedMsg{}
+		}
+		files, err := m.git.GetRefCompareDiff(*m.revisions, m.diffViewMode, m.diffOptions(), m.pathspec, m.logger)
+		if err != nil {
+			return m.logAndWrapError("get ref compare diff", err, map[string]any{
+				"revisions": m.revisions.String(),
+			})
+		}
+		return allDiffsLoadedMsg{files}
+	})
+}
+
+// StageHunk writes a hunk of the unstaged diff into the index
+func (m Model) StageHunk(path string, hunk Hunk) tea.Cmd {
+	return m.StageHunkLines(path, hunk, wholeHunk(hunk))
+}
+
+// StageHunkLines writes the selected lines of an unstaged hunk into the index
+func (m Model) StageHunkLines(path string, hunk Hunk, lines lineRange) tea.Cmd {
+	return m.withGitService(func() tea.Msg {
+		if err := m.git.StageHunkLines(path, hunk, lines, m.logger); err != nil {
+			return m.logAndWrapError("stage hunk", err, map[string]any{
+				"file":      path,
+				"old_start": hunk.OldStart,
+			})
+		}
+		return m.checkWorkingTreeChanges()
+	})
+}
+
+// UnstageHunk reverts a hunk of the staged diff in the index
+func (m Model) UnstageHunk(path string, hunk Hunk) tea.Cmd {
+	return m.UnstageHunkLines(path, hunk, wholeHunk(hunk))
+}
+
+// UnstageHunkLines reverts the selected lines of a staged hunk in the index
+func (m Model) UnstageHunkLines(path string, hunk Hunk, lines lineRange) tea.Cmd {
+	return m.withGitService(func() tea.Msg {
+		if err := m.git.UnstageHunkLines(path, hunk, lines, m.logger); err != nil {
+			return m.logAndWrapError("unstage hunk", err, map[string]any{
+				"file":      path,
+				"new_start": hunk.NewStart,
+			})
+		}
+		return m.checkWorkingTreeChanges()
+	})
+}
+
+// DiscardFile restores the index version of a file into the worktree
+func (m Model) DiscardFile(path string) tea.Cmd {
+	return m.withGitService(func() tea.Msg {
+		if err := m.git.DiscardFile(path, m.logger); err != nil {
+			return m.logAndWrapError("discard file", err, map[string]any{
+				"file": path,
+			})
+		}
+		return m.checkWorkingTreeChanges()
+	})
+}
+
+// DiscardHunk reverts a hunk of the unstaged diff in the worktree
+func (m Model) DiscardHunk(path string, hunk Hunk) tea.Cmd {
+	return m.withGitService(func() tea.Msg {
+		if err := m.git.DiscardHunk(path, hunk, m.logger); err != nil {
+			return m.logAndWrapError("discard hunk", err, map[string]any{
+				"file":      path,
+				"new_start": hunk.NewStart,
+			})
+		}
+		return m.checkWorkingTreeChanges()
+	})
+}
+
 // Messages
 
 type gitInfoMsg struct {
@@ -240,6 +574,25 @@ type commitsLoadedMsg struct {
 	commits []Commit
 }
 
+type commitLogLoadedMsg struct {
+	offset  int // position of the first commit in the log
+	commits []Commit
+	hasMore bool
+}
+
+type commitDiffLoadedMsg struct {
+	commit Commit
+	files  []FileDiff
+}
+
+type baseBranchMsg struct {
+	base *BaseBranch // nil when no base branch could be resolved
+}
+
+type branchesLoadedMsg struct {
+	branches []string
+}
+
 type errMsg struct {
 	err error
 }
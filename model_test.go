@@ -1,6 +1,10 @@
 package main
 
 import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
@@ -190,6 +194,383 @@ func TestModelUpdateToggleViewMode(t *testing.T) {
 	}
 }
 
+func TestModelUpdateToggleSideBySide(t *testing.T) {
+	model := setupModel(t)
+
+	msg := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'|'}}
+	newModel, cmd := model.Update(msg)
+
+	if newModel.(Model).diffViewMode != SideBySide {
+		t.Errorf("Update('|') diffViewMode = %v, want %v", newModel.(Model).diffViewMode, SideBySide)
+	}
+	if cmd != nil {
+		t.Error("Update('|') from Diff Only should not reload diffs")
+	}
+
+	newModel, _ = newModel.Update(msg)
+	if newModel.(Model).diffViewMode != DiffOnly {
+		t.Errorf("Update('|') second toggle diffViewMode = %v, want %v", newModel.(Model).diffViewMode, DiffOnly)
+	}
+}
+
+func TestModelCurrentHunkFollowsScroll(t *testing.T) {
+	model := setupModel(t)
+	model.files = []FileDiff{{Path: "file.go", ChangeType: Modified}}
+	model.diffFiles = []FileDiff{{
+		Path: "file.go",
+		Hunks: []Hunk{
+			{OldStart: 1, NewStart: 1, Lines: []DiffLine{{Type: LineAdded, Content: "a"}, {Type: LineAdded, Content: "b"}}},
+			{OldStart: 10, NewStart: 12, Lines: []DiffLine{{Type: LineRemoved, Content: "c"}}},
+		},
+	}}
+	model.buildFileTree()
+	model.panel = DiffPanel
+
+	// Layout: file header (0), hunk 0 separator (1) + 2 rows, hunk 1 separator (4)
+	for scroll, wantHunk := range map[int]int{0: 0, 1: 0, 3: 0, 4: 1, 5: 1} {
+		model.diffScroll = scroll
+		loc, ok := model.currentHunk()
+		if !ok || loc.hunkIdx != wantHunk {
+			t.Errorf("currentHunk() at scroll %d = %d (ok=%v), want %d", scroll, loc.hunkIdx, ok, wantHunk)
+		}
+	}
+
+	model.diffMode = Staged
+	if cmd := model.stageCurrentHunk(); cmd != nil {
+		t.Error("stageCurrentHunk() should be a no-op outside Unstaged mode")
+	}
+	model.diffMode = Unstaged
+	if cmd := model.unstageCurrentHunk(); cmd != nil {
+		t.Error("unstageCurrentHunk() should be a no-op outside Staged mode")
+	}
+}
+
+func TestModelVisualSelection(t *testing.T) {
+	model := setupModel(t)
+	model.files = []FileDiff{{Path: "file.go", ChangeType: Modified}}
+	model.diffFiles = []FileDiff{{
+		Path: "file.go",
+		Hunks: []Hunk{{OldStart: 1, NewStart: 1, Lines: []DiffLine{
+			{Type: LineContext, Content: "a"},
+			{Type: LineRemoved, Content: "b"},
+			{Type: LineAdded, Content: "B"},
+			{Type: LineAdded, Content: "C"},
+			{Type: LineContext, Content: "d"},
+		}}},
+	}}
+	model.buildFileTree()
+	model.panel = DiffPanel
+	model.height = 40
+
+	press := func(m Model, key string) Model {
+		var msg tea.KeyMsg
+		if key == "esc" {
+			msg = tea.KeyMsg{Type: tea.KeyEsc}
+		} else {
+			msg = tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)}
+		}
+		updated, _ := m.Update(msg)
+		return updated.(Model)
+	}
+
+	model = press(model, "v")
+	if model.selection == nil || model.selection.lines() != (lineRange{first: 1, last: 1}) {
+		t.Fatalf("v should select the first changed line, got %+v", model.selection)
+	}
+
+	model = press(model, "j")
+	model = press(model, "j")
+	model = press(model, "j")
+	model = press(model, "j")
+	if got := model.selection.lines(); got != (lineRange{first: 1, last: 4}) {
+		t.Errorf("j should extend the selection up to the hunk end, got %+v", got)
+	}
+
+	model = press(model, "esc")
+	if model.selection != nil {
+		t.Fatal("esc should leave visual mode")
+	}
+
+	model = press(model, "V")
+	if got := model.selection.lines(); got != (lineRange{first: 1, last: 3}) {
+		t.Errorf("V should select all changed lines, got %+v", got)
+	}
+	model = press(model, "o")
+	if model.selection.cursor != 1 || model.selection.anchor != 3 {
+		t.Errorf("o should swap selection ends, got %+v", model.selection)
+	}
+
+	model.diffMode = Staged
+	model = press(model, "a")
+	if model.selection == nil {
+		t.Error("a should keep the selection when staging is unavailable in Staged mode")
+	}
+}
+
+func TestModelDiscardNeedsConfirmation(t *testing.T) {
+	model := setupModel(t)
+	model.files = []FileDiff{{Path: "file.go", ChangeType: Modified}}
+	model.buildFileTree()
+	model.width = 100
+	model.height = 30
+
+	newModel, cmd := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'d'}})
+	model = newModel.(Model)
+	if cmd != nil {
+		t.Fatal("d should only ask for confirmation")
+	}
+	if model.pendingDiscard == nil || model.pendingDiscard.path != "file.go" || model.pendingDiscard.hunk != nil {
+		t.Fatalf("d in the file tree should request a whole-file discard, got %+v", model.pendingDiscard)
+	}
+	if view := stripAnsi(model.View()); !strings.Contains(view, "Discard all unstaged changes in file.go?") {
+		t.Errorf("confirmation modal not rendered:\n%s", view)
+	}
+
+	// Other keys are ignored while the modal is open
+	newModel, _ = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'s'}})
+	model = newModel.(Model)
+	if model.diffMode != Unstaged || model.pendingDiscard == nil {
+		t.Fatal("keys other than y/n should not act while confirming")
+	}
+
+	newModel, cmd = model.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	model = newModel.(Model)
+	if model.pendingDiscard != nil || cmd != nil {
+		t.Fatal("esc should cancel the discard without running it")
+	}
+
+	model.diffMode = Staged
+	newModel, _ = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'d'}})
+	if newModel.(Model).pendingDiscard != nil {
+		t.Error("discard should only be available in Unstaged mode")
+	}
+}
+
+func TestModelBranchPicker(t *testing.T) {
+	model := setupModel(t)
+	model.width = 100
+	model.height = 30
+	model.resolvedBase = &BaseBranch{Name: "trunk", Source: BaseFromRemoteHead}
+
+	newModel, _ := model.Update(branchesLoadedMsg{branches: []string{"feature", "trunk", "origin/trunk"}})
+	model = newModel.(Model)
+	if model.branchPicker == nil || model.branchPicker.cursor != 1 {
+		t.Fatalf("picker should open on the current base, got %+v", model.branchPicker)
+	}
+	if view := stripAnsi(model.View()); !strings.Contains(view, "trunk (current)") {
+		t.Errorf("branch picker not rendered:\n%s", view)
+	}
+
+	newModel, _ = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'j'}})
+	model = newModel.(Model)
+	newModel, cmd := model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	model = newModel.(Model)
+	if model.branchPicker != nil || cmd == nil {
+		t.Fatal("enter should close the picker and reload")
+	}
+	if model.baseBranch != "origin/trunk" || model.diffMode != BranchCompare {
+		t.Errorf("base = %q, mode = %v; want origin/trunk in Branch Compare", model.baseBranch, model.diffMode)
+	}
+}
+
+func TestModelPathspecPrompt(t *testing.T) {
+	model := setupModel(t)
+	model.width = 100
+	model.height = 30
+
+	newModel, _ := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'P'}})
+	model = newModel.(Model)
+	if !model.pathspecMode {
+		t.Fatal("P should open the pathspec prompt")
+	}
+	newModel, _ = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("src :!src/gen")})
+	model = newModel.(Model)
+	newModel, cmd := model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	model = newModel.(Model)
+	if model.pathspecMode || cmd == nil {
+		t.Fatal("enter should close the prompt and reload")
+	}
+	if !model.pathspec.Matches("src/a.go") || model.pathspec.Matches("src/gen/b.go") || model.pathspec.Matches("main.go") {
+		t.Errorf("pathspec %q not applied as expected", model.pathspec)
+	}
+	if view := stripAnsi(model.View()); !strings.Contains(view, "Paths: src :!src/gen") {
+		t.Errorf("header should show the pathspec:\n%s", view)
+	}
+
+	// The prompt starts from the current pathspec; clearing it removes the filter
+	newModel, _ = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'P'}})
+	model = newModel.(Model)
+	for range model.pathspecInput {
+		newModel, _ = model.Update(tea.KeyMsg{Type: tea.KeyBackspace})
+		model = newModel.(Model)
+	}
+	newModel, _ = model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if !newModel.(Model).pathspec.IsEmpty() {
+		t.Error("an empty prompt should clear the pathspec")
+	}
+}
+
+func TestModelExportPatchPrompt(t *testing.T) {
+	model := setupModel(t)
+	model.width = 100
+	model.height = 30
+	model.diffFiles = []FileDiff{{
+		Path:       "a.txt",
+		ChangeType: Modified,
+		Hunks: []Hunk{{OldStart: 1, OldCount: 1, NewStart: 1, NewCount: 1, Lines: []DiffLine{
+			{Type: LineRemoved, Content: "old", OldLineNum: 1},
+			{Type: LineAdded, Content: "new", NewLineNum: 1},
+		}}},
+	}}
+
+	model.whitespace = IgnoreAllSpace
+	newModel, _ := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'e'}})
+	if newModel.(Model).exportMode || newModel.(Model).notice == "" {
+		t.Error("e should refuse to export while whitespace is ignored")
+	}
+
+	model.whitespace = WhitespaceExact
+	newModel, _ = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'e'}})
+	model = newModel.(Model)
+	if !model.exportMode || model.exportInput != defaultPatchFile {
+		t.Fatalf("e should open the export prompt with %q, got %q", defaultPatchFile, model.exportInput)
+	}
+
+	path := filepath.Join(t.TempDir(), "review.patch")
+	model.exportInput = path
+	newModel, cmd := model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	model = newModel.(Model)
+	if model.exportMode || cmd == nil {
+		t.Fatal("enter should close the prompt and write the patch")
+	}
+	newModel, _ = model.Update(cmd())
+	model = newModel.(Model)
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read exported patch: %v", err)
+	}
+	want := "diff --git a/a.txt b/a.txt\n--- a/a.txt\n+++ b/a.txt\n@@ -1,1 +1,1 @@\n-old\n+new\n"
+	if string(content) != want {
+		t.Errorf("exported patch = %q, want %q", content, want)
+	}
+	if view := stripAnsi(model.View()); !strings.Contains(view, "Exported 1 file(s) to") {
+		t.Errorf("footer should confirm the export:\n%s", view)
+	}
+
+	// The notice goes away with the next key press
+	newModel, _ = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'k'}})
+	if newModel.(Model).notice != "" {
+		t.Error("notice should be cleared by the next key")
+	}
+}
+
+func TestModelPatchInput(t *testing.T) {
+	files, err := ParsePatch(strings.NewReader(formatPatchMail))
+	if err != nil {
+		t.Fatalf("ParsePatch: %v", err)
+	}
+	model := NewModel(nil, nil).WithInput(patchDiffInput("fix.patch", files))
+	model.width = 100
+	model.height = 30
+
+	// No repository is needed: Init delivers the parsed files
+	newModel, _ := model.Update(model.Init()())
+	model = newModel.(Model)
+	if model.err != nil || len(model.diffFiles) != len(files) || len(model.flattenTree()) == 0 {
+		t.Fatalf("patch not loaded: err %v, %d diff files", model.err, len(model.diffFiles))
+	}
+	if view := stripAnsi(model.View()); !strings.Contains(view, "[Patch]") {
+		t.Errorf("header should show the patch mode:\n%s", view)
+	}
+
+	// Mode cycling and context changes do not apply to a patch
+	for _, key := range []rune{'s', 'b', 'o', 'A', 'w', 'W'} {
+		newModel, cmd := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{key}})
+		if newModel.(Model).diffMode != InputDiff || cmd != nil {
+			t.Errorf("key %q should be ignored in patch mode", key)
+		}
+	}
+
+	// The pathspec filters the patch
+	model.pathspec, _ = ParsePathspec([]string{"lib"}, "")
+	newModel, _ = model.Update(model.LoadInputDiffs()())
+	if got := newModel.(Model).diffFiles; len(got) != 1 || got[0].Path != "lib/parse.go" {
+		t.Errorf("pathspec lib selected %+v", got)
+	}
+}
+
+func TestModelCycleDiffAlgorithm(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{
+		"old.java": strings.Join(histogramExampleOld, "\n") + "\n",
+		"new.java": strings.Join(histogramExampleNew, "\n") + "\n",
+	})
+	diff, err := newNoIndexDiff(filepath.Join(dir, "old.java"), filepath.Join(dir, "new.java"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	model := NewModel(nil, nil).WithInput(diff.diffInput())
+	model.width = 100
+	model.height = 30
+	newModel, _ := model.Update(model.Init()())
+	model = newModel.(Model)
+	myersAdded := model.diffFiles[0].LinesAdded
+
+	newModel, cmd := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'A'}})
+	model = newModel.(Model)
+	if model.diffAlgorithm != AlgorithmPatience || model.notice != "Diff algorithm: patience" || cmd == nil {
+		t.Fatalf("A should switch to patience and reload, got %v, notice %q", model.diffAlgorithm, model.notice)
+	}
+	newModel, _ = model.Update(cmd())
+	model = newModel.(Model)
+	if added := model.diffFiles[0].LinesAdded; added == myersAdded {
+		t.Errorf("patience should add other lines than myers (%d)", myersAdded)
+	}
+	if view := stripAnsi(model.View()); !strings.Contains(view, "[Patience]") {
+		t.Errorf("header should show the algorithm:\n%s", view)
+	}
+}
+
+func TestModelWhitespaceToggles(t *testing.T) {
+	model := setupModel(t)
+	model.width = 100
+	model.height = 30
+	model.panel = DiffPanel
+	model.diffFiles = []FileDiff{{
+		Path: "a.txt",
+		Hunks: []Hunk{{OldStart: 1, OldCount: 1, NewStart: 1, NewCount: 1, Lines: []DiffLine{
+			{Type: LineRemoved, Content: "old", OldLineNum: 1},
+			{Type: LineAdded, Content: "new", NewLineNum: 1},
+		}}},
+	}}
+
+	for _, want := range []WhitespaceMode{IgnoreCRAtEOL, IgnoreSpaceChange, IgnoreAllSpace} {
+		newModel, cmd := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'w'}})
+		model = newModel.(Model)
+		if model.whitespace != want || model.notice != "Whitespace: "+want.Label() || cmd == nil {
+			t.Fatalf("w should switch to %q and reload, got %q, notice %q", want.Label(), model.whitespace.Label(), model.notice)
+		}
+	}
+	if view := stripAnsi(model.View()); !strings.Contains(view, "[Ignore all space]") {
+		t.Errorf("header should show the whitespace mode:\n%s", view)
+	}
+
+	// Hunks that leave out differences cannot be applied to the files
+	newModel, cmd := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'a'}})
+	model = newModel.(Model)
+	if cmd != nil || !strings.Contains(model.notice, "cannot be staged") {
+		t.Errorf("a should refuse to stage while whitespace is ignored, notice %q", model.notice)
+	}
+
+	newModel, _ = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'w'}})
+	newModel, _ = newModel.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'W'}})
+	model = newModel.(Model)
+	if model.whitespace != WhitespaceExact || !model.blankLines || !model.diffOptions().IgnoreBlankLines {
+		t.Errorf("w should wrap to exact whitespace and W hide blank lines, got %q, %v", model.whitespace.Label(), model.blankLines)
+	}
+}
+
 func TestModelUpdateTogglePanel(t *testing.T) {
 	model := setupModel(t)
 
@@ -457,6 +838,39 @@ func TestBuildFileTree(t *testing.T) {
 	}
 }
 
+func TestFileTreeShowsRenames(t *testing.T) {
+	model := setupModel(t)
+	model.width = 100
+	model.height = 30
+
+	// Status lists both paths of the rename; the diff pairs them
+	model.applyFilesLoaded(filesLoadedMsg{files: []FileDiff{
+		{Path: "docs/guide.md", ChangeType: Added},
+		{Path: "old/util.go", ChangeType: Deleted},
+		{Path: "src/util.go", ChangeType: Added},
+	}})
+	model.applyAllDiffsLoaded(allDiffsLoadedMsg{files: []FileDiff{
+		{Path: "docs/guide.md", ChangeType: Added, LinesAdded: 3},
+		{Path: "src/util.go", OldPath: "old/util.go", ChangeType: Renamed, Similarity: 100},
+	}})
+
+	var names []string
+	for _, node := range model.flattenTree() {
+		if !node.isDir {
+			names = append(names, treeNodeName(node))
+		}
+	}
+	if want := []string{"guide.md", "old/util.go → util.go"}; !slices.Equal(names, want) {
+		t.Errorf("tree files = %q, want %q", names, want)
+	}
+
+	model.selectedIndex = len(model.flattenTree()) - 1
+	view := stripAnsi(model.View())
+	if !strings.Contains(view, "old/util.go → src/util.go") || !strings.Contains(view, "Renamed without changes") {
+		t.Errorf("diff panel should show the rename:\n%s", view)
+	}
+}
+
 func TestFlattenTree(t *testing.T) {
 	model := setupModel(t)
 
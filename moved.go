@@ -0,0 +1,280 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// minMovedAlnum is how many letters and digits a block needs to count as
+// moved, as git's --color-moved: shorter blocks such as a lone "}" reappear
+// by coincidence
+const minMovedAlnum = 20
+
+// movedLine locates a removed or added line within a []FileDiff
+type movedLine struct {
+	file, hunk, line int
+	run              int // lines of the same run are adjacent in their hunk
+	key              string
+}
+
+// markMovedLines finds blocks of removed lines that are added again elsewhere,
+// in the same file or another one, and gives both copies of each block the
+// same MoveID. Lines are compared in whitespace's normalized form. Earlier
+// marks are cleared.
+func markMovedLines(files []FileDiff, whitespace WhitespaceMode) {
+	var removed, added []movedLine
+	run := 0
+	for f := range files {
+		for h := range files[f].Hunks {
+			lines := files[f].Hunks[h].Lines
+			for i := range lines {
+				lines[i].MoveID = 0
+				if i == 0 || lines[i].Type != lines[i-1].Type {
+					run++
+				}
+				line := movedLine{file: f, hunk: h, line: i, run: run, key: whitespace.normalize(lines[i].Content)}
+				switch lines[i].Type {
+				case LineRemoved:
+					removed = append(removed, line)
+				case LineAdded:
+					added = append(added, line)
+				}
+			}
+		}
+	}
+	if len(removed) == 0 || len(added) == 0 {
+		return
+	}
+
+	addedByKey := make(map[string][]int)
+	for i, line := range added {
+		addedByKey[line.key] = append(addedByKey[line.key], i)
+	}
+	addedUsed := make([]bool, len(added))
+	lineAt := func(l movedLine) *DiffLine {
+		return &files[l.file].Hunks[l.hunk].Lines[l.line]
+	}
+	removedHashes, addedHashes := hashMovedLines(removed, added)
+	reach := movedBlockReach(removed)
+
+	moveID := 0
+	for i := 0; i < len(removed); {
+		// The shortest block starting here with enough letters and digits.
+		// Only added copies of all of it can start a moved block, so lines
+		// such as "}" repeated thousands of times are never extended.
+		if reach[i] == 0 {
+			i++
+			continue
+		}
+		need := reach[i] - i
+		want := removedHashes.block(i, need)
+
+		// The longest block starting here that is added again in one run
+		bestStart, bestLen := 0, 0
+		for _, start := range addedByKey[removed[i].key] {
+			if start+need > len(added) || addedHashes.block(start, need) != want {
+				continue
+			}
+			n := 0
+			for i+n < len(removed) && start+n < len(added) &&
+				removed[i+n].run == removed[i].run && added[start+n].run == added[start].run &&
+				!addedUsed[start+n] && removed[i+n].key == added[start+n].key {
+				n++
+			}
+			if n > bestLen {
+				bestStart, bestLen = start, n
+			}
+		}
+		if bestLen < need {
+			i++
+			continue
+		}
+
+		moveID++
+		for n := 0; n < bestLen; n++ {
+			lineAt(removed[i+n]).MoveID = moveID
+			lineAt(added[bestStart+n]).MoveID = moveID
+			addedUsed[bestStart+n] = true
+		}
+		i += bestLen
+	}
+}
+
+// movedBlockReach returns, for each line, the end of the shortest block of
+// its run that starts there and has minMovedAlnum letters and digits, or 0
+// when the rest of the run has fewer
+func movedBlockReach(lines []movedLine) []int {
+	reach := make([]int, len(lines))
+	end, alnum := 0, 0
+	for i := range lines {
+		if end < i {
+			end, alnum = i, 0
+		}
+		for alnum < minMovedAlnum && end < len(lines) && lines[end].run == lines[i].run {
+			alnum += countAlnum(lines[end].key)
+			end++
+		}
+		if alnum >= minMovedAlnum {
+			reach[i] = end
+		}
+		alnum -= countAlnum(lines[i].key)
+	}
+	return reach
+}
+
+// blockHashes holds prefix hashes of a sequence of lines, so any block of
+// them hashes in constant time
+type blockHashes struct {
+	prefix, pow []uint64
+}
+
+// hashMovedLines hashes the keys of removed and added lines alike
+func hashMovedLines(removed, added []movedLine) (blockHashes, blockHashes) {
+	ids := make(map[string]uint64)
+	hash := func(lines []movedLine) blockHashes {
+		h := blockHashes{prefix: make([]uint64, len(lines)+1), pow: make([]uint64, len(lines)+1)}
+		h.pow[0] = 1
+		for i, line := range lines {
+			id, ok := ids[line.key]
+			if !ok {
+				id = uint64(len(ids)) + 1
+				ids[line.key] = id
+			}
+			h.prefix[i+1] = h.prefix[i]*movedHashBase + id
+			h.pow[i+1] = h.pow[i] * movedHashBase
+		}
+		return h
+	}
+	return hash(removed), hash(added)
+}
+
+// movedHashBase is the odd multiplier of the block hash, computed modulo 2^64
+const movedHashBase = 1_000_003
+
+// block returns the hash of the n lines starting at start
+func (h blockHashes) block(start, n int) uint64 {
+	return h.prefix[start+n] - h.prefix[start]*h.pow[n]
+}
+
+func countAlnum(s string) int {
+	count := 0
+	for _, r := range s {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			count++
+		}
+	}
+	return count
+}
+
+// jumpToMove scrolls from the first moved line at or below the top of the
+// diff panel to the other copy of its block, selecting that copy's file
+func (m *Model) jumpToMove() {
+	if m.panel != DiffPanel || !m.diffViewMode.showsHunks() {
+		return
+	}
+
+	line, ok := m.firstVisibleMovedLine()
+	if !ok {
+		m.notice = "No moved lines below"
+		return
+	}
+	path, target, ok := findMoveCounterpart(m.diffFiles, line)
+	if !ok {
+		return
+	}
+
+	if !m.selectTreePath(path) {
+		m.notice = fmt.Sprintf("%s is not in the file list", path)
+		return
+	}
+	if row, ok := m.movedLineRow(target); ok {
+		m.diffScroll = row
+	}
+
+	if target.Type == LineRemoved {
+		m.notice = fmt.Sprintf("Moved from %s:%d", path, target.OldLineNum)
+	} else {
+		m.notice = fmt.Sprintf("Moved to %s:%d", path, target.NewLineNum)
+	}
+}
+
+// firstVisibleMovedLine returns the first moved line rendered at or after
+// the top row of the diff panel
+func (m Model) firstVisibleMovedLine() (DiffLine, bool) {
+	layout := m.computeDiffLayout(m.getSelectedDiffFiles())
+	for i, loc := range layout.hunks {
+		hunk := loc.hunk()
+		for idx, line := range hunk.Lines {
+			if line.MoveID == 0 {
+				continue
+			}
+			if layout.hunkStarts[i]+1+m.hunkRowOfLine(hunk, idx) >= m.diffScroll {
+				return line, true
+			}
+		}
+	}
+	return DiffLine{}, false
+}
+
+// movedLineRow returns the diff panel row of the first line of target's
+// copy of its moved block
+func (m Model) movedLineRow(target DiffLine) (int, bool) {
+	layout := m.computeDiffLayout(m.getSelectedDiffFiles())
+	for i, loc := range layout.hunks {
+		hunk := loc.hunk()
+		for idx, line := range hunk.Lines {
+			if line.MoveID == target.MoveID && line.Type == target.Type {
+				return layout.hunkStarts[i] + 1 + m.hunkRowOfLine(hunk, idx), true
+			}
+		}
+	}
+	return 0, false
+}
+
+// findMoveCounterpart returns the first line of the other copy of line's
+// moved block, and the path of its file
+func findMoveCounterpart(files []FileDiff, line DiffLine) (string, DiffLine, bool) {
+	for _, file := range files {
+		for _, hunk := range file.Hunks {
+			for _, candidate := range hunk.Lines {
+				if candidate.MoveID == line.MoveID && candidate.Type != line.Type {
+					return file.Path, candidate, true
+				}
+			}
+		}
+	}
+	return "", DiffLine{}, false
+}
+
+// selectTreePath selects the file at path in the file tree, expanding its
+// directories, and reports whether the tree shows it
+func (m *Model) selectTreePath(path string) bool {
+	if selected, ok := m.selectedFilePath(); ok && selected == path {
+		return true
+	}
+	expandDirectoriesTo(m.fileTree, path)
+	for i, node := range m.flattenTree() {
+		if node.isDir || node.path != path {
+			continue
+		}
+		m.selectedIndex = i
+		m.diffScroll = 0
+		visibleHeight := m.visibleContentRows()
+		if i < m.scrollOffset || i >= m.scrollOffset+visibleHeight {
+			m.scrollOffset = max(0, i-visibleHeight/2)
+		}
+		return true
+	}
+	return false
+}
+
+// expandDirectoriesTo expands the directories that contain path
+func expandDirectoriesTo(nodes []TreeNode, path string) {
+	for i := range nodes {
+		if nodes[i].isDir && strings.HasPrefix(path, nodes[i].path+"/") {
+			nodes[i].isExpanded = true
+			expandDirectoriesTo(nodes[i].children, path)
+		}
+	}
+}
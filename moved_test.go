@@ -0,0 +1,133 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// movedTestFiles moves helper from a.go to b.go, reindented, and edits a
+// closing brace in place
+func movedTestFiles() []FileDiff {
+	return []FileDiff{
+		{Path: "a.go", Hunks: []Hunk{{OldStart: 1, OldCount: 5, NewStart: 1, NewCount: 2, Lines: []DiffLine{
+			{Type: LineContext, Content: "package a", OldLineNum: 1, NewLineNum: 1},
+			{Type: LineRemoved, Content: "func helper() int {", OldLineNum: 2},
+			{Type: LineRemoved, Content: "\treturn 42", OldLineNum: 3},
+			{Type: LineRemoved, Content: "}", OldLineNum: 4},
+			{Type: LineRemoved, Content: "}", OldLineNum: 5},
+			{Type: LineAdded, Content: "// end", NewLineNum: 2},
+		}}}},
+		{Path: "b.go", Hunks: []Hunk{{OldStart: 1, OldCount: 1, NewStart: 1, NewCount: 5, Lines: []DiffLine{
+			{Type: LineContext, Content: "package b", OldLineNum: 1, NewLineNum: 1},
+			{Type: LineAdded, Content: "}", NewLineNum: 2},
+			{Type: LineAdded, Content: "func helper() int {", NewLineNum: 3},
+			{Type: LineAdded, Content: "    return 42", NewLineNum: 4},
+			{Type: LineAdded, Content: "}", NewLineNum: 5},
+		}}}},
+	}
+}
+
+func moveIDs(file FileDiff) []int {
+	var ids []int
+	for _, line := range file.Hunks[0].Lines {
+		ids = append(ids, line.MoveID)
+	}
+	return ids
+}
+
+func TestMarkMovedLines(t *testing.T) {
+	files := movedTestFiles()
+
+	// Reindented, the block is not the same text
+	markMovedLines(files, WhitespaceExact)
+	for _, file := range files {
+		for _, id := range moveIDs(file) {
+			if id != 0 {
+				t.Fatalf("%s: moves = %v, want none", file.Path, moveIDs(file))
+			}
+		}
+	}
+
+	markMovedLines(files, IgnoreSpaceChange)
+	if got, want := moveIDs(files[0]), []int{0, 1, 1, 1, 0, 0}; !slices.Equal(got, want) {
+		t.Errorf("a.go moves = %v, want %v", got, want)
+	}
+	// The lone brace is too short to count as moved
+	if got, want := moveIDs(files[1]), []int{0, 0, 1, 1, 1}; !slices.Equal(got, want) {
+		t.Errorf("b.go moves = %v, want %v", got, want)
+	}
+
+	// Marks from an earlier pass are cleared
+	markMovedLines(files, WhitespaceExact)
+	if moveIDs(files[0])[1] != 0 {
+		t.Error("markMovedLines should clear earlier moves")
+	}
+}
+
+// Thousands of identical lines, as in generated code, must not make the
+// search for moved blocks slow
+func TestMarkMovedLinesRepeatedLines(t *testing.T) {
+	const count = 5000
+	hunk := func(lineType LineType, contents ...string) []Hunk {
+		lines := make([]DiffLine, 0, count)
+		for i := 0; i < count; i++ {
+			for _, content := range contents {
+				lines = append(lines, DiffLine{Type: lineType, Content: content})
+			}
+		}
+		return []Hunk{{Lines: lines}}
+	}
+	files := []FileDiff{
+		{Path: "a.go", Hunks: append(hunk(LineRemoved, "}"), hunk(LineRemoved, "\treturn nil, err")...)},
+		{Path: "b.go", Hunks: slices.Concat(hunk(LineAdded, "}"), hunk(LineAdded, "\treturn nil, err", "}"), hunk(LineAdded, "\treturn nil, err"))},
+	}
+	files[0].Hunks[0].Lines = append(files[0].Hunks[0].Lines, DiffLine{Type: LineRemoved, Content: "return errors.New(\"unreachable\")"})
+
+	markMovedLines(files, WhitespaceExact)
+	for _, line := range files[0].Hunks[0].Lines {
+		if line.MoveID != 0 {
+			t.Fatal("braces without the line after them should not be moved")
+		}
+	}
+	for i, line := range files[0].Hunks[1].Lines {
+		if line.MoveID == 0 {
+			t.Fatalf("returns: line %d not moved", i)
+		}
+	}
+}
+
+func TestModelJumpToMove(t *testing.T) {
+	model := setupModel(t)
+	model.width = 100
+	model.height = 30
+	model.whitespace = IgnoreAllSpace
+	newModel, _ := model.Update(allDiffsLoadedMsg{files: movedTestFiles()})
+	model = newModel.(Model)
+	model.panel = DiffPanel
+
+	if path, _ := model.selectedFilePath(); path != "a.go" {
+		t.Fatalf("selected %q, want a.go", path)
+	}
+	_, _, style := diffLinePrefixAndStyles(model.diffFiles[0].Hunks[0].Lines[1])
+	if style.GetForeground() != diffMovedRemovedStyle.GetForeground() {
+		t.Error("moved lines should have the moved style")
+	}
+
+	newModel, _ = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'m'}})
+	model = newModel.(Model)
+	if path, _ := model.selectedFilePath(); path != "b.go" || model.notice != "Moved to b.go:3" {
+		t.Fatalf("m should jump to b.go:3, selected %q, notice %q", path, model.notice)
+	}
+	// File header, hunk header, then the context line and the brace
+	if model.diffScroll != 4 {
+		t.Errorf("diffScroll = %d, want 4", model.diffScroll)
+	}
+
+	newModel, _ = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'m'}})
+	model = newModel.(Model)
+	if path, _ := model.selectedFilePath(); path != "a.go" || model.notice != "Moved from a.go:2" {
+		t.Errorf("m should jump back to a.go:2, selected %q, notice %q", path, model.notice)
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+)
+
+// noIndexDiff compares two files or two directory trees outside a repository,
+// as git diff --no-index does
+type noIndexDiff struct {
+	oldPath string
+	newPath string
+}
+
+// newNoIndexDiff checks that both paths exist. A file compared with a
+// directory is compared with the file of the same name in that directory.
+func newNoIndexDiff(oldPath, newPath string) (noIndexDiff, error) {
+	oldInfo, err := os.Stat(oldPath)
+	if err != nil {
+		return noIndexDiff{}, fmt.Errorf("--no-index: %w", err)
+	}
+	newInfo, err := os.Stat(newPath)
+	if err != nil {
+		return noIndexDiff{}, fmt.Errorf("--no-index: %w", err)
+	}
+
+	switch {
+	case oldInfo.IsDir() && !newInfo.IsDir():
+		oldPath = filepath.Join(oldPath, filepath.Base(newPath))
+	case !oldInfo.IsDir() && newInfo.IsDir():
+		newPath = filepath.Join(newPath, filepath.Base(oldPath))
+	}
+	return noIndexDiff{oldPath: oldPath, newPath: newPath}, nil
+}
+
+// fileDiffs computes the diff of the two files, or of every file under the
+// two directories. Added, deleted and modified files come from the walk.
+func (d noIndexDiff) fileDiffs(diffOpts DiffOptions) ([]FileDiff, error) {
+	// Two files are compared under the new name, whatever the old one is
+	name := filepath.ToSlash(d.newPath)
+	oldFiles, err := walkNoIndexFiles(d.oldPath, name)
+	if err != nil {
+		return nil, err
+	}
+	newFiles, err := walkNoIndexFiles(d.newPath, name)
+	if err != nil {
+		return nil, err
+	}
+
+	pathSet := make(map[string]struct{}, len(oldFiles)+len(newFiles))
+	for path := range oldFiles {
+		pathSet[path] = struct{}{}
+	}
+	for path := range newFiles {
+		pathSet[path] = struct{}{}
+	}
+
+	files := make([]FileDiff, 0, len(pathSet))
+	for _, path := range sortedPathsFromSet(pathSet) {
+		oldContent, oldExists, err := readNoIndexFile(oldFiles, path)
+		if err != nil {
+			return nil, err
+		}
+		newContent, newExists, err := readNoIndexFile(newFiles, path)
+		if err != nil {
+			return nil, err
+		}
+
+		file, err := newFileDiffFromContents(path, oldContent, newContent, oldExists, newExists, false, diffOpts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compute diff for %s: %w", path, err)
+		}
+		if file == nil {
+			continue
+		}
+		file.OldMode, file.NewMode = noIndexFileMode(oldFiles[path]), noIndexFileMode(newFiles[path])
+		files = append(files, *file)
+	}
+	return files, nil
+}
+
+// walkNoIndexFiles maps the slash-separated path of every regular file under
+// root to its name on disk. A root that is a file is mapped as fileName.
+func walkNoIndexFiles(root, fileName string) (map[string]string, error) {
+	info, err := os.Stat(root)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return map[string]string{}, nil
+		}
+		return nil, err
+	}
+	if !info.IsDir() {
+		return map[string]string{fileName: root}, nil
+	}
+
+	files := make(map[string]string)
+	err = filepath.WalkDir(root, func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, name)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = name
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("walk directory: %w", err)
+	}
+	return files, nil
+}
+
+// readNoIndexFile reads path from one side of the comparison
+func readNoIndexFile(files map[string]string, path string) ([]byte, bool, error) {
+	name, ok := files[path]
+	if !ok {
+		return nil, false, nil
+	}
+	content, err := os.ReadFile(name)
+	if err != nil {
+		return nil, false, err
+	}
+	return content, true, nil
+}
+
+// noIndexFileMode returns the mode of a file on disk, 0 when it cannot be read
+func noIndexFileMode(name string) filemode.FileMode {
+	info, err := os.Stat(name)
+	if err != nil {
+		return filemode.Empty
+	}
+	mode, err := filemode.NewFromOSFileMode(info.Mode())
+	if err != nil {
+		return filemode.Empty
+	}
+	return mode
+}
+
+// diffInput shows the comparison in the TUI; the context size can change
+// since the hunks are computed from the files
+func (d noIndexDiff) diffInput() diffInput {
+	return diffInput{
+		label:   "No Index",
+		title:   fmt.Sprintf("No index: %s → %s", d.oldPath, d.newPath),
+		compute: d.fileDiffs,
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func writeTestFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for path, content := range files {
+		writeWorktreeFile(t, root, path, content)
+	}
+}
+
+func TestNoIndexDirectories(t *testing.T) {
+	dir := t.TempDir()
+	oldDir, newDir := filepath.Join(dir, "old"), filepath.Join(dir, "new")
+	writeTestFiles(t, oldDir, map[string]string{"app.conf": "x=1\ny=2\n", "sub/gone.txt": "bye\n", "same.txt": "same\n"})
+	writeTestFiles(t, newDir, map[string]string{"app.conf": "x=1\ny=3\n", "sub/added.txt": "hi\n", "same.txt": "same\n"})
+
+	diff, err := newNoIndexDiff(oldDir, newDir)
+	if err != nil {
+		t.Fatalf("newNoIndexDiff: %v", err)
+	}
+	files, err := diff.fileDiffs(DiffOptions{Context: DefaultDiffContext})
+	if err != nil {
+		t.Fatalf("fileDiffs: %v", err)
+	}
+
+	want := []struct {
+		path       string
+		changeType ChangeType
+	}{
+		{"app.conf", Modified},
+		{"sub/added.txt", Added},
+		{"sub/gone.txt", Deleted},
+	}
+	if len(files) != len(want) {
+		t.Fatalf("got %d files, want %d: %+v", len(files), len(want), files)
+	}
+	for i, w := range want {
+		if files[i].Path != w.path || files[i].ChangeType != w.changeType {
+			t.Errorf("file %d = %s (%v), want %s (%v)", i, files[i].Path, files[i].ChangeType, w.path, w.changeType)
+		}
+	}
+}
+
+func TestNoIndexFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{"a.yaml": "one\n", "b.yaml": "two\n", "out/a.yaml": "three\n"})
+
+	diff, err := newNoIndexDiff(filepath.Join(dir, "a.yaml"), filepath.Join(dir, "b.yaml"))
+	if err != nil {
+		t.Fatalf("newNoIndexDiff: %v", err)
+	}
+	files, err := diff.fileDiffs(DiffOptions{Context: DefaultDiffContext})
+	if err != nil || len(files) != 1 || files[0].ChangeType != Modified || files[0].LinesAdded != 1 {
+		t.Fatalf("two files = %+v, %v; want one modified file", files, err)
+	}
+
+	// A file compared with a directory uses the file of the same name in it
+	diff, err = newNoIndexDiff(filepath.Join(dir, "a.yaml"), filepath.Join(dir, "out"))
+	if err != nil || diff.newPath != filepath.Join(dir, "out", "a.yaml") {
+		t.Fatalf("file and directory = %+v, %v", diff, err)
+	}
+
+	if _, err := newNoIndexDiff(filepath.Join(dir, "missing"), dir); err == nil {
+		t.Error("expected an error for a missing path")
+	}
+}
@@ -0,0 +1,411 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+)
+
+var (
+	hunkHeaderPattern = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@(?: (.*))?`)
+	ansiEscapePattern = regexp.MustCompile("\x1b\\[[0-9;]*m")
+)
+
+// ParsePatch parses unified diff text into file diffs: git diff and git
+// format-patch output (mail headers and commit messages are skipped) as well
+// as plain diff -u output. Color escapes, as written by git for a pager, are
+// ignored. Combined diffs of merges (diff --cc) are skipped.
+func ParsePatch(r io.Reader) ([]FileDiff, error) {
+	parser := patchParser{}
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadString('\n')
+		if line != "" {
+			parser.lineNum++
+			line = ansiEscapePattern.ReplaceAllString(strings.TrimSuffix(line, "\n"), "")
+			if parseErr := parser.parseLine(line); parseErr != nil {
+				return nil, fmt.Errorf("line %d: %w", parser.lineNum, parseErr)
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := parser.finish(); err != nil {
+		return nil, err
+	}
+	return parser.files, nil
+}
+
+type patchParser struct {
+	files    []FileDiff
+	file     *FileDiff // file whose headers or hunks are being read, nil outside a file
+	lineNum  int
+	skipping bool // inside a combined diff, skipped until the next file
+	// Name on the --- line just read, waiting for its +++ line
+	oldName    string
+	hasOldName bool
+	// Prefixes on the paths of the current file, from its diff --git line;
+	// empty when it has none
+	prefixes [2]string
+
+	// Lines still expected in the current hunk on each side
+	oldLeft, newLeft int
+	// Numbers of the next old and new line of the current hunk
+	oldLine, newLine int
+}
+
+func (p *patchParser) inHunk() bool {
+	return p.oldLeft > 0 || p.newLeft > 0
+}
+
+func (p *patchParser) parseLine(line string) error {
+	if p.inHunk() {
+		return p.parseHunkLine(line)
+	}
+
+	line = strings.TrimSuffix(line, "\r")
+	// --- only names a file when +++ follows, so a commit message line
+	// starting with --- is not taken for a header
+	oldName, afterOldName := p.oldName, p.hasOldName
+	p.hasOldName = false
+
+	switch {
+	case strings.HasPrefix(line, "diff --git "):
+		p.startFile()
+		p.file.OldPath, p.file.Path, p.prefixes = splitGitDiffPaths(strings.TrimPrefix(line, "diff --git "))
+	case strings.HasPrefix(line, "diff --cc ") || strings.HasPrefix(line, "diff --combined "):
+		p.endFile()
+		p.skipping = true
+	case p.skipping:
+		return nil
+	case strings.HasPrefix(line, "--- "):
+		p.oldName, p.hasOldName = line[4:], true
+	case strings.HasPrefix(line, "+++ ") && afterOldName:
+		if p.file == nil || len(p.file.Hunks) > 0 {
+			// diff -u output has no diff --git line before each file
+			p.startFile()
+		}
+		p.setPatchFileNames(oldName, line[4:])
+	case strings.HasPrefix(line, "@@ "):
+		return p.startHunk(line)
+	case strings.HasPrefix(line, `\`):
+		p.markMissingNewline()
+	case p.file == nil:
+		// Mail headers and commit message before the first file
+	case strings.HasPrefix(line, "new file mode "):
+		p.file.ChangeType = Added
+		p.file.NewMode, _ = filemode.New(strings.TrimPrefix(line, "new file mode "))
+	case strings.HasPrefix(line, "deleted file mode "):
+		p.file.ChangeType = Deleted
+		p.file.OldMode, _ = filemode.New(strings.TrimPrefix(line, "deleted file mode "))
+	case strings.HasPrefix(line, "old mode "):
+		p.file.OldMode, _ = filemode.New(strings.TrimPrefix(line, "old mode "))
+	case strings.HasPrefix(line, "new mode "):
+		p.file.NewMode, _ = filemode.New(strings.TrimPrefix(line, "new mode "))
+	case strings.HasPrefix(line, "similarity index "):
+		p.file.Similarity, _ = strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(line, "similarity index "), "%"))
+	case strings.HasPrefix(line, "copy from "):
+		p.file.ChangeType = Copied
+		p.file.OldPath = unquotePatchPath(strings.TrimPrefix(line, "copy from "))
+	case strings.HasPrefix(line, "copy to "):
+		p.file.ChangeType = Copied
+		p.file.Path = unquotePatchPath(strings.TrimPrefix(line, "copy to "))
+	case strings.HasPrefix(line, "rename from "):
+		p.file.ChangeType = Renamed
+		p.file.OldPath = unquotePatchPath(strings.TrimPrefix(line, "rename from "))
+	case strings.HasPrefix(line, "rename to "):
+		p.file.ChangeType = Renamed
+		p.file.Path = unquotePatchPath(strings.TrimPrefix(line, "rename to "))
+	}
+	return nil
+}
+
+// parseHunkLine adds a context, removed or added line to the current hunk
+func (p *patchParser) parseHunkLine(line string) error {
+	hunk := &p.file.Hunks[len(p.file.Hunks)-1]
+	prefix, content := byte(' '), ""
+	if line != "" {
+		// An empty line is context whose trailing space was stripped in transit
+		prefix, content = line[0], line[1:]
+	}
+
+	switch {
+	case prefix == ' ' && p.oldLeft > 0 && p.newLeft > 0:
+		hunk.Lines = append(hunk.Lines, DiffLine{Type: LineContext, Content: content, OldLineNum: p.oldLine, NewLineNum: p.newLine})
+		p.oldLine++
+		p.newLine++
+		p.oldLeft--
+		p.newLeft--
+	case prefix == '-' && p.oldLeft > 0:
+		hunk.Lines = append(hunk.Lines, DiffLine{Type: LineRemoved, Content: content, OldLineNum: p.oldLine})
+		p.oldLine++
+		p.oldLeft--
+	case prefix == '+' && p.newLeft > 0:
+		hunk.Lines = append(hunk.Lines, DiffLine{Type: LineAdded, Content: content, NewLineNum: p.newLine})
+		p.newLine++
+		p.newLeft--
+	case prefix == '\\':
+		p.markMissingNewline()
+	default:
+		return fmt.Errorf("unexpected %q in hunk (%d old and %d new lines missing)", line, p.oldLeft, p.newLeft)
+	}
+	return nil
+}
+
+// startHunk parses a hunk header and starts collecting its lines
+func (p *patchParser) startHunk(line string) error {
+	if p.file == nil {
+		return fmt.Errorf("hunk header before file header: %q", line)
+	}
+	match := hunkHeaderPattern.FindStringSubmatch(line)
+	if match == nil {
+		return fmt.Errorf("invalid hunk header %q", line)
+	}
+
+	oldStart, oldCount := parseHunkRange(match[1], match[2])
+	newStart, newCount := parseHunkRange(match[3], match[4])
+	p.file.Hunks = append(p.file.Hunks, Hunk{OldStart: oldStart, OldCount: oldCount, NewStart: newStart, NewCount: newCount, Function: match[5]})
+	p.oldLeft, p.newLeft = oldCount, newCount
+	p.oldLine, p.newLine = oldStart, newStart
+	return nil
+}
+
+// parseHunkRange parses one side of a hunk header. A missing count means one
+// line; an empty range names the line before it, which is converted back to
+// the start formatHunkRange expects.
+func parseHunkRange(start, count string) (int, int) {
+	first, _ := strconv.Atoi(start)
+	lines := 1
+	if count != "" {
+		lines, _ = strconv.Atoi(count)
+	}
+	if lines == 0 {
+		first++
+	}
+	return first, lines
+}
+
+// markMissingNewline records "\ No newline at end of file" for the line before it
+func (p *patchParser) markMissingNewline() {
+	if p.file == nil || len(p.file.Hunks) == 0 {
+		return
+	}
+	lines := p.file.Hunks[len(p.file.Hunks)-1].Lines
+	if len(lines) == 0 {
+		return
+	}
+	last := lines[len(lines)-1]
+	if last.Type != LineAdded {
+		p.file.OldNoNewlineLine = last.OldLineNum
+	}
+	if last.Type != LineRemoved {
+		p.file.NewNoNewlineLine = last.NewLineNum
+	}
+}
+
+func (p *patchParser) startFile() {
+	p.endFile()
+	p.files = append(p.files, FileDiff{ChangeType: Modified})
+	p.file = &p.files[len(p.files)-1]
+	p.prefixes = [2]string{}
+}
+
+// setPatchFileNames sets the paths of the current file from the names on
+// its --- and +++ lines. Prefixes are removed when both names carry a pair
+// of them; a name facing /dev/null loses the prefix of the diff --git line.
+func (p *patchParser) setPatchFileNames(oldName, newName string) {
+	oldPath, hasOld := parsePatchFileName(oldName)
+	newPath, hasNew := parsePatchFileName(newName)
+	switch {
+	case hasOld && hasNew:
+		oldPath, newPath, _ = stripPatchPrefixes(oldPath, newPath)
+	case hasOld:
+		oldPath = strings.TrimPrefix(oldPath, p.prefixes[0])
+	case hasNew:
+		newPath = strings.TrimPrefix(newPath, p.prefixes[1])
+	}
+
+	if hasOld {
+		p.file.OldPath = oldPath
+	} else {
+		p.file.ChangeType = Added
+	}
+	if hasNew {
+		p.file.Path = newPath
+	} else {
+		p.file.ChangeType = Deleted
+	}
+}
+
+// endFile completes the current file: the old path is only kept for renames
+// and copies, and a deleted file is shown under its old path
+func (p *patchParser) endFile() {
+	p.skipping = false
+	if p.file == nil {
+		return
+	}
+	switch {
+	case p.file.ChangeType == Deleted && p.file.OldPath != "":
+		p.file.Path = p.file.OldPath
+	case p.file.Path == "":
+		p.file.Path = p.file.OldPath
+	}
+	if p.file.ChangeType != Renamed && p.file.ChangeType != Copied {
+		p.file.OldPath = ""
+		p.file.Similarity = 0
+	}
+	p.file.LinesAdded, p.file.LinesRemoved = countHunkLineStats(p.file.Hunks)
+	p.file = nil
+}
+
+func (p *patchParser) finish() error {
+	if p.inHunk() {
+		return fmt.Errorf("line %d: patch ends inside a hunk (%d old and %d new lines missing)", p.lineNum, p.oldLeft, p.newLeft)
+	}
+	p.endFile()
+	return nil
+}
+
+// parsePatchFileName parses the name on a ---/+++ line. It reports false for
+// /dev/null, the missing side of an added or deleted file.
+func parsePatchFileName(name string) (string, bool) {
+	if !strings.HasPrefix(name, `"`) {
+		// diff -u appends a tab and a timestamp
+		name, _, _ = strings.Cut(name, "\t")
+	}
+	name = unquotePatchPath(strings.TrimRight(name, " "))
+	if name == "/dev/null" {
+		return "", false
+	}
+	return name, true
+}
+
+// splitGitDiffPaths splits the two paths of a diff --git line and removes
+// their prefixes, which it returns. Unquoted paths may contain spaces, so the
+// line is split where both halves name the same file; ---/+++ and rename
+// lines correct the guess when they differ.
+func splitGitDiffPaths(paths string) (string, string, [2]string) {
+	if strings.HasPrefix(paths, `"`) {
+		if end := closingQuote(paths); end > 0 {
+			return stripPatchPrefixes(unquotePatchPath(paths[:end+1]), unquotePatchPath(strings.TrimSpace(paths[end+1:])))
+		}
+	}
+
+	if middle := len(paths) / 2; middle > 0 && paths[middle] == ' ' {
+		if oldPath, newPath, prefixes := stripPatchPrefixes(paths[:middle], paths[middle+1:]); oldPath == newPath {
+			return oldPath, newPath, prefixes
+		}
+	}
+	oldPath, newPath, _ := strings.Cut(paths, " ")
+	return stripPatchPrefixes(oldPath, unquotePatchPath(newPath))
+}
+
+func closingQuote(s string) int {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
+
+// unquotePatchPath decodes a path git quoted because of special characters
+func unquotePatchPath(path string) string {
+	if !strings.HasPrefix(path, `"`) {
+		return path
+	}
+	if unquoted, err := strconv.Unquote(path); err == nil {
+		return unquoted
+	}
+	return path
+}
+
+// patchPrefixPairs are the prefixes git puts in front of old and new paths:
+// a/ and b/, or with diff.mnemonicPrefix the pairs of i/ (index), w/ (work
+// tree), c/ (commit), o/ (object), and 1/ and 2/ for --no-index
+var patchPrefixPairs = [][2]string{{"a/", "b/"}, {"i/", "w/"}, {"c/", "w/"}, {"c/", "i/"}, {"o/", "w/"}, {"1/", "2/"}}
+
+// stripPatchPrefixes removes the prefixes from an old and a new path when
+// they carry one of patchPrefixPairs, and returns the pair. Paths of a diff
+// made with --no-prefix are left alone, even when they start with a letter
+// and a slash.
+func stripPatchPrefixes(oldPath, newPath string) (string, string, [2]string) {
+	for _, pair := range patchPrefixPairs {
+		if len(oldPath) > len(pair[0]) && len(newPath) > len(pair[1]) &&
+			strings.HasPrefix(oldPath, pair[0]) && strings.HasPrefix(newPath, pair[1]) {
+			return oldPath[len(pair[0]):], newPath[len(pair[1]):], pair
+		}
+	}
+	return oldPath, newPath, [2]string{}
+}
+
+// readPatchInput reads a patch file, or stdin when name is "-"
+func readPatchInput(name string) ([]byte, error) {
+	if name == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(name)
+}
+
+// loadPatchDiffs reads and parses a patch, keeping the files pathspec selects
+func loadPatchDiffs(name string, pathspec Pathspec) ([]FileDiff, error) {
+	content, err := readPatchInput(name)
+	if err != nil {
+		return nil, fmt.Errorf("read patch: %w", err)
+	}
+	files, err := parsePatchInput(name, content)
+	if err != nil {
+		return nil, err
+	}
+	return filterFileDiffs(files, pathspec), nil
+}
+
+func parsePatchInput(name string, content []byte) ([]FileDiff, error) {
+	files, err := ParsePatch(bytes.NewReader(content))
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", patchInputName(name), err)
+	}
+	return files, nil
+}
+
+// filterFileDiffs returns the files whose path pathspec selects
+func filterFileDiffs(files []FileDiff, pathspec Pathspec) []FileDiff {
+	if pathspec.IsEmpty() {
+		return files
+	}
+	filtered := make([]FileDiff, 0, len(files))
+	for _, file := range files {
+		if pathspec.Matches(file.Path) {
+			filtered = append(filtered, file)
+		}
+	}
+	return filtered
+}
+
+// patchInputName describes a patch argument for messages
+func patchInputName(name string) string {
+	if name == "-" {
+		return "stdin"
+	}
+	return name
+}
+
+// patchDiffInput shows a parsed patch in the TUI
+func patchDiffInput(name string, files []FileDiff) diffInput {
+	return diffInput{label: "Patch", title: "Patch: " + patchInputName(name), files: files}
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const formatPatchMail = `From 1234567890abcdef Mon Sep 17 00:00:00 2001
+From: Dev <dev@example.com>
+Subject: [PATCH] Rework the parser
+
+--- a/ignored.txt in the message is not a file header
+---
+ lib/parse.go | 3 ++-
+ 2 files changed
+
+diff --git a/lib/parse.go b/lib/parse.go
+index 1111111..2222222 100644
+--- a/lib/parse.go
++++ b/lib/parse.go
+@@ -1,3 +1,3 @@ package lib
+ package lib
+-func old() {}
++func new() {}
+ // end
+diff --git a/docs/new file.md b/docs/new file.md
+new file mode 100644
+index 0000000..3333333
+--- /dev/null
++++ b/docs/new file.md
+@@ -0,0 +1,2 @@
++# Title
++no newline
+\ No newline at end of file
+diff --git a/gone.txt b/gone.txt
+deleted file mode 100644
+--- a/gone.txt
++++ /dev/null
+@@ -1 +0,0 @@
+-bye
+diff --git a/old/name.go b/new/name.go
+similarity index 100%
+rename from old/name.go
+rename to new/name.go
+diff --git a/old/name.go b/old/copy.go
+similarity index 90%
+copy from old/name.go
+copy to old/copy.go
+diff --git a/logo.png b/logo.png
+index 4444444..5555555 100644
+Binary files a/logo.png and b/logo.png differ
+--
+2.43.0
+`
+
+func TestParsePatchGitFormatPatch(t *testing.T) {
+	files, err := ParsePatch(strings.NewReader(formatPatchMail))
+	if err != nil {
+		t.Fatalf("ParsePatch: %v", err)
+	}
+
+	want := []struct {
+		path, oldPath string
+		changeType    ChangeType
+		hunks         int
+		added         int
+		removed       int
+	}{
+		{"lib/parse.go", "", Modified, 1, 1, 1},
+		{"docs/new file.md", "", Added, 1, 2, 0},
+		{"gone.txt", "", Deleted, 1, 0, 1},
+		{"new/name.go", "old/name.go", Renamed, 0, 0, 0},
+		{"old/copy.go", "old/name.go", Copied, 0, 0, 0},
+		{"logo.png", "", Modified, 0, 0, 0},
+	}
+	if len(files) != len(want) {
+		t.Fatalf("parsed %d files, want %d: %+v", len(files), len(want), files)
+	}
+	for i, w := range want {
+		f := files[i]
+		if f.Path != w.path || f.OldPath != w.oldPath || f.ChangeType != w.changeType || len(f.Hunks) != w.hunks || f.LinesAdded != w.added || f.LinesRemoved != w.removed {
+			t.Errorf("file %d = %s (old %q, type %v, %d hunks, +%d/-%d), want %+v", i, f.Path, f.OldPath, f.ChangeType, len(f.Hunks), f.LinesAdded, f.LinesRemoved, w)
+		}
+	}
+
+	hunk := files[0].Hunks[0]
+	if hunk.OldStart != 1 || hunk.OldCount != 3 || hunk.NewStart != 1 || hunk.NewCount != 3 || hunk.Function != "package lib" {
+		t.Errorf("hunk range = %+v", hunk)
+	}
+	wantLines := []DiffLine{
+		{Type: LineContext, Content: "package lib", OldLineNum: 1, NewLineNum: 1},
+		{Type: LineRemoved, Content: "func old() {}", OldLineNum: 2},
+		{Type: LineAdded, Content: "func new() {}", NewLineNum: 2},
+		{Type: LineContext, Content: "// end", OldLineNum: 3, NewLineNum: 3},
+	}
+	for i, line := range wantLines {
+		if hunk.Lines[i] != line {
+			t.Errorf("line %d = %+v, want %+v", i, hunk.Lines[i], line)
+		}
+	}
+
+	if files[1].NewNoNewlineLine != 2 || files[1].OldNoNewlineLine != 0 {
+		t.Errorf("missing newline not recorded: old %d, new %d", files[1].OldNoNewlineLine, files[1].NewNoNewlineLine)
+	}
+	if deleted := files[2].Hunks[0]; deleted.NewStart != 1 || deleted.NewCount != 0 || deleted.Function != "" {
+		t.Errorf("empty range should start after the line git names: %+v", deleted)
+	}
+}
+
+func TestParsePatchPlainAndColored(t *testing.T) {
+	plain := "--- a.txt\t2024-01-01 10:00:00\n+++ a.txt\t2024-01-02 10:00:00\n@@ -1,2 +1,2 @@\n-one\n+uno\n two\n" +
+		"--- b.txt\n+++ b.txt\n@@ -3 +3 @@\n-x\n+y\n"
+	colored := "\x1b[1mdiff --git a/a.txt b/a.txt\x1b[m\n\x1b[1m--- a/a.txt\x1b[m\n\x1b[1m+++ b/a.txt\x1b[m\n" +
+		"\x1b[36m@@ -1,2 +1,2 @@\x1b[m\n\x1b[31m-one\x1b[m\n\x1b[32m+uno\x1b[m\n two\n"
+
+	for name, input := range map[string]string{"diff -u": plain, "colored": colored} {
+		files, err := ParsePatch(strings.NewReader(input))
+		if err != nil {
+			t.Fatalf("%s: ParsePatch: %v", name, err)
+		}
+		if len(files) == 0 || files[0].Path != "a.txt" || files[0].LinesAdded != 1 || files[0].Hunks[0].Lines[1].Content != "uno" {
+			t.Errorf("%s: unexpected files %+v", name, files)
+		}
+	}
+
+	files, _ := ParsePatch(strings.NewReader(plain))
+	if len(files) != 2 || files[1].Path != "b.txt" || files[1].Hunks[0].OldStart != 3 || files[1].Hunks[0].OldCount != 1 {
+		t.Errorf("second diff -u file = %+v", files)
+	}
+}
+
+func TestParsePatchPrefixes(t *testing.T) {
+	noPrefix := "diff --git x/main.go x/main.go\n--- x/main.go\n+++ x/main.go\n@@ -1 +1 @@\n-a\n+b\n" +
+		"diff --git x/new.go x/new.go\nnew file mode 100644\n--- /dev/null\n+++ x/new.go\n@@ -0,0 +1 @@\n+c\n"
+	mnemonic := "diff --git i/main.go w/main.go\n--- i/main.go\n+++ w/main.go\n@@ -1 +1 @@\n-a\n+b\n" +
+		"diff --git c/new.go w/new.go\nnew file mode 100644\n--- /dev/null\n+++ w/new.go\n@@ -0,0 +1 @@\n+c\n"
+
+	for input, want := range map[string][]string{
+		noPrefix: {"x/main.go", "x/new.go"},
+		mnemonic: {"main.go", "new.go"},
+	} {
+		files, err := ParsePatch(strings.NewReader(input))
+		if err != nil {
+			t.Fatalf("ParsePatch: %v", err)
+		}
+		if len(files) != 2 || files[0].Path != want[0] || files[1].Path != want[1] || files[1].ChangeType != Added {
+			t.Errorf("%q: files = %+v, want paths %v", input, files, want)
+		}
+	}
+}
+
+func TestParsePatchErrors(t *testing.T) {
+	for name, input := range map[string]string{
+		"truncated hunk": "diff --git a/a b/a\n--- a/a\n+++ b/a\n@@ -1,2 +1,2 @@\n-one\n",
+		"bad line":       "diff --git a/a b/a\n--- a/a\n+++ b/a\n@@ -1 +1 @@\n*one\n",
+		"bad header":     "diff --git a/a b/a\n--- a/a\n+++ b/a\n@@ -x +1 @@\n",
+		"orphan hunk":    "@@ -1 +1 @@\n-a\n+b\n",
+	} {
+		if _, err := ParsePatch(strings.NewReader(input)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+
+	files, err := ParsePatch(strings.NewReader("commit abc\n\n    just a log\n"))
+	if err != nil || len(files) != 0 {
+		t.Errorf("text without a diff = %+v, %v; want no files", files, err)
+	}
+}
+
+func TestParsePatchRoundTrip(t *testing.T) {
+	files, err := ParsePatch(strings.NewReader(formatPatchMail))
+	if err != nil {
+		t.Fatalf("ParsePatch: %v", err)
+	}
+	var out bytes.Buffer
+	if err := writePatch(&out, files); err != nil {
+		t.Fatalf("writePatch: %v", err)
+	}
+
+	reparsed, err := ParsePatch(&out)
+	if err != nil {
+		t.Fatalf("ParsePatch(writePatch output): %v\n%s", err, out.String())
+	}
+	// Files without hunks are not written
+	want := patchFiles(files)
+	if len(reparsed) != len(want) {
+		t.Fatalf("round trip gave %d files, want %d", len(reparsed), len(want))
+	}
+	for i := range want {
+		if reparsed[i].Path != want[i].Path || reparsed[i].ChangeType != want[i].ChangeType ||
+			reparsed[i].NewNoNewlineLine != want[i].NewNoNewlineLine || len(reparsed[i].Hunks) != len(want[i].Hunks) {
+			t.Errorf("file %d round trip = %+v, want %+v", i, reparsed[i], want[i])
+		}
+	}
+}
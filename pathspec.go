@@ -0,0 +1,208 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Pathspec restricts diffs to matching paths, like the pathspec after -- in
+// git diff. Patterns without wildcards match a file or everything under a
+// directory; patterns with *, ? or [...] are globs where * also matches /.
+// Patterns prefixed with :! or :^ (or :(exclude)) exclude matching paths.
+// The zero value matches every path.
+type Pathspec struct {
+	patterns []string // patterns as given, for display
+	include  []pathPattern
+	exclude  []pathPattern
+}
+
+type pathPattern struct {
+	literal string         // repo-relative path, unless glob is set; empty matches everything
+	glob    *regexp.Regexp // set when the pattern has wildcards
+}
+
+// ParsePathspec parses pathspec patterns. prefix is the repo-relative
+// directory patterns are relative to (the working directory on the command
+// line, empty for the repo root); :/ or :(top) makes a pattern root-relative.
+func ParsePathspec(patterns []string, prefix string) (Pathspec, error) {
+	spec := Pathspec{patterns: patterns}
+	for _, raw := range patterns {
+		pattern, exclude, top, err := parsePathspecMagic(raw)
+		if err != nil {
+			return Pathspec{}, err
+		}
+
+		base := prefix
+		if top {
+			base = ""
+		}
+		parsed, err := newPathPattern(base, pattern)
+		if err != nil {
+			return Pathspec{}, fmt.Errorf("pathspec %q: %w", raw, err)
+		}
+
+		if exclude {
+			spec.exclude = append(spec.exclude, parsed)
+		} else {
+			spec.include = append(spec.include, parsed)
+		}
+	}
+	return spec, nil
+}
+
+// literalPathspec matches the given repo-relative paths exactly, without
+// wildcards or magic
+func literalPathspec(paths ...string) Pathspec {
+	spec := Pathspec{patterns: paths}
+	for _, filePath := range paths {
+		spec.include = append(spec.include, pathPattern{literal: filePath})
+	}
+	return spec
+}
+
+// parsePathspecMagic strips the leading magic of a pattern: :!, :^, :/ and
+// the long forms :(exclude) and :(top)
+func parsePathspecMagic(raw string) (pattern string, exclude, top bool, err error) {
+	if !strings.HasPrefix(raw, ":") {
+		return raw, false, false, nil
+	}
+
+	rest := raw[1:]
+	if strings.HasPrefix(rest, "(") {
+		magic, pattern, found := strings.Cut(rest[1:], ")")
+		if !found {
+			return "", false, false, fmt.Errorf("pathspec %q: missing ')'", raw)
+		}
+		for _, word := range strings.Split(magic, ",") {
+			switch strings.TrimSpace(word) {
+			case "exclude":
+				exclude = true
+			case "top":
+				top = true
+			default:
+				return "", false, false, fmt.Errorf("pathspec %q: unsupported magic %q", raw, word)
+			}
+		}
+		return pattern, exclude, top, nil
+	}
+
+	for len(rest) > 0 {
+		switch rest[0] {
+		case '!', '^':
+			exclude = true
+		case '/':
+			top = true
+		case ':':
+			return rest[1:], exclude, top, nil
+		default:
+			return rest, exclude, top, nil
+		}
+		rest = rest[1:]
+	}
+	return rest, exclude, top, nil
+}
+
+func newPathPattern(base, pattern string) (pathPattern, error) {
+	joined := path.Clean(path.Join(base, pattern))
+	if joined == ".." || strings.HasPrefix(joined, "../") {
+		return pathPattern{}, fmt.Errorf("outside repository")
+	}
+	if joined == "." {
+		joined = ""
+	}
+
+	if !strings.ContainsAny(joined, "*?[") {
+		return pathPattern{literal: joined}, nil
+	}
+	glob, err := globToRegexp(joined)
+	if err != nil {
+		return pathPattern{}, err
+	}
+	return pathPattern{glob: glob}, nil
+}
+
+// globToRegexp translates a glob to an anchored regexp. Unlike path.Match, *
+// crosses directory separators, matching git's default pathspec behavior.
+func globToRegexp(glob string) (*regexp.Regexp, error) {
+	var expr strings.Builder
+	expr.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; c {
+		case '*':
+			expr.WriteString(".*")
+		case '?':
+			expr.WriteString(".")
+		case '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated [ in %q", glob)
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			expr.WriteString("[" + class + "]")
+			i += end + 1
+		case '\\':
+			if i+1 < len(glob) {
+				i++
+			}
+			expr.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	expr.WriteString("$")
+	return regexp.Compile(expr.String())
+}
+
+func (p pathPattern) matches(filePath string) bool {
+	if p.glob != nil {
+		return p.glob.MatchString(filePath)
+	}
+	return p.literal == "" || filePath == p.literal || strings.HasPrefix(filePath, p.literal+"/")
+}
+
+// IsEmpty reports whether the pathspec matches every path
+func (p Pathspec) IsEmpty() bool {
+	return len(p.include) == 0 && len(p.exclude) == 0
+}
+
+// Matches reports whether a repo-relative path is selected by the pathspec
+func (p Pathspec) Matches(filePath string) bool {
+	for _, pattern := range p.exclude {
+		if pattern.matches(filePath) {
+			return false
+		}
+	}
+	if len(p.include) == 0 {
+		return true
+	}
+	for _, pattern := range p.include {
+		if pattern.matches(filePath) {
+			return true
+		}
+	}
+	return false
+}
+
+// filter returns the paths selected by the pathspec
+func (p Pathspec) filter(paths []string) []string {
+	if p.IsEmpty() {
+		return paths
+	}
+	filtered := make([]string, 0, len(paths))
+	for _, filePath := range paths {
+		if p.Matches(filePath) {
+			filtered = append(filtered, filePath)
+		}
+	}
+	return filtered
+}
+
+// String renders the patterns as they were given
+func (p Pathspec) String() string {
+	return strings.Join(p.patterns, " ")
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestPathspecMatches(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		prefix   string
+		matches  []string
+		rejects  []string
+	}{
+		{
+			name:    "empty matches everything",
+			matches: []string{"a.go", "services/billing/x.go"},
+		},
+		{
+			name:     "directory prefix",
+			patterns: []string{"services/billing/"},
+			matches:  []string{"services/billing/x.go", "services/billing/deep/y.go"},
+			rejects:  []string{"services/billing-v2/x.go", "services/x.go"},
+		},
+		{
+			name:     "glob star crosses directories",
+			patterns: []string{"*.go"},
+			matches:  []string{"main.go", "services/billing/x.go"},
+			rejects:  []string{"README.md"},
+		},
+		{
+			name:     "glob with class and question mark",
+			patterns: []string{"v[0-9]/?.txt"},
+			matches:  []string{"v1/a.txt"},
+			rejects:  []string{"vx/a.txt", "v1/ab.txt"},
+		},
+		{
+			name:     "exclude only",
+			patterns: []string{":!vendor"},
+			matches:  []string{"main.go", "vendorx/a.go"},
+			rejects:  []string{"vendor/a.go"},
+		},
+		{
+			name:     "include and exclude forms",
+			patterns: []string{"services", ":^services/legacy", ":(exclude)*_test.go"},
+			matches:  []string{"services/billing/x.go"},
+			rejects:  []string{"services/legacy/x.go", "services/billing/x_test.go", "main.go"},
+		},
+		{
+			name:     "relative to prefix",
+			patterns: []string{"billing", "../shared"},
+			prefix:   "services",
+			matches:  []string{"services/billing/x.go", "shared/y.go"},
+			rejects:  []string{"billing/x.go"},
+		},
+		{
+			name:     "top magic ignores prefix",
+			patterns: []string{":/docs", ".", ":(top,exclude)services/legacy"},
+			prefix:   "services",
+			matches:  []string{"docs/a.md", "services/x.go"},
+			rejects:  []string{"main.go", "services/legacy/a.go"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec, err := ParsePathspec(tt.patterns, tt.prefix)
+			if err != nil {
+				t.Fatalf("ParsePathspec(%q) error: %v", tt.patterns, err)
+			}
+			for _, path := range tt.matches {
+				if !spec.Matches(path) {
+					t.Errorf("%q should match %s", tt.patterns, path)
+				}
+			}
+			for _, path := range tt.rejects {
+				if spec.Matches(path) {
+					t.Errorf("%q should not match %s", tt.patterns, path)
+				}
+			}
+		})
+	}
+}
+
+func TestParsePathspecErrors(t *testing.T) {
+	for _, pattern := range []string{"../outside", ":(icase)a", ":(exclude", "a[b"} {
+		if _, err := ParsePathspec([]string{pattern}, ""); err == nil {
+			t.Errorf("ParsePathspec(%q) should fail", pattern)
+		}
+	}
+}
+
+func TestGetDiffsRestrictedByPathspec(t *testing.T) {
+	gitService, root := setupTempGitService(t, map[string]string{
+		"main.go":               "package main\n",
+		"services/billing/a.go": "package billing\n",
+		"services/legacy/b.go":  "package legacy\n",
+	})
+	for _, path := range []string{"main.go", "services/billing/a.go", "services/legacy/b.go"} {
+		writeWorktreeFile(t, root, path, "// changed\n")
+	}
+
+	pathspec, err := ParsePathspec([]string{"services", ":!services/legacy"}, "")
+	if err != nil {
+		t.Fatalf("ParsePathspec: %v", err)
+	}
+	logger := newDefaultLogger(ERROR)
+
+	files, err := gitService.GetChangedFiles(Unstaged, pathspec)
+	if err != nil {
+		t.Fatalf("GetChangedFiles: %v", err)
+	}
+	diffs, err := gitService.GetDiffWithContext(Unstaged, DiffOnly, DiffOptions{Context: DefaultDiffContext}, pathspec, logger)
+	if err != nil {
+		t.Fatalf("GetDiffWithContext: %v", err)
+	}
+	branchDiffs, err := gitService.GetUnifiedBranchCompareDiff("", DiffOnly, DiffOptions{Context: DefaultDiffContext}, pathspec, logger)
+	if err != nil {
+		t.Fatalf("GetUnifiedBranchCompareDiff: %v", err)
+	}
+
+	for name, got := range map[string][]FileDiff{"changed files": files, "diff": diffs, "branch compare": branchDiffs} {
+		if len(got) != 1 || got[0].Path != "services/billing/a.go" {
+			t.Errorf("%s = %v, want only services/billing/a.go", name, got)
+		}
+	}
+}
+
+func TestRepoRelativePrefix(t *testing.T) {
+	root := t.TempDir()
+	tests := map[string]string{root: "", root + "/services/billing": "services/billing"}
+	for dir, want := range tests {
+		got, err := repoRelativePrefix(root, dir)
+		if err != nil || got != want {
+			t.Errorf("repoRelativePrefix(%q) = %q, %v; want %q", dir, got, err, want)
+		}
+	}
+	if _, err := repoRelativePrefix(root, t.TempDir()); err == nil {
+		t.Error("a directory outside the repository should fail")
+	}
+}
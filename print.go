@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+// colorMode controls ANSI colors in --print output
+type colorMode int
+
+const (
+	colorAuto colorMode = iota // colors only when stdout is a terminal
+	colorAlways
+	colorNever
+)
+
+func parseColorMode(value string) (colorMode, error) {
+	switch value {
+	case "auto":
+		return colorAuto, nil
+	case "always":
+		return colorAlways, nil
+	case "never":
+		return colorNever, nil
+	default:
+		return colorAuto, fmt.Errorf("invalid color mode %q: want auto, always or never", value)
+	}
+}
+
+// useColor reports whether output to a terminal (or not) should be colored
+func (mode colorMode) useColor(terminal bool) bool {
+	switch mode {
+	case colorAlways:
+		return true
+	case colorNever:
+		return false
+	default:
+		return terminal
+	}
+}
+
+// isTerminal reports whether f is a character device such as a terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// configureColor sets the color profile used by every lipgloss style
+func configureColor(mode colorMode, terminal bool) {
+	if !mode.useColor(terminal) {
+		lipgloss.SetColorProfile(termenv.Ascii)
+		return
+	}
+	if !terminal {
+		// Piped output (less -R, CI logs) is not probed for color support
+		lipgloss.SetColorProfile(termenv.ANSI256)
+	}
+}
+
+// loadDiffs runs the diff pipeline of the mode selected on the command line
+func loadDiffs(gitService *GitService, opts cliOptions, pathspec Pathspec, logger *Logger) ([]FileDiff, error) {
+	if opts.patchFile != "" {
+		return loadPatchDiffs(opts.patchFile, pathspec)
+	}
+	if opts.external != nil {
+		files, err := opts.external.fileDiffs(opts.diffOptions())
+		return filterFileDiffs(files, pathspec), err
+	}
+	if opts.noIndex != nil {
+		files, err := opts.noIndex.fileDiffs(opts.diffOptions())
+		return filterFileDiffs(files, pathspec), err
+	}
+	if opts.commit != "" {
+		return gitService.GetCommitDiff(opts.commit, DiffOnly, opts.diffOptions(), pathspec, logger)
+	}
+
+	switch opts.diffMode() {
+	case RefCompare:
+		return gitService.GetRefCompareDiff(*opts.revisions, DiffOnly, opts.diffOptions(), pathspec, logger)
+	case BranchCompare:
+		return gitService.GetUnifiedBranchCompareDiff(opts.baseBranch, DiffOnly, opts.diffOptions(), pathspec, logger)
+	default:
+		return gitService.GetDiffWithContext(opts.diffMode(), DiffOnly, opts.diffOptions(), pathspec, logger)
+	}
+}
+
+// printDiffs writes files to w with the hunk rendering of the diff panel:
+// line numbers, syntax highlighting and word-level emphasis
+func printDiffs(w io.Writer, files []FileDiff) error {
+	renderer := Model{highlighter: NewSyntaxHighlighter(), diffViewMode: DiffOnly}
+
+	for fileIdx, file := range files {
+		if fileIdx > 0 {
+			if _, err := fmt.Fprintln(w); err != nil {
+				return err
+			}
+		}
+
+		lines := []string{printFileHeader(file)}
+		hunks := file.Hunks
+		switch {
+		case file.Structure != nil:
+			lines = append(lines, structuralLines(file.Structure)...)
+			hunks = nil
+		case len(hunks) == 0:
+			lines = append(lines, noHunksLines(file, 0)...)
+		}
+		lines = append(lines, declSummaryLines(file)...)
+		for _, hunk := range hunks {
+			lines = append(lines, diffHunkStyle.Render(formatHunkHeader(hunk)))
+			lines = append(lines, renderer.renderHunkRows(hunk, file.Path, nil)...)
+		}
+
+		for _, line := range lines {
+			if _, err := fmt.Fprintln(w, line); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func printFileHeader(file FileDiff) string {
+	header := GetStatusSymbol(file.ChangeType) + " " + file.displayPath()
+	if file.LinesAdded > 0 || file.LinesRemoved > 0 {
+		header += formatLineStats(file.LinesAdded, file.LinesRemoved)
+	}
+	return diffFileHeaderStyle.Render(header)
+}
+
+// formatHunkHeader formats the range line of a hunk like git, followed by
+// its enclosing function: @@ -1,3 +1,4 @@ func main() {
+func formatHunkHeader(hunk Hunk) string {
+	header := fmt.Sprintf("@@ -%s +%s @@", formatHunkRange(hunk.OldStart, hunk.OldCount), formatHunkRange(hunk.NewStart, hunk.NewCount))
+	if hunk.Function != "" {
+		header += " " + hunk.Function
+	}
+	return header
+}
+
+// formatHunkRange formats one side of a hunk header. An empty range names the
+// line before it, so a created file's old side is 0,0 as git writes it.
+func formatHunkRange(start, count int) string {
+	if count == 0 {
+		start = max(0, start-1)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+// runPrint prints the diff to stdout instead of starting the TUI
+func runPrint(gitService *GitService, opts cliOptions, pathspec Pathspec, logger *Logger) error {
+	// git sets GIT_PAGER_IN_USE when it pipes our output to its pager
+	configureColor(opts.color, isTerminal(os.Stdout) || os.Getenv("GIT_PAGER_IN_USE") == "true")
+
+	files, err := loadDiffs(gitService, opts, pathspec, logger)
+	if err != nil {
+		return fmt.Errorf("load diff: %w", err)
+	}
+	markMovedLines(files, opts.whitespace)
+	if err := printDiffs(os.Stdout, files); err != nil {
+		return fmt.Errorf("write diff: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+func TestColorModeUseColor(t *testing.T) {
+	tests := []struct {
+		value    string
+		terminal bool
+		want     bool
+	}{
+		{"auto", true, true},
+		{"auto", false, false},
+		{"always", false, true},
+		{"never", true, false},
+	}
+	for _, tt := range tests {
+		mode, err := parseColorMode(tt.value)
+		if err != nil {
+			t.Fatalf("parseColorMode(%q): %v", tt.value, err)
+		}
+		if got := mode.useColor(tt.terminal); got != tt.want {
+			t.Errorf("%s.useColor(%v) = %v, want %v", tt.value, tt.terminal, got, tt.want)
+		}
+	}
+	if _, err := parseColorMode("sometimes"); err == nil {
+		t.Error("parseColorMode should reject unknown modes")
+	}
+}
+
+func TestPrintDiffs(t *testing.T) {
+	profile := lipgloss.ColorProfile()
+	t.Cleanup(func() { lipgloss.SetColorProfile(profile) })
+
+	gitService, root := setupTempGitService(t, map[string]string{"main.go": "package main\n\nfunc a() {}\n"})
+	writeWorktreeFile(t, root, "main.go", "package main\n\nfunc b() {}\n")
+	files, err := loadDiffs(gitService, cliOptions{}, Pathspec{}, newDefaultLogger(ERROR))
+	if err != nil {
+		t.Fatalf("loadDiffs: %v", err)
+	}
+
+	configureColor(colorNever, true)
+	var plain bytes.Buffer
+	if err := printDiffs(&plain, files); err != nil {
+		t.Fatalf("printDiffs: %v", err)
+	}
+	output := plain.String()
+	if strings.Contains(output, "\x1b[") {
+		t.Errorf("colorless output should not contain ANSI escapes:\n%q", output)
+	}
+	for _, want := range []string{"M main.go +1/-1", "@@ -1,3 +1,3 @@", "   3      - func a() {}", "        3 + func b() {}"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output missing %q:\n%s", want, output)
+		}
+	}
+
+	configureColor(colorAlways, false)
+	var colored bytes.Buffer
+	if err := printDiffs(&colored, files); err != nil {
+		t.Fatalf("printDiffs: %v", err)
+	}
+	if !strings.Contains(colored.String(), "\x1b[") {
+		t.Error("--color=always output should contain ANSI escapes")
+	}
+	if stripAnsi(colored.String()) != output {
+		t.Errorf("colored output should match plain output once escapes are removed:\n%s", stripAnsi(colored.String()))
+	}
+}
+
+func TestCLIOptionsDiffMode(t *testing.T) {
+	tests := []struct {
+		args []string
+		want DiffMode
+	}{
+		{nil, Unstaged},
+		{[]string{"--print", "--staged"}, Staged},
+		{[]string{"--base", "trunk"}, BranchCompare},
+		{[]string{"--no-tui", "v1..v2", "--color=never"}, RefCompare},
+	}
+	for _, tt := range tests {
+		opts, err := parseCLIArgs(tt.args)
+		if err != nil {
+			t.Fatalf("parseCLIArgs(%q): %v", tt.args, err)
+		}
+		if got := opts.diffMode(); got != tt.want {
+			t.Errorf("parseCLIArgs(%q).diffMode() = %v, want %v", tt.args, got, tt.want)
+		}
+	}
+
+	for _, args := range [][]string{{"--staged", "v1"}, {"--color"}, {"--color=rainbow"}} {
+		if _, err := parseCLIArgs(args); err == nil {
+			t.Errorf("parseCLIArgs(%q) should fail", args)
+		}
+	}
+}
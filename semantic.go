@@ -0,0 +1,415 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"path"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// DeclChange is a top-level declaration of a Go file that differs between
+// the old and new version. Formatting and comments are not changes.
+type DeclChange struct {
+	Kind     ChangeKind
+	Name     string   // function signature, "type Config struct" or "var name"
+	Details  []string // what changed in a modified declaration
+	OldStart int      // lines of the declaration in the old file (0 if added)
+	OldEnd   int
+	NewStart int // lines of the declaration in the new file (0 if removed)
+	NewEnd   int
+}
+
+// goDecl is a top-level declaration of a parsed Go file
+type goDecl struct {
+	key        string // identifies the declaration in both versions: "func Model.View"
+	name       string
+	tokens     string // the declaration's tokens without comments or layout
+	signature  string // tokens of a function's signature
+	body       string // tokens of a function's body
+	members    []goMember
+	memberKind string // "field" of a struct or "method" of an interface
+	start, end int
+}
+
+// goMember is a struct field or interface method
+type goMember struct {
+	name   string
+	text   string // shown as in the source, with its type
+	tokens string
+}
+
+// goDeclChanges compares the top-level declarations of two versions of a Go
+// file. It returns nil for other files, when the semantic summary is off, and
+// when either version does not parse.
+func goDeclChanges(filePath string, oldContent, newContent []byte, opts DiffOptions) []DeclChange {
+	if !opts.Semantic || path.Ext(filePath) != ".go" {
+		return nil
+	}
+	oldDecls, err := parseGoDecls(filePath, oldContent)
+	if err != nil {
+		return nil
+	}
+	newDecls, err := parseGoDecls(filePath, newContent)
+	if err != nil {
+		return nil
+	}
+	return diffGoDecls(oldDecls, newDecls)
+}
+
+// parseGoDecls lists the declarations of a Go file; a missing or empty file
+// has none
+func parseGoDecls(filename string, src []byte) ([]goDecl, error) {
+	if len(src) == 0 {
+		return nil, nil
+	}
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, src, parser.SkipObjectResolution)
+	if err != nil {
+		return nil, err
+	}
+	tokFile := fset.File(file.Pos())
+	source := func(from, to token.Pos) string {
+		return string(src[tokFile.Offset(from):tokFile.Offset(to)])
+	}
+	lines := func(node ast.Node) (int, int) {
+		return tokFile.Line(node.Pos()), tokFile.Line(node.End())
+	}
+
+	var decls []goDecl
+	seen := make(map[string]int)
+	add := func(decl goDecl) {
+		// init functions, and blank identifiers, can be declared many times
+		seen[decl.key]++
+		if n := seen[decl.key]; n > 1 {
+			decl.key = fmt.Sprintf("%s#%d", decl.key, n)
+		}
+		decls = append(decls, decl)
+	}
+
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			signatureEnd := decl.End()
+			if decl.Body != nil {
+				signatureEnd = decl.Body.Lbrace
+			}
+			signature := source(decl.Pos(), signatureEnd)
+			d := goDecl{
+				key:       "func " + funcDeclKey(decl),
+				name:      collapseSpace(signature),
+				tokens:    goTokens(source(decl.Pos(), decl.End())),
+				signature: goTokens(signature),
+			}
+			if decl.Body != nil {
+				d.body = goTokens(source(decl.Body.Pos(), decl.Body.End()))
+			}
+			d.start, d.end = lines(decl)
+			add(d)
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				switch spec := spec.(type) {
+				case *ast.TypeSpec:
+					d := goDecl{
+						key:    "type " + spec.Name.Name,
+						name:   "type " + collapseSpace(source(spec.Pos(), spec.End())),
+						tokens: goTokens(source(spec.Pos(), spec.End())),
+					}
+					switch typ := spec.Type.(type) {
+					case *ast.StructType:
+						d.name = "type " + collapseSpace(source(spec.Pos(), typ.Pos())) + " struct"
+						d.memberKind = "field"
+						d.members = goMembers(typ.Fields, source, " ")
+					case *ast.InterfaceType:
+						d.name = "type " + collapseSpace(source(spec.Pos(), typ.Pos())) + " interface"
+						d.memberKind = "method"
+						d.members = goMembers(typ.Methods, source, "")
+					}
+					d.start, d.end = lines(spec)
+					add(d)
+				case *ast.ValueSpec:
+					start, end := lines(spec)
+					for _, name := range spec.Names {
+						add(goDecl{
+							key:    decl.Tok.String() + " " + name.Name,
+							name:   decl.Tok.String() + " " + name.Name,
+							tokens: goTokens(source(spec.Pos(), spec.End())),
+							start:  start,
+							end:    end,
+						})
+					}
+				}
+			}
+		}
+	}
+	return decls, nil
+}
+
+// funcDeclKey names a function, or a method by its receiver's base type
+func funcDeclKey(decl *ast.FuncDecl) string {
+	if decl.Recv == nil || len(decl.Recv.List) == 0 {
+		return decl.Name.Name
+	}
+	typ := decl.Recv.List[0].Type
+	if star, ok := typ.(*ast.StarExpr); ok {
+		typ = star.X
+	}
+	switch generic := typ.(type) {
+	case *ast.IndexExpr:
+		typ = generic.X
+	case *ast.IndexListExpr:
+		typ = generic.X
+	}
+	if ident, ok := typ.(*ast.Ident); ok {
+		return ident.Name + "." + decl.Name.Name
+	}
+	return decl.Name.Name
+}
+
+// goMembers lists the fields of a struct or methods of an interface; an
+// embedded type is named by its type
+func goMembers(fields *ast.FieldList, source func(from, to token.Pos) string, separator string) []goMember {
+	var members []goMember
+	for _, field := range fields.List {
+		typ := source(field.Type.Pos(), field.End())
+		if len(field.Names) == 0 {
+			members = append(members, goMember{name: collapseSpace(source(field.Type.Pos(), field.Type.End())), text: collapseSpace(typ), tokens: goTokens(typ)})
+			continue
+		}
+		for _, name := range field.Names {
+			members = append(members, goMember{name: name.Name, text: name.Name + separator + collapseSpace(typ), tokens: goTokens(typ)})
+		}
+	}
+	return members
+}
+
+// goTokens returns the tokens of Go source separated by spaces, so sources
+// that differ only in layout and comments are equal
+func goTokens(src string) string {
+	var s scanner.Scanner
+	fset := token.NewFileSet()
+	s.Init(fset.AddFile("", -1, len(src)), []byte(src), nil, 0)
+	var tokens []string
+	for {
+		_, tok, lit := s.Scan()
+		if tok == token.RBRACE || tok == token.RPAREN || tok == token.EOF {
+			// A semicolon before a closing bracket is only there when the
+			// bracket is on its own line
+			if n := len(tokens); n > 0 && tokens[n-1] == ";" {
+				tokens = tokens[:n-1]
+			}
+		}
+		switch {
+		case tok == token.EOF:
+			return strings.Join(tokens, " ")
+		case tok == token.SEMICOLON:
+			tokens = append(tokens, ";") // written or inserted at a line end
+		case lit != "":
+			tokens = append(tokens, lit)
+		default:
+			tokens = append(tokens, tok.String())
+		}
+	}
+}
+
+// collapseSpace joins the words of s with single spaces
+func collapseSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// diffGoDecls pairs declarations by key: added and modified ones are listed
+// in the order of the new file, followed by the removed ones
+func diffGoDecls(oldDecls, newDecls []goDecl) []DeclChange {
+	oldByKey := make(map[string]goDecl, len(oldDecls))
+	for _, decl := range oldDecls {
+		oldByKey[decl.key] = decl
+	}
+	newKeys := make(map[string]bool, len(newDecls))
+
+	var changes []DeclChange
+	for _, decl := range newDecls {
+		newKeys[decl.key] = true
+		old, ok := oldByKey[decl.key]
+		switch {
+		case !ok:
+			changes = append(changes, DeclChange{Kind: KindAdded, Name: decl.name, NewStart: decl.start, NewEnd: decl.end})
+		case old.tokens != decl.tokens:
+			changes = append(changes, DeclChange{
+				Kind:     KindModified,
+				Name:     decl.name,
+				Details:  declChangeDetails(old, decl),
+				OldStart: old.start,
+				OldEnd:   old.end,
+				NewStart: decl.start,
+				NewEnd:   decl.end,
+			})
+		}
+	}
+	for _, decl := range oldDecls {
+		if !newKeys[decl.key] {
+			changes = append(changes, DeclChange{Kind: KindRemoved, Name: decl.name, OldStart: decl.start, OldEnd: decl.end})
+		}
+	}
+	return changes
+}
+
+// declChangeDetails describes what changed in a declaration: its signature
+// and body, the fields or methods of its type, or its definition
+func declChangeDetails(old, decl goDecl) []string {
+	if strings.HasPrefix(decl.key, "func ") {
+		var details []string
+		if old.signature != decl.signature {
+			details = append(details, "signature was "+old.name)
+		}
+		if old.body != decl.body {
+			details = append(details, "body changed")
+		}
+		return details
+	}
+	if decl.memberKind == "" || old.memberKind != decl.memberKind {
+		if old.name != decl.name {
+			return []string{"was " + old.name}
+		}
+		return []string{"definition changed"}
+	}
+
+	details := memberChangeDetails(decl.memberKind, old.members, decl.members)
+	if len(details) == 0 {
+		details = []string{"definition changed"}
+	}
+	return details
+}
+
+// memberChangeDetails lists the fields or methods added or changed, in the
+// new order, followed by the removed ones
+func memberChangeDetails(kind string, oldMembers, newMembers []goMember) []string {
+	oldByName := make(map[string]goMember, len(oldMembers))
+	for _, member := range oldMembers {
+		oldByName[member.name] = member
+	}
+	newNames := make(map[string]bool, len(newMembers))
+
+	var details []string
+	for _, member := range newMembers {
+		newNames[member.name] = true
+		old, ok := oldByName[member.name]
+		switch {
+		case !ok:
+			details = append(details, fmt.Sprintf("+ %s %s", kind, member.text))
+		case old.tokens != member.tokens:
+			details = append(details, fmt.Sprintf("~ %s %s (was %s)", kind, member.text, old.text))
+		}
+	}
+	for _, member := range oldMembers {
+		if !newNames[member.name] {
+			details = append(details, fmt.Sprintf("- %s %s", kind, member.text))
+		}
+	}
+	return details
+}
+
+// declSummaryLines renders the declaration summary shown above the hunks of
+// a Go file, ending in a blank line, or nothing when there is no summary
+func declSummaryLines(file FileDiff) []string {
+	if len(file.Decls) == 0 {
+		return nil
+	}
+	counts := make(map[ChangeKind]int)
+	for _, decl := range file.Decls {
+		counts[decl.Kind]++
+	}
+	var parts []string
+	for _, kind := range []ChangeKind{KindAdded, KindModified, KindRemoved} {
+		if counts[kind] > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", counts[kind], kind))
+		}
+	}
+
+	lines := []string{panelInfoStyle.Render("Go declarations: " + strings.Join(parts, ", "))}
+	for _, decl := range file.Decls {
+		lines = append(lines, GetChangeKindStyle(decl.Kind).Render(decl.Kind.Symbol())+" "+decl.Name)
+		for _, detail := range decl.Details {
+			lines = append(lines, panelInfoStyle.Render("    "+detail))
+		}
+	}
+	return append(lines, "")
+}
+
+// declLineRow returns the diff panel row of the first changed line of decl,
+// in the hunks of file
+func (m Model) declLineRow(layout diffLayout, file *FileDiff, decl DeclChange) (int, bool) {
+	for i, loc := range layout.hunks {
+		if loc.file != file {
+			continue
+		}
+		hunk := loc.hunk()
+		for idx, line := range hunk.Lines {
+			removed := line.Type == LineRemoved && line.OldLineNum >= decl.OldStart && line.OldLineNum <= decl.OldEnd
+			added := line.Type == LineAdded && line.NewLineNum >= decl.NewStart && line.NewLineNum <= decl.NewEnd
+			if removed || added {
+				return layout.hunkStarts[i] + 1 + m.hunkRowOfLine(hunk, idx), true
+			}
+		}
+	}
+	return 0, false
+}
+
+// jumpToDecl scrolls to the hunks of the next changed declaration below the
+// top of the diff panel, back to the first after the last
+func (m *Model) jumpToDecl() {
+	if m.panel != DiffPanel || !m.diffViewMode.showsHunks() {
+		return
+	}
+
+	type declTarget struct {
+		row  int
+		decl DeclChange
+	}
+	layout := m.computeDiffLayout(m.getSelectedDiffFiles())
+	var targets []declTarget
+	for _, file := range m.getSelectedDiffFiles() {
+		for _, decl := range file.Decls {
+			if row, ok := m.declLineRow(layout, file, decl); ok {
+				targets = append(targets, declTarget{row, decl})
+			}
+		}
+	}
+	if len(targets) == 0 {
+		if m.semantic {
+			m.notice = "No changed declarations"
+		} else {
+			m.notice = "Press S to summarize the changed Go declarations"
+		}
+		return
+	}
+
+	sort.SliceStable(targets, func(i, j int) bool { return targets[i].row < targets[j].row })
+	target := targets[0]
+	for _, candidate := range targets {
+		if candidate.row > m.diffScroll {
+			target = candidate
+			break
+		}
+	}
+	m.diffScroll = target.row
+	m.notice = fmt.Sprintf("%s: %s", target.decl.Name, target.decl.Kind)
+}
+
+// toggleSemantic shows or hides the declaration summary of Go files
+func (m *Model) toggleSemantic() tea.Cmd {
+	if m.hasFixedHunks() {
+		return nil
+	}
+	m.semantic = !m.semantic
+	if m.semantic {
+		m.notice = "Go declaration summary shown"
+	} else {
+		m.notice = "Go declaration summary hidden"
+	}
+	return m.reloadCurrentDiffs()
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const semanticOldSource = `package main
+
+import "fmt"
+
+type Config struct {
+	Name  string
+	Size  int
+	Debug bool
+}
+
+type Store interface {
+	Get(key string) string
+}
+
+func init() { fmt.Println("first") }
+
+func init() { fmt.Println("second") }
+
+func legacy() {}
+
+func run(name string) error {
+	return nil
+}
+
+func main() {
+	fmt.Println(1)
+}
+
+func (c Config) String() string {
+	// reformatted in the new version
+	return c.Name
+}
+`
+
+const semanticNewSource = `package main
+
+import "fmt"
+
+type Config struct {
+	Name string
+	Size int64
+	Mode int ` + "`json:\"mode\"`" + `
+}
+
+type Store interface {
+	Get(key string) string
+	Put(key, value string)
+}
+
+func init() { fmt.Println("first") }
+
+func init() { fmt.Println("2nd") }
+
+func run(name string, force bool) error {
+	return nil
+}
+
+// helper is new
+func helper() {}
+
+func main() {
+	fmt.Println(2)
+}
+
+func (c Config) String() string { return c.Name }
+`
+
+func TestGoDeclChanges(t *testing.T) {
+	opts := DiffOptions{Semantic: true}
+	changes := goDeclChanges("main.go", []byte(semanticOldSource), []byte(semanticNewSource), opts)
+
+	want := []struct {
+		kind    ChangeKind
+		name    string
+		details []string
+	}{
+		{KindModified, "type Config struct", []string{"~ field Size int64 (was Size int)", "+ field Mode int `json:\"mode\"`", "- field Debug bool"}},
+		{KindModified, "type Store interface", []string{"+ method Put(key, value string)"}},
+		{KindModified, "func init()", []string{"body changed"}},
+		{KindModified, "func run(name string, force bool) error", []string{"signature was func run(name string) error"}},
+		{KindAdded, "func helper()", nil},
+		{KindModified, "func main()", []string{"body changed"}},
+		{KindRemoved, "func legacy()", nil},
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("got %d changes, want %d: %+v", len(changes), len(want), changes)
+	}
+	for i, w := range want {
+		got := changes[i]
+		if got.Kind != w.kind || got.Name != w.name || strings.Join(got.Details, "\n") != strings.Join(w.details, "\n") {
+			t.Errorf("change %d = %v %q %q, want %v %q %q", i, got.Kind, got.Name, got.Details, w.kind, w.name, w.details)
+		}
+	}
+	if config := changes[0]; config.OldStart != 5 || config.OldEnd != 9 || config.NewStart != 5 || config.NewEnd != 9 {
+		t.Errorf("Config lines = %+v", config)
+	}
+	if removed := changes[len(changes)-1]; removed.NewStart != 0 || removed.OldStart != 19 {
+		t.Errorf("legacy lines = %+v", removed)
+	}
+
+	// A new file declares everything; other files, unparsable ones and a
+	// disabled summary have no declarations
+	if added := goDeclChanges("main.go", nil, []byte(semanticNewSource), opts); len(added) != 8 || added[0].Kind != KindAdded {
+		t.Errorf("new file changes = %+v", added)
+	}
+	for name, changes := range map[string][]DeclChange{
+		"not Go":        goDeclChanges("main.txt", []byte(semanticOldSource), []byte(semanticNewSource), opts),
+		"parse error":   goDeclChanges("main.go", []byte(semanticOldSource), []byte("package main\nfunc {"), opts),
+		"summary off":   goDeclChanges("main.go", []byte(semanticOldSource), []byte(semanticNewSource), DiffOptions{}),
+		"only comments": goDeclChanges("main.go", []byte(semanticOldSource), []byte(strings.ReplaceAll(semanticOldSource, "// reformatted", "// changed")), opts),
+	} {
+		if changes != nil {
+			t.Errorf("%s: changes = %+v, want none", name, changes)
+		}
+	}
+}
+
+func TestModelJumpsToDecl(t *testing.T) {
+	model := setupModel(t)
+	model.width = 120
+	model.height = 30
+	file, err := newFileDiffFromContents("main.go", []byte(semanticOldSource), []byte(semanticNewSource), true, true, false, DiffOptions{Context: 1, Semantic: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	newModel, _ := model.Update(allDiffsLoadedMsg{files: []FileDiff{*file}})
+	model = newModel.(Model)
+	model.semantic = true
+
+	lines := model.buildDiffPanelLines()
+	if got := model.computeDiffLayout(model.getSelectedDiffFiles()).totalLines; got != len(lines) {
+		t.Errorf("layout has %d lines, the panel renders %d", got, len(lines))
+	}
+	view := stripAnsi(strings.Join(lines, "\n"))
+	if !strings.Contains(view, "Go declarations: 1 added, 5 modified, 1 removed\n~ type Config struct\n    ~ field Size int64 (was Size int)") {
+		t.Fatalf("panel should start with the declaration summary:\n%s", view)
+	}
+
+	// Each D lands on the first changed line of the next declaration
+	model.panel = DiffPanel
+	for _, want := range []string{"Name  string", "Put(key, value string)", `fmt.Println("second")`} {
+		newModel, _ = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'D'}})
+		model = newModel.(Model)
+		if got := stripAnsi(lines[model.diffScroll]); !strings.Contains(got, want) {
+			t.Errorf("D scrolled to %q, want the line with %q", got, want)
+		}
+	}
+	if model.notice != "func init(): modified" {
+		t.Errorf("notice = %q", model.notice)
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+const splitColumnSeparator = " │ "
+
+// splitRow pairs an old-side and a new-side line of a hunk for the side-by-side layout.
+// Indices point into Hunk.Lines; -1 marks a filler cell where that side has no line.
+type splitRow struct {
+	oldIdx int
+	newIdx int
+}
+
+// pairHunkRows aligns a hunk into side-by-side rows. Context lines occupy both
+// columns, while each run of removed and added lines is paired row by row with
+// filler cells padding the shorter side.
+func pairHunkRows(lines []DiffLine) []splitRow {
+	rows := make([]splitRow, 0, len(lines))
+	var removed, added []int
+
+	flushChanges := func() {
+		for i := 0; i < max(len(removed), len(added)); i++ {
+			row := splitRow{oldIdx: -1, newIdx: -1}
+			if i < len(removed) {
+				row.oldIdx = removed[i]
+			}
+			if i < len(added) {
+				row.newIdx = added[i]
+			}
+			rows = append(rows, row)
+		}
+		removed = removed[:0]
+		added = added[:0]
+	}
+
+	for i, line := range lines {
+		switch line.Type {
+		case LineRemoved:
+			removed = append(removed, i)
+		case LineAdded:
+			added = append(added, i)
+		default:
+			flushChanges()
+			rows = append(rows, splitRow{oldIdx: i, newIdx: i})
+		}
+	}
+	flushChanges()
+
+	return rows
+}
+
+// hunkRowCount returns how many display rows a hunk occupies in the current view mode
+func (m Model) hunkRowCount(hunk Hunk) int {
+	if m.diffViewMode == SideBySide {
+		return len(pairHunkRows(hunk.Lines))
+	}
+	return len(hunk.Lines)
+}
+
+// renderSplitHunkRows renders a hunk as two aligned columns: old on the left, new on the right
+func (m Model) renderSplitHunkRows(input hunkRenderInput) []string {
+	contentWidth := diffContentWidth(m.width, m.diffViewMode)
+	separator := diffSubtleStyle.Render(splitColumnSeparator)
+	columnWidth := max(0, (contentWidth-lipgloss.Width(splitColumnSeparator))/2)
+
+	pairs := pairHunkRows(input.hunk.Lines)
+	rows := make([]string, 0, len(pairs))
+	for _, pair := range pairs {
+		left := m.renderSplitCell(input, pair.oldIdx, columnWidth, oldLineNumber)
+		right := m.renderSplitCell(input, pair.newIdx, columnWidth, newLineNumber)
+		rows = append(rows, left+separator+right)
+	}
+	return rows
+}
+
+// renderSplitCell renders one column of a split row, or blank filler when idx is -1.
+// lineNumber selects which side's line number the column shows.
+func (m Model) renderSplitCell(input hunkRenderInput, idx, width int, lineNumber func(DiffLine) int) string {
+	if idx < 0 {
+		return strings.Repeat(" ", width)
+	}
+
+	diffLine := input.hunk.Lines[idx]
+	prefix, prefixStyle, _ := diffLinePrefixAndStyles(diffLine)
+	cell := input.lineNumStyle(idx).Render(formatLineNumber(lineNumber(diffLine))) + " " +
+		prefixStyle.Render(prefix) + " " +
+		m.renderDiffLineContent(diffLine, input.filePath, input.emphasis[idx])
+	return fitToWidth(cell, width)
+}
+
+func oldLineNumber(diffLine DiffLine) int {
+	return diffLine.OldLineNum
+}
+
+func newLineNumber(diffLine DiffLine) int {
+	return diffLine.NewLineNum
+}
+
+// fitToWidth truncates or pads a styled string so it occupies exactly width cells
+func fitToWidth(content string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	truncated := lipgloss.NewStyle().MaxWidth(width).Render(content)
+	return truncated + strings.Repeat(" ", max(0, width-lipgloss.Width(truncated)))
+}
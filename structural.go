@@ -0,0 +1,390 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"gopkg.in/yaml.v3"
+)
+
+// maxStructuralValueLen is the longest value shown in the structural view, in runes
+const maxStructuralValueLen = 60
+
+// StructuralDiff is the difference between two versions of a JSON or YAML
+// file as values rather than lines: formatting and key order do not count
+type StructuralDiff struct {
+	Changes []ValueChange
+}
+
+// ValueChange is a value that was added, removed or modified at a key path.
+// Values are written as JSON; the side where the path does not exist is empty.
+type ValueChange struct {
+	Kind ChangeKind
+	Path string // spec.template.spec.containers[name=web].image
+	Old  string
+	New  string
+}
+
+// isStructuredPath reports whether a file is JSON or YAML by its extension
+func isStructuredPath(filePath string) bool {
+	switch strings.ToLower(path.Ext(filePath)) {
+	case ".json", ".yaml", ".yml":
+		return true
+	}
+	return false
+}
+
+// structuralDiff compares two versions of a JSON or YAML file by value. It
+// returns nil for other files, when the structural view is off, and unless
+// both versions exist and parse.
+func structuralDiff(filePath string, oldContent, newContent []byte, opts DiffOptions) *StructuralDiff {
+	if !opts.Structural || !isStructuredPath(filePath) || len(oldContent) == 0 || len(newContent) == 0 {
+		return nil
+	}
+	oldValue, err := parseStructured(filePath, oldContent)
+	if err != nil {
+		return nil
+	}
+	newValue, err := parseStructured(filePath, newContent)
+	if err != nil {
+		return nil
+	}
+	diff := &StructuralDiff{}
+	diff.compare("", oldValue, newValue)
+	return diff
+}
+
+// parseStructured decodes a JSON or YAML file. A YAML file of several
+// documents is a list of them.
+func parseStructured(filePath string, content []byte) (any, error) {
+	if strings.ToLower(path.Ext(filePath)) == ".json" {
+		decoder := json.NewDecoder(bytes.NewReader(content))
+		decoder.UseNumber()
+		var value any
+		if err := decoder.Decode(&value); err != nil {
+			return nil, fmt.Errorf("parse JSON: %w", err)
+		}
+		if _, err := decoder.Token(); !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("parse JSON: data after the top-level value")
+		}
+		return normalizeValue(value), nil
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	var documents []any
+	for {
+		var document any
+		err := decoder.Decode(&document)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parse YAML: %w", err)
+		}
+		documents = append(documents, normalizeValue(document))
+	}
+	if len(documents) == 1 {
+		return documents[0], nil
+	}
+	return documents, nil
+}
+
+// normalizeValue gives decoded JSON and YAML the same types: string keyed
+// maps, and numbers as int64 when they are integers, uint64 when they are
+// integers too large for int64, or float64 otherwise
+func normalizeValue(value any) any {
+	switch value := value.(type) {
+	case map[string]any:
+		for key, item := range value {
+			value[key] = normalizeValue(item)
+		}
+		return value
+	case map[any]any:
+		normalized := make(map[string]any, len(value))
+		for key, item := range value {
+			normalized[fmt.Sprint(key)] = normalizeValue(item)
+		}
+		return normalized
+	case []any:
+		for i, item := range value {
+			value[i] = normalizeValue(item)
+		}
+		return value
+	case json.Number:
+		if n, err := value.Int64(); err == nil {
+			return n
+		}
+		if n, err := strconv.ParseUint(value.String(), 10, 64); err == nil {
+			return n
+		}
+		if f, err := value.Float64(); err == nil {
+			return f
+		}
+		return value.String()
+	case int:
+		return int64(value)
+	}
+	return value
+}
+
+// compare adds the changes between oldValue and newValue at keyPath. Maps
+// are compared by key, lists of maps with unique names by name, other lists
+// by index.
+func (d *StructuralDiff) compare(keyPath string, oldValue, newValue any) {
+	switch oldTyped := oldValue.(type) {
+	case map[string]any:
+		if newTyped, ok := newValue.(map[string]any); ok {
+			d.compareMaps(keyPath, oldTyped, newTyped)
+			return
+		}
+	case []any:
+		if newTyped, ok := newValue.([]any); ok {
+			d.compareLists(keyPath, oldTyped, newTyped)
+			return
+		}
+	}
+	if !equalScalars(oldValue, newValue) {
+		d.add(KindModified, keyPath, oldValue, newValue)
+	}
+}
+
+func (d *StructuralDiff) compareMaps(keyPath string, oldMap, newMap map[string]any) {
+	keys := make([]string, 0, len(oldMap)+len(newMap))
+	for key := range oldMap {
+		keys = append(keys, key)
+	}
+	for key := range newMap {
+		if _, ok := oldMap[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		oldItem, inOld := oldMap[key]
+		newItem, inNew := newMap[key]
+		itemPath := joinKeyPath(keyPath, key)
+		switch {
+		case !inOld:
+			d.add(KindAdded, itemPath, nil, newItem)
+		case !inNew:
+			d.add(KindRemoved, itemPath, oldItem, nil)
+		default:
+			d.compare(itemPath, oldItem, newItem)
+		}
+	}
+}
+
+// listIdentityKeys are the fields that name the items of a list, such as the
+// containers of a Kubernetes pod
+var listIdentityKeys = []string{"name", "id"}
+
+func (d *StructuralDiff) compareLists(keyPath string, oldList, newList []any) {
+	for _, field := range listIdentityKeys {
+		oldNames, oldOK := listItemNames(oldList, field)
+		newNames, newOK := listItemNames(newList, field)
+		if !oldOK || !newOK {
+			continue
+		}
+		oldByName := make(map[string]any, len(oldList))
+		for i, name := range oldNames {
+			oldByName[name] = oldList[i]
+		}
+		newSet := make(map[string]bool, len(newList))
+		for i, name := range newNames {
+			newSet[name] = true
+			itemPath := fmt.Sprintf("%s[%s=%s]", keyPath, field, name)
+			if oldItem, ok := oldByName[name]; ok {
+				d.compare(itemPath, oldItem, newList[i])
+			} else {
+				d.add(KindAdded, itemPath, nil, newList[i])
+			}
+		}
+		for i, name := range oldNames {
+			if !newSet[name] {
+				d.add(KindRemoved, fmt.Sprintf("%s[%s=%s]", keyPath, field, name), oldList[i], nil)
+			}
+		}
+		return
+	}
+
+	for i := 0; i < max(len(oldList), len(newList)); i++ {
+		itemPath := fmt.Sprintf("%s[%d]", keyPath, i)
+		switch {
+		case i >= len(oldList):
+			d.add(KindAdded, itemPath, nil, newList[i])
+		case i >= len(newList):
+			d.add(KindRemoved, itemPath, oldList[i], nil)
+		default:
+			d.compare(itemPath, oldList[i], newList[i])
+		}
+	}
+}
+
+// listItemNames returns the field of every item of list, when all items are
+// maps with a different string or number in it
+func listItemNames(list []any, field string) ([]string, bool) {
+	if len(list) == 0 {
+		return nil, false
+	}
+	names := make([]string, len(list))
+	seen := make(map[string]bool, len(list))
+	for i, item := range list {
+		fields, ok := item.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		switch name := fields[field].(type) {
+		case string, int64, uint64:
+			names[i] = fmt.Sprint(name)
+		default:
+			return nil, false
+		}
+		if seen[names[i]] {
+			return nil, false
+		}
+		seen[names[i]] = true
+	}
+	return names, true
+}
+
+// equalScalars compares two values that are not both maps or both lists;
+// numbers are equal when their values are, so 1 and 1.0 do not differ.
+// Integers compare exactly, as float64 cannot tell large ones apart.
+func equalScalars(a, b any) bool {
+	switch x := a.(type) {
+	case int64:
+		switch y := b.(type) {
+		case int64:
+			return x == y
+		case uint64:
+			return x >= 0 && uint64(x) == y
+		}
+	case uint64:
+		switch y := b.(type) {
+		case int64:
+			return y >= 0 && uint64(y) == x
+		case uint64:
+			return x == y
+		}
+	}
+	if x, ok := numberValue(a); ok {
+		if y, ok := numberValue(b); ok {
+			return x == y
+		}
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// numberValue returns a number as float64, to compare integers with floats
+func numberValue(value any) (float64, bool) {
+	switch value := value.(type) {
+	case int64:
+		return float64(value), true
+	case uint64:
+		return float64(value), true
+	case float64:
+		return value, true
+	}
+	return 0, false
+}
+
+func (d *StructuralDiff) add(kind ChangeKind, keyPath string, oldValue, newValue any) {
+	change := ValueChange{Kind: kind, Path: keyPath}
+	if kind != KindAdded {
+		change.Old = formatStructuredValue(oldValue)
+	}
+	if kind != KindRemoved {
+		change.New = formatStructuredValue(newValue)
+	}
+	d.Changes = append(d.Changes, change)
+}
+
+// plainKeyPattern matches the keys written without quotes in a key path
+var plainKeyPattern = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$-]*$`)
+
+// joinKeyPath appends a map key to a path: spec.replicas, or
+// metadata.annotations["app.kubernetes.io/name"] for other keys
+func joinKeyPath(keyPath, key string) string {
+	if !plainKeyPattern.MatchString(key) {
+		return keyPath + "[" + strconv.Quote(key) + "]"
+	}
+	if keyPath == "" {
+		return key
+	}
+	return keyPath + "." + key
+}
+
+// formatStructuredValue writes a value as compact JSON, cut to
+// maxStructuralValueLen runes
+func formatStructuredValue(value any) string {
+	var encoded bytes.Buffer
+	encoder := json.NewEncoder(&encoded)
+	encoder.SetEscapeHTML(false)
+	text := fmt.Sprint(value)
+	if err := encoder.Encode(value); err == nil {
+		text = strings.TrimSuffix(encoded.String(), "\n")
+	}
+	if runes := []rune(text); len(runes) > maxStructuralValueLen {
+		return string(runes[:maxStructuralValueLen-1]) + "…"
+	}
+	return text
+}
+
+// String describes the change: spec.replicas: 2 → 3
+func (c ValueChange) String() string {
+	keyPath := c.Path
+	if keyPath == "" {
+		keyPath = "(root)"
+	}
+	switch c.Kind {
+	case KindAdded:
+		return keyPath + ": " + c.New
+	case KindRemoved:
+		return keyPath + ": " + c.Old
+	default:
+		return keyPath + ": " + c.Old + " → " + c.New
+	}
+}
+
+// structuralLines renders the structural view shown instead of the hunks of
+// a JSON or YAML file
+func structuralLines(diff *StructuralDiff) []string {
+	if len(diff.Changes) == 0 {
+		return []string{panelInfoStyle.Render("No structural changes: only formatting or key order differ")}
+	}
+	noun := "changes"
+	if len(diff.Changes) == 1 {
+		noun = "change"
+	}
+	lines := []string{panelInfoStyle.Render(fmt.Sprintf("Structure: %d %s (formatting and key order ignored)", len(diff.Changes), noun))}
+	for _, change := range diff.Changes {
+		lines = append(lines, GetChangeKindStyle(change.Kind).Render(change.Kind.Symbol())+" "+change.String())
+	}
+	return lines
+}
+
+// toggleStructural switches JSON and YAML files between hunks and the
+// structural view
+func (m *Model) toggleStructural() tea.Cmd {
+	if m.hasFixedHunks() {
+		return nil
+	}
+	m.structural = !m.structural
+	if m.structural {
+		m.notice = "JSON/YAML files show changed values"
+	} else {
+		m.notice = "JSON/YAML files show line changes"
+	}
+	return m.reloadCurrentDiffs()
+}
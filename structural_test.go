@@ -0,0 +1,141 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+const structuralOldManifest = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  labels: {app: web}
+  annotations:
+    app.kubernetes.io/part-of: shop
+spec:
+  replicas: 2
+  paused: true
+  template:
+    spec:
+      containers:
+        - name: web
+          image: nginx:1.25
+          args: [--port, "80"]
+        - name: sidecar
+          image: envoy:1
+`
+
+// structuralNewManifest is the old manifest reformatted with its keys and
+// containers reordered, and a few values changed
+const structuralNewManifest = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  annotations:
+    app.kubernetes.io/part-of: store
+  labels:
+    app: web
+    tier: frontend
+  name: web
+spec:
+  template:
+    spec:
+      containers:
+      - name: sidecar
+        image: envoy:1
+      - image: "nginx:1.26"
+        name: web
+        args:
+        - --port
+        - "80"
+        - --verbose
+  replicas: 3
+`
+
+func TestStructuralDiff(t *testing.T) {
+	opts := DiffOptions{Structural: true}
+	diff := structuralDiff("deploy.yaml", []byte(structuralOldManifest), []byte(structuralNewManifest), opts)
+	if diff == nil {
+		t.Fatal("both manifests parse, so they should be compared by value")
+	}
+	want := []string{
+		`~ metadata.annotations["app.kubernetes.io/part-of"]: "shop" → "store"`,
+		`+ metadata.labels.tier: "frontend"`,
+		`- spec.paused: true`,
+		`~ spec.replicas: 2 → 3`,
+		`+ spec.template.spec.containers[name=web].args[2]: "--verbose"`,
+		`~ spec.template.spec.containers[name=web].image: "nginx:1.25" → "nginx:1.26"`,
+	}
+	var got []string
+	for _, change := range diff.Changes {
+		got = append(got, change.Kind.Symbol()+" "+change.String())
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("changes:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	// Numbers compare by value, and JSON only by its values
+	if diff := structuralDiff("c.json", []byte(`{"a": 1, "b": [1, 2], "c": "<x>"}`), []byte("{\n  \"c\": \"<x>\",\n  \"b\": [1, 2.0],\n  \"a\": 1.0\n}\n"), opts); diff == nil || len(diff.Changes) != 0 {
+		t.Errorf("reformatted JSON should have no changes, got %+v", diff)
+	}
+	if diff := structuralDiff("c.json", []byte(`{"a": "<1>"}`), []byte(`{"a": 1, "b": {"x": [true]}}`), opts); diff == nil ||
+		diff.Changes[0].String() != `a: "<1>" → 1` || diff.Changes[1].String() != `b: {"x":[true]}` {
+		t.Errorf("JSON changes = %+v", diff)
+	}
+
+	// Integers past float64's precision still compare exactly
+	for _, pair := range [][2]string{
+		{`{"id": 9007199254740993}`, `{"id": 9007199254740992}`},
+		{`{"id": 18446744073709551615}`, `{"id": 18446744073709551614}`},
+		{`{"id": 9223372036854775807}`, `{"id": 9223372036854775808}`},
+	} {
+		if diff := structuralDiff("c.json", []byte(pair[0]), []byte(pair[1]), opts); diff == nil || len(diff.Changes) != 1 {
+			t.Errorf("%s -> %s: changes = %+v, want one", pair[0], pair[1], diff)
+		}
+	}
+	if diff := structuralDiff("c.yaml", []byte("id: 18446744073709551615\n"), []byte("id: 18446744073709551614\n"), opts); diff == nil ||
+		len(diff.Changes) != 1 || diff.Changes[0].String() != "id: 18446744073709551615 → 18446744073709551614" {
+		t.Errorf("YAML integer changes = %+v", diff)
+	}
+
+	// Several YAML documents are a list
+	if diff := structuralDiff("all.yml", []byte("a: 1\n---\nb: 2\n"), []byte("a: 1\n---\nb: 3\n"), opts); diff == nil || len(diff.Changes) != 1 || diff.Changes[0].Path != "[1].b" {
+		t.Errorf("multi-document changes = %+v", diff)
+	}
+
+	for name, diff := range map[string]*StructuralDiff{
+		"view off":       structuralDiff("deploy.yaml", []byte(structuralOldManifest), []byte(structuralNewManifest), DiffOptions{}),
+		"not structured": structuralDiff("deploy.txt", []byte(structuralOldManifest), []byte(structuralNewManifest), opts),
+		"new file":       structuralDiff("deploy.yaml", nil, []byte(structuralNewManifest), opts),
+		"invalid YAML":   structuralDiff("deploy.yaml", []byte(structuralOldManifest), []byte("a: [1"), opts),
+		"trailing JSON":  structuralDiff("c.json", []byte(`{}`), []byte(`{} {}`), opts),
+	} {
+		if diff != nil {
+			t.Errorf("%s: got a structural diff %+v", name, diff)
+		}
+	}
+}
+
+func TestModelShowsStructuralView(t *testing.T) {
+	model := setupModel(t)
+	model.width = 120
+	model.height = 30
+	file, err := newFileDiffFromContents("deploy.yaml", []byte(structuralOldManifest), []byte(structuralNewManifest), true, true, false, DiffOptions{Context: 3, Structural: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	newModel, _ := model.Update(allDiffsLoadedMsg{files: []FileDiff{*file}})
+	model = newModel.(Model)
+
+	lines := model.buildDiffPanelLines()
+	layout := model.computeDiffLayout(model.getSelectedDiffFiles())
+	if layout.totalLines != len(lines) {
+		t.Errorf("layout has %d lines, the panel renders %d", layout.totalLines, len(lines))
+	}
+	if len(layout.hunks) != 0 {
+		t.Errorf("the structural view has no hunks to stage, got %d", len(layout.hunks))
+	}
+	view := stripAnsi(strings.Join(lines, "\n"))
+	if !strings.Contains(view, "Structure: 6 changes (formatting and key order ignored)\n~ metadata") || strings.Contains(view, "@@") {
+		t.Errorf("panel should list the changed values instead of hunks:\n%s", view)
+	}
+}
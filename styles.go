@@ -97,12 +97,43 @@ var (
 				Foreground(colorRed196).
 				Bold(true)
 
+	// Intra-line emphasis for the changed spans of paired removed/added lines
+	diffAddedEmphasisStyle = lipgloss.NewStyle().
+				Background(lipgloss.Color("22")).
+				Bold(true)
+
+	diffRemovedEmphasisStyle = lipgloss.NewStyle().
+					Background(lipgloss.Color("52")).
+					Bold(true)
+
+	// Moved blocks, after git's --color-moved=zebra: adjacent blocks alternate
+	diffMovedRemovedStyle = lipgloss.NewStyle().
+				Foreground(lipgloss.Color("170")). // Magenta
+				Bold(true)
+
+	diffMovedRemovedAltStyle = lipgloss.NewStyle().
+					Foreground(lipgloss.Color("68")). // Steel blue
+					Bold(true)
+
+	diffMovedAddedStyle = lipgloss.NewStyle().
+				Foreground(lipgloss.Color("80")). // Cyan
+				Bold(true)
+
+	diffMovedAddedAltStyle = lipgloss.NewStyle().
+				Foreground(lipgloss.Color("179")). // Amber
+				Bold(true)
+
 	diffContextStyle = lipgloss.NewStyle().
 				Foreground(colorGray245)
 
 	diffLineNumStyle = lipgloss.NewStyle().
 				Foreground(colorGray244)
 
+	// Line numbers of lines inside a visual selection
+	diffSelectedLineNumStyle = lipgloss.NewStyle().
+					Foreground(colorGray235).
+					Background(colorSoftBlue75)
+
 	diffSubtleStyle = lipgloss.NewStyle().
 			Foreground(colorGray244)
 
@@ -151,6 +182,11 @@ var (
 				Bold(true).
 				MarginTop(1)
 
+	// Confirmation modal styles
+	confirmTitleStyle = lipgloss.NewStyle().
+				Foreground(colorRed203).
+				Bold(true)
+
 	// Error styles
 	errorStyle = lipgloss.NewStyle().
 			Foreground(colorRed203).
@@ -232,6 +268,8 @@ func GetStatusStyle(changeType ChangeType) lipgloss.Style {
 		return statusDeletedStyle
 	case Renamed:
 		return statusModifiedStyle // Same as modified for now
+	case Copied:
+		return statusAddedStyle
 	default:
 		return subtleStyle
 	}
@@ -248,7 +286,22 @@ func GetStatusSymbol(changeType ChangeType) string {
 		return "D"
 	case Renamed:
 		return "R"
+	case Copied:
+		return "C"
 	default:
 		return "?"
 	}
 }
+
+// GetChangeKindStyle returns the style of the mark of a declaration or value
+// change, colored like the file tree
+func GetChangeKindStyle(kind ChangeKind) lipgloss.Style {
+	switch kind {
+	case KindAdded:
+		return addedStyle
+	case KindRemoved:
+		return deletedStyle
+	default:
+		return modifiedStyle
+	}
+}
@@ -21,6 +21,17 @@ type dirNode struct {
 type diffLayout struct {
 	totalLines int
 	hunkStarts []int
+	hunks      []hunkLocation // parallel to hunkStarts
+}
+
+// hunkLocation identifies a rendered hunk within the selected files
+type hunkLocation struct {
+	file    *FileDiff
+	hunkIdx int
+}
+
+func (loc hunkLocation) hunk() Hunk {
+	return loc.file.Hunks[loc.hunkIdx]
 }
 
 type treeChangeSummary struct {
@@ -34,7 +45,7 @@ func (s *treeChangeSummary) add(linesAdded, linesRemoved int, changeType ChangeT
 	s.linesAdded += linesAdded
 	s.linesRemoved += linesRemoved
 	switch changeType {
-	case Added:
+	case Added, Copied:
 		s.hasAdded = true
 	case Deleted:
 		s.hasDeleted = true
@@ -73,11 +84,39 @@ func (m Model) handleKeyMsg(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m.handleSearchInput(key, msg)
 	}
 
+	if m.pathspecMode {
+		return m, m.handlePathspecInput(key, msg)
+	}
+
+	if m.exportMode {
+		return m, m.handleExportInput(key, msg)
+	}
+
+	if m.logRefMode {
+		return m, m.handleLogRefInput(key, msg)
+	}
+	m.notice = ""
+
+	// A pending discard takes every key until it is answered
+	if m.pendingDiscard != nil {
+		return m, m.handleConfirmKey(key)
+	}
+
+	if m.branchPicker != nil {
+		return m, m.handleBranchPickerKey(key)
+	}
+
 	m.resetPendingVimTopJumpIfNeeded(key)
 	if m.shouldIgnoreKey(key) {
 		return m, nil
 	}
 
+	if m.selection != nil {
+		if cmd, handled := m.handleVisualKey(key); handled {
+			return m, cmd
+		}
+	}
+
 	return m, m.runKeyAction(key)
 }
 
@@ -116,6 +155,80 @@ func (m *Model) handleSearchInput(key string, msg tea.KeyMsg) (tea.Model, tea.Cm
 	}
 }
 
+// handlePathspecInput handles keyboard input in the pathspec prompt. Enter
+// applies the typed patterns (an empty prompt clears the pathspec) and reloads.
+func (m *Model) handlePathspecInput(key string, msg tea.KeyMsg) tea.Cmd {
+	switch key {
+	case "esc", "ctrl+c":
+		m.pathspecMode = false
+		m.pathspecInput = ""
+	case "enter":
+		pathspec, err := ParsePathspec(strings.Fields(m.pathspecInput), "")
+		if err != nil {
+			m.err = err
+			return nil
+		}
+		m.pathspec = pathspec
+		m.pathspecMode = false
+		m.pathspecInput = ""
+		return m.switchDiffMode(m.diffMode)
+	case "backspace":
+		if len(m.pathspecInput) > 0 {
+			m.pathspecInput = m.pathspecInput[:len(m.pathspecInput)-1]
+		}
+	default:
+		if len(msg.Runes) > 0 && isPrintableRune(msg.Runes[0]) {
+			m.pathspecInput += string(msg.Runes)
+		}
+	}
+	return nil
+}
+
+// enterPathspecMode opens the pathspec prompt, prefilled with the current pathspec
+func (m *Model) enterPathspecMode() {
+	m.pathspecMode = true
+	m.pathspecInput = m.pathspec.String()
+}
+
+// handleExportInput handles keyboard input in the patch export prompt. Enter
+// writes the loaded diffs to the typed path, relative to the working directory.
+func (m *Model) handleExportInput(key string, msg tea.KeyMsg) tea.Cmd {
+	switch key {
+	case "esc", "ctrl+c":
+		m.exportMode = false
+		m.exportInput = ""
+	case "enter":
+		path := strings.TrimSpace(m.exportInput)
+		if path == "" {
+			path = defaultPatchFile
+		}
+		m.exportMode = false
+		m.exportInput = ""
+		return m.exportPatch(path)
+	case "backspace":
+		if len(m.exportInput) > 0 {
+			m.exportInput = m.exportInput[:len(m.exportInput)-1]
+		}
+	default:
+		if len(msg.Runes) > 0 && isPrintableRune(msg.Runes[0]) {
+			m.exportInput += string(msg.Runes)
+		}
+	}
+	return nil
+}
+
+// enterExportMode opens the patch export prompt with the default file name.
+// While whitespace or blank lines are ignored the hunks leave out differences
+// and take context lines from the new side, so they would not apply.
+func (m *Model) enterExportMode() {
+	if !m.diffOptions().exact() {
+		m.notice = "Patches cannot be exported while whitespace or blank lines are ignored"
+		return
+	}
+	m.exportMode = true
+	m.exportInput = defaultPatchFile
+}
+
 // isPrintableRune checks if a rune is a printable character (not a control character)
 func isPrintableRune(r rune) bool {
 	return r >= 32 && r < 127
@@ -129,10 +242,12 @@ func (m *Model) runKeyAction(key string) tea.Cmd {
 		m.handleUpKey(key)
 	case "down", "j":
 		m.handleDownKey(key)
+		return m.loadMoreCommits()
 	case "pgup":
 		m.handlePageUp()
 	case "pgdown":
 		m.handlePageDown()
+		return m.loadMoreCommits()
 	case "g":
 		m.handleVimTopJump()
 	case "G":
@@ -143,23 +258,66 @@ func (m *Model) runKeyAction(key string) tea.Cmd {
 		return m.selectFileTreeItem()
 	case "s":
 		return m.toggleDiffMode()
+	case "b":
+		if m.diffMode == InputDiff {
+			return nil
+		}
+		return m.LoadBranches()
 	case "f":
 		return m.toggleDiffViewMode()
+	case "|":
+		return m.toggleSideBySide()
+	case "a":
+		return m.stageCurrentHunk()
+	case "u":
+		return m.unstageCurrentHunk()
+	case "d":
+		m.requestDiscard()
+	case "v":
+		m.enterVisualMode(false)
+	case "V":
+		m.enterVisualMode(true)
 	case "o":
 		return m.adjustDiffContext(DefaultDiffContext)
 	case "O":
 		return m.resetDiffContext()
+	case "A":
+		return m.cycleDiffAlgorithm()
+	case "w":
+		return m.cycleWhitespace()
+	case "W":
+		return m.toggleBlankLines()
+	case "m":
+		m.jumpToMove()
+	case "S":
+		return m.toggleSemantic()
+	case "D":
+		m.jumpToDecl()
+	case "K":
+		return m.toggleStructural()
+	case "i":
+		return m.openImagePreview()
 	case "?":
 		m.toggleHelp()
 	case "/":
 		return m.enterSearchMode()
+	case "P":
+		m.enterPathspecMode()
+	case "e":
+		m.enterExportMode()
+	case "L":
+		m.enterLogRefMode()
+	case "H":
+		return m.openFileHistory()
+	case "esc":
+		return m.closeFileHistory()
 	}
 	return nil
 }
 
 // enterSearchMode activates search mode for file tree filtering
 func (m *Model) enterSearchMode() tea.Cmd {
-	if m.panel != FileTreePanel || m.diffViewMode == WholeFile {
+	if m.panel != FileTreePanel || m.diffViewMode == WholeFile || m.diffMode == CommitLog {
 		return nil
 	}
 	m.searchMode = true
@@ -202,7 +360,7 @@ func (m *Model) handleUpKey(key string) {
 		m.moveUp()
 		return
 	}
-	if key == "k" && m.diffViewMode == DiffOnly {
+	if key == "k" && m.diffViewMode.showsHunks() {
 		m.jumpToPrevHunk()
 		return
 	}
@@ -214,7 +372,7 @@ func (m *Model) handleDownKey(key string) {
 		m.moveDown()
 		return
 	}
-	if key == "j" && m.diffViewMode == DiffOnly {
+	if key == "j" && m.diffViewMode.showsHunks() {
 		m.jumpToNextHunk()
 		return
 	}
@@ -271,7 +429,7 @@ func (m *Model) togglePanel() {
 }
 
 func (m *Model) adjustDiffContext(delta int) tea.Cmd {
-	if m.diffViewMode != DiffOnly {
+	if !m.diffViewMode.showsHunks() || m.hasFixedHunks() {
 		return nil
 	}
 	m.diffContext += delta
@@ -279,15 +437,80 @@ func (m *Model) adjustDiffContext(delta int) tea.Cmd {
 }
 
 func (m *Model) resetDiffContext() tea.Cmd {
-	if m.diffViewMode != DiffOnly {
+	if !m.diffViewMode.showsHunks() || m.hasFixedHunks() {
 		return nil
 	}
 	m.diffContext = DefaultDiffContext
 	return m.reloadCurrentDiffs()
 }
 
+// cycleDiffAlgorithm recomputes the diffs with the next algorithm
+func (m *Model) cycleDiffAlgorithm() tea.Cmd {
+	if m.hasFixedHunks() {
+		return nil
+	}
+	m.diffAlgorithm = m.diffAlgorithm.Next()
+	m.notice = "Diff algorithm: " + m.diffAlgorithm.String()
+	return m.reloadCurrentDiffs()
+}
+
+// cycleWhitespace recomputes the diffs ignoring more whitespace, back to
+// exact comparison after ignoring all of it
+func (m *Model) cycleWhitespace() tea.Cmd {
+	if m.hasFixedHunks() {
+		return nil
+	}
+	m.whitespace = m.whitespace.Next()
+	m.notice = "Whitespace: " + m.whitespace.Label()
+	return m.reloadCurrentDiffs()
+}
+
+// toggleBlankLines hides or shows changes that only add or remove blank lines
+func (m *Model) toggleBlankLines() tea.Cmd {
+	if m.hasFixedHunks() {
+		return nil
+	}
+	m.blankLines = !m.blankLines
+	if m.blankLines {
+		m.notice = "Blank line changes hidden"
+	} else {
+		m.notice = "Blank line changes shown"
+	}
+	return m.reloadCurrentDiffs()
+}
+
+// hunkEditsBlocked refuses to stage, unstage or discard hunks while
+// whitespace or blank lines are ignored, since the hunks then leave out
+// differences and no longer match the files line for line, and hunks of a
+// renamed or copied file, which are relative to another path
+func (m *Model) hunkEditsBlocked() bool {
+	if m.panel != DiffPanel || !m.diffViewMode.showsHunks() {
+		return false
+	}
+	if !m.diffOptions().exact() {
+		m.notice = "Hunks cannot be staged or discarded while whitespace or blank lines are ignored"
+		return true
+	}
+	if loc, ok := m.currentHunk(); ok && loc.file.displayPath() != loc.file.Path {
+		m.notice = "Hunks of renamed or copied files cannot be staged or discarded"
+		return true
+	}
+	return false
+}
+
 func (m *Model) toggleDiffMode() tea.Cmd {
-	m.diffMode = nextDiffMode(m.diffMode)
+	if m.diffMode == InputDiff {
+		return nil
+	}
+	return m.switchDiffMode(nextDiffMode(m.diffMode, m.revisions != nil))
+}
+
+// switchDiffMode drops the data loaded for the old mode and loads the new one
+func (m *Model) switchDiffMode(mode DiffMode) tea.Cmd {
+	m.diffMode = mode
+	if mode != CommitLog {
+		m.logPath = ""
+	}
 	m.resetSelectionAndLoadedData()
 	// Clear search when changing modes
 	m.searchQuery = ""
@@ -296,7 +519,7 @@ func (m *Model) toggleDiffMode() tea.Cmd {
 }
 
 func (m *Model) toggleDiffViewMode() tea.Cmd {
-	if m.diffViewMode == DiffOnly {
+	if m.diffViewMode.showsHunks() {
 		m.diffViewMode = WholeFile
 		m.panel = DiffPanel
 	} else {
@@ -312,6 +535,54 @@ func (m *Model) toggleDiffViewMode() tea.Cmd {
 	return m.reloadDiffsForCurrentMode()
 }
 
+// toggleSideBySide switches between the split layout and the unified Diff Only layout.
+// Both layouts share the same hunks, so only the whole-file transition needs a reload.
+func (m *Model) toggleSideBySide() tea.Cmd {
+	previous := m.diffViewMode
+	if m.diffViewMode == SideBySide {
+		m.diffViewMode = DiffOnly
+	} else {
+		m.diffViewMode = SideBySide
+	}
+
+	m.diffScroll = 0
+	if previous != WholeFile {
+		return nil
+	}
+
+	m.diffFiles = nil
+	m.searchQuery = ""
+	m.searchMode = false
+	return m.reloadDiffsForCurrentMode()
+}
+
+func (m *Model) stageCurrentHunk() tea.Cmd {
+	if m.diffMode != Unstaged || m.hunkEditsBlocked() {
+		return nil
+	}
+	return m.withCurrentHunk(m.StageHunk)
+}
+
+func (m *Model) unstageCurrentHunk() tea.Cmd {
+	if m.diffMode != Staged || m.hunkEditsBlocked() {
+		return nil
+	}
+	return m.withCurrentHunk(m.UnstageHunk)
+}
+
+// withCurrentHunk runs action on the hunk under the cursor when the diff panel
+// is focused and shows individual hunks.
+func (m Model) withCurrentHunk(action func(path string, hunk Hunk) tea.Cmd) tea.Cmd {
+	if m.panel != DiffPanel || !m.diffViewMode.showsHunks() {
+		return nil
+	}
+	loc, ok := m.currentHunk()
+	if !ok {
+		return nil
+	}
+	return action(loc.file.Path, loc.hunk())
+}
+
 func (m Model) handleAsyncMsg(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch typed := msg.(type) {
 	case gitInfoMsg:
@@ -324,6 +595,14 @@ func (m Model) handleAsyncMsg(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.applyAllDiffsLoaded(typed)
 	case commitsLoadedMsg:
 		m.applyCommitsLoaded(typed)
+	case commitLogLoadedMsg:
+		return m, m.applyCommitLogLoaded(typed)
+	case commitDiffLoadedMsg:
+		m.applyCommitDiffLoaded(typed)
+	case baseBranchMsg:
+		m.resolvedBase = typed.base
+	case branchesLoadedMsg:
+		m.openBranchPicker(typed.branches)
 	case filesChangedMsg:
 		return m.handleFilesChanged(typed)
 	case diffLoadedMsg:
@@ -336,6 +615,9 @@ func (m Model) handleAsyncMsg(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.err = typed.err
 	case clearErrorMsg:
 		m.err = nil
+	case patchExportedMsg:
+		m.err = nil
+		m.notice = fmt.Sprintf("Exported %d file(s) to %s", typed.files, typed.path)
 	default:
 	}
 
@@ -347,7 +629,7 @@ func (m *Model) setHelpVisibility(visible bool) {
 }
 
 func (m Model) loadBranchCompareData() tea.Cmd {
-	return tea.Batch(m.LoadCommitsAhead(), m.LoadBranchCompareDiff(m.commits))
+	return tea.Batch(m.LoadBaseBranch(), m.LoadCommitsAhead(), m.LoadBranchCompareDiff(m.commits))
 }
 
 func (m Model) handleGitInfoLoaded(msg gitInfoMsg) (tea.Model, tea.Cmd) {
@@ -376,7 +658,7 @@ func (m *Model) applyFilesLoaded(msg filesLoadedMsg) {
 	m.files = msg.files
 	m.err = nil
 
-	if m.diffMode != BranchCompare && len(m.diffFiles) > 0 {
+	if !m.diffMode.loadsAllDiffs() && len(m.diffFiles) > 0 {
 		m.files = mergeFilesWithDiffStats(m.files, m.diffFiles)
 	}
 
@@ -386,9 +668,11 @@ func (m *Model) applyFilesLoaded(msg filesLoadedMsg) {
 
 func (m *Model) applyAllDiffsLoaded(msg allDiffsLoadedMsg) {
 	m.diffFiles = msg.files
+	markMovedLines(m.diffFiles, m.whitespace)
 	m.err = nil
+	m.selection = nil
 
-	if m.diffMode == BranchCompare {
+	if m.diffMode.loadsAllDiffs() {
 		m.lastFileHash = computeBranchCompareHash(msg.files, m.commits)
 		m.files = aggregateBranchCompareFiles(msg.files)
 	} else {
@@ -397,7 +681,7 @@ func (m *Model) applyAllDiffsLoaded(msg allDiffsLoadedMsg) {
 	}
 
 	m.buildFileTree()
-	if m.selectedIndex >= len(m.flattenTree()) {
+	if m.selectedIndex >= m.listLength() {
 		m.selectedIndex = 0
 	}
 }
@@ -410,11 +694,12 @@ func (m *Model) applyCommitsLoaded(msg commitsLoadedMsg) {
 
 func (m Model) handleFilesChanged(msg filesChangedMsg) (tea.Model, tea.Cmd) {
 	m.lastFileHash = msg.hash
-	if m.diffMode != BranchCompare {
+	if !m.diffMode.loadsAllDiffs() {
 		m.files = msg.files
 		m.buildFileTree()
 	}
 	m.diffFiles = nil
+	m.selection = nil
 	return m, m.reloadDiffsForCurrentMode()
 }
 
@@ -423,14 +708,18 @@ func (m *Model) upsertLoadedDiff(file FileDiff) {
 		return
 	}
 
+	replaced := false
 	for i := range m.diffFiles {
 		if m.diffFiles[i].Path == file.Path {
 			m.diffFiles[i] = file
-			return
+			replaced = true
+			break
 		}
 	}
-
-	m.diffFiles = append(m.diffFiles, file)
+	if !replaced {
+		m.diffFiles = append(m.diffFiles, file)
+	}
+	markMovedLines(m.diffFiles, m.whitespace)
 }
 
 // moveUp moves the selection up
@@ -447,7 +736,7 @@ func (m *Model) moveUp() {
 
 // moveDown moves the selection down
 func (m *Model) moveDown() {
-	maxIndex := len(m.flattenTree()) - 1
+	maxIndex := m.listLength() - 1
 
 	if m.selectedIndex < maxIndex {
 		m.selectedIndex++
@@ -480,7 +769,7 @@ func (m *Model) movePageUp() {
 func (m *Model) movePageDown() {
 	visibleHeight := m.visibleContentRows()
 
-	maxIndex := len(m.flattenTree()) - 1
+	maxIndex := m.listLength() - 1
 	if maxIndex < 0 {
 		m.selectedIndex = 0
 		m.scrollOffset = 0
@@ -555,7 +844,7 @@ func (m *Model) moveDiffToBottom() {
 
 func (m Model) visibleContentRows() int {
 	// Terminal rows minus header/footer rows and panel border rows.
-	return panelContentHeight(contentHeight(m.height, m.searchMode))
+	return panelContentHeight(contentHeight(m.height, m.inputBarVisible()))
 }
 
 // getDiffLineCount returns the total number of lines in the current diff
@@ -564,8 +853,20 @@ func (m *Model) getDiffLineCount() int {
 	return m.computeDiffLayout(filesToRender).totalLines
 }
 
+// listLength returns the number of rows in the left panel: commits in the
+// commit log, tree nodes otherwise
+func (m *Model) listLength() int {
+	if m.diffMode == CommitLog {
+		return len(m.commits)
+	}
+	return len(m.flattenTree())
+}
+
 // selectItem handles selection of current item
 func (m *Model) selectItem() tea.Cmd {
+	if m.diffMode == CommitLog {
+		return m.selectCommit()
+	}
 	flatTree := m.flattenTree()
 	if m.selectedIndex < 0 || m.selectedIndex >= len(flatTree) {
 		return nil
@@ -578,8 +879,8 @@ func (m *Model) selectItem() tea.Cmd {
 		return nil
 	}
 
-	// In branch compare mode, file diffs are already loaded.
-	if m.diffMode == BranchCompare {
+	// In commit compare modes, file diffs are already loaded.
+	if m.diffMode.loadsAllDiffs() {
 		m.diffScroll = 0
 		return nil
 	}
@@ -621,6 +922,24 @@ func (m *Model) jumpToPrevHunk() {
 	m.diffScroll = hunkStarts[0]
 }
 
+// currentHunk returns the hunk under the top of the diff viewport: the last hunk
+// starting at or above diffScroll, or the first hunk when scrolled above all of them.
+func (m Model) currentHunk() (hunkLocation, bool) {
+	layout := m.computeDiffLayout(m.getSelectedDiffFiles())
+	if len(layout.hunks) == 0 {
+		return hunkLocation{}, false
+	}
+
+	current := 0
+	for i, start := range layout.hunkStarts {
+		if start > m.diffScroll {
+			break
+		}
+		current = i
+	}
+	return layout.hunks[current], true
+}
+
 func (m Model) getCurrentHunkStartLines() []int {
 	filesToRender := m.getSelectedDiffFiles()
 	return m.computeDiffLayout(filesToRender).hunkStarts
@@ -628,35 +947,33 @@ func (m Model) getCurrentHunkStartLines() []int {
 
 func (m Model) computeDiffLayout(filesToRender []*FileDiff) diffLayout {
 	layout := diffLayout{}
+	lineNum := len(m.diffPanelHeaderLines()) // compare or commit header
 	if len(filesToRender) == 0 {
-		if m.diffMode == BranchCompare {
-			layout.totalLines = 3 // branch header + blank + message
-		} else {
-			layout.totalLines = 1 // message
-		}
+		layout.totalLines = lineNum + 1 // message
 		return layout
 	}
 
-	lineNum := 0
-	if m.diffMode == BranchCompare {
-		lineNum += 2 // branch header + blank
-	}
-
 	for fileIdx, selectedFile := range filesToRender {
 		if fileIdx > 0 {
 			lineNum += 2 // blank + separator
 		}
 
 		lineNum++ // file header
+		if selectedFile.Structure != nil {
+			lineNum += len(structuralLines(selectedFile.Structure)) // changed values instead of hunks
+			continue
+		}
 		if len(selectedFile.Hunks) == 0 {
-			lineNum++ // no-hunk message
+			lineNum += noHunksLineCount(*selectedFile, diffContentWidth(m.width, m.diffViewMode)) // no-hunk message or binary summary
 			continue
 		}
 
-		for _, hunk := range selectedFile.Hunks {
+		lineNum += len(declSummaryLines(*selectedFile)) // Go declaration summary
+		for hunkIdx, hunk := range selectedFile.Hunks {
 			layout.hunkStarts = append(layout.hunkStarts, lineNum)
+			layout.hunks = append(layout.hunks, hunkLocation{file: selectedFile, hunkIdx: hunkIdx})
 			lineNum++ // hunk separator line
-			lineNum += len(hunk.Lines)
+			lineNum += m.hunkRowCount(hunk)
 		}
 	}
 
@@ -665,6 +982,15 @@ func (m Model) computeDiffLayout(filesToRender []*FileDiff) diffLayout {
 }
 
 func (m Model) getSelectedDiffFiles() []*FileDiff {
+	if m.diffMode == CommitLog {
+		// The left panel lists commits, so every file of the commit is shown
+		files := make([]*FileDiff, len(m.diffFiles))
+		for i := range m.diffFiles {
+			files[i] = &m.diffFiles[i]
+		}
+		return files
+	}
+
 	flatTree := m.flattenTree()
 	if m.selectedIndex < 0 || m.selectedIndex >= len(flatTree) {
 		return nil
@@ -681,7 +1007,7 @@ func (m Model) getSelectedDiffFiles() []*FileDiff {
 			continue
 		}
 		matching = append(matching, &m.diffFiles[i])
-		if m.diffMode != BranchCompare {
+		if !m.diffMode.loadsAllDiffs() {
 			break
 		}
 	}
@@ -863,18 +1189,26 @@ func buildTreeNodesWithSummary(dir *dirNode, depth int) ([]TreeNode, int, int, C
 			changeType:   file.ChangeType,
 			linesAdded:   file.LinesAdded,
 			linesRemoved: file.LinesRemoved,
+			oldPath:      file.OldPath,
 		})
 	}
 
 	return nodes, summary.linesAdded, summary.linesRemoved, summary.changeType()
 }
 
-func nextDiffMode(mode DiffMode) DiffMode {
+// nextDiffMode returns the mode after mode in the s cycle. RefCompare is only
+// part of the cycle when revisions were given on the command line.
+func nextDiffMode(mode DiffMode, hasRevisions bool) DiffMode {
 	switch mode {
 	case Unstaged:
 		return Staged
 	case Staged:
 		return BranchCompare
+	case BranchCompare:
+		if hasRevisions {
+			return RefCompare
+		}
+		return Unstaged
 	default:
 		return Unstaged
 	}
@@ -888,20 +1222,44 @@ func (m *Model) resetSelectionAndLoadedData() {
 	m.files = nil
 	m.commits = nil
 	m.selectedCommit = nil
+	m.logHasMore = false
+	m.logLoading = false
 }
 
 func (m Model) reloadByDiffMode() tea.Cmd {
-	if m.diffMode == BranchCompare {
-		return tea.Batch(m.LoadCommitsAhead(), m.LoadBranchCompareDiff(nil))
+	switch m.diffMode {
+	case InputDiff:
+		return m.LoadInputDiffs()
+	case BranchCompare:
+		return tea.Batch(m.LoadBaseBranch(), m.LoadCommitsAhead(), m.LoadBranchCompareDiff(nil))
+	case RefCompare:
+		return m.LoadRefCompareDiff()
+	case CommitLog:
+		if m.logPath != "" {
+			return m.LoadFileHistory()
+		}
+		return m.LoadCommitLog(0)
+	default:
+		return tea.Batch(m.LoadFiles(), m.LoadAllDiffs())
 	}
-	return tea.Batch(m.LoadFiles(), m.LoadAllDiffs())
 }
 
 func (m Model) reloadDiffsForCurrentMode() tea.Cmd {
-	if m.diffMode == BranchCompare {
+	switch m.diffMode {
+	case InputDiff:
+		return m.LoadInputDiffs()
+	case BranchCompare:
 		return m.loadBranchCompareData()
+	case RefCompare:
+		return m.LoadRefCompareDiff()
+	case CommitLog:
+		if m.selectedCommit == nil {
+			return nil
+		}
+		return m.LoadLogCommit(*m.selectedCommit)
+	default:
+		return m.LoadAllDiffs()
 	}
-	return m.LoadAllDiffs()
 }
 
 // checkForChanges checks if the git repo has changed and reloads if necessary
@@ -912,22 +1270,28 @@ func (m Model) checkForChanges() tea.Cmd {
 			return nil
 		}
 
-		if m.diffMode == BranchCompare {
+		switch m.diffMode {
+		case InputDiff, CommitLog:
+			// Neither depends on the working tree
+			return nil
+		case BranchCompare:
 			return m.checkBranchCompareChanges()
+		case RefCompare:
+			return m.checkRefCompareChanges()
+		default:
+			return m.checkWorkingTreeChanges()
 		}
-
-		return m.checkWorkingTreeChanges()
 	}
 }
 
 func (m Model) checkBranchCompareChanges() tea.Msg {
-	commits, err := m.git.GetCommitsAheadOfMain()
+	commits, err := m.git.GetCommitsAheadOfBase(m.baseBranch)
 	if err != nil {
 		m.logger.Error("check commits in branch compare", err, nil)
 		return nil
 	}
 
-	unifiedDiffs, err := m.git.GetUnifiedBranchCompareDiff(m.diffViewMode, m.diffContext, m.logger)
+	unifiedDiffs, err := m.git.GetUnifiedBranchCompareDiff(m.baseBranch, m.diffViewMode, m.diffOptions(), m.pathspec, m.logger)
 	if err != nil {
 		m.logger.Error("check unified branch compare diff", err, nil)
 		return nil
@@ -942,8 +1306,26 @@ func (m Model) checkBranchCompareChanges() tea.Msg {
 	return filesChangedMsg{hash: currentHash}
 }
 
+func (m Model) checkRefCompareChanges() tea.Msg {
+	diffs, err := m.git.GetRefCompareDiff(*m.revisions, m.diffViewMode, m.diffOptions(), m.pathspec, m.logger)
+	if err != nil {
+		m.logger.Error("check ref compare diff", err, map[string]any{
+			"revisions": m.revisions.String(),
+		})
+		return nil
+	}
+
+	currentHash := computeDiffHash(diffs)
+	if currentHash == m.lastFileHash {
+		return nil
+	}
+
+	m.logChangeDetected(currentHash, nil)
+	return filesChangedMsg{hash: currentHash}
+}
+
 func (m Model) checkWorkingTreeChanges() tea.Msg {
-	files, err := m.git.GetChangedFiles(m.diffMode)
+	files, err := m.git.GetChangedFiles(m.diffMode, m.pathspec)
 	if err != nil {
 		m.logger.Error("check file list for changes", err, map[string]any{
 			"mode": m.diffMode,
@@ -951,7 +1333,7 @@ func (m Model) checkWorkingTreeChanges() tea.Msg {
 		return nil
 	}
 
-	diffs, err := m.git.GetDiffWithContext(m.diffMode, m.diffViewMode, m.diffContext, m.logger)
+	diffs, err := m.git.GetDiffWithContext(m.diffMode, m.diffViewMode, m.diffOptions(), m.pathspec, m.logger)
 	if err != nil {
 		m.logger.Error("check diff content for changes", err, map[string]any{
 			"mode": m.diffMode,
@@ -1070,12 +1452,26 @@ func mergeFilesWithDiffStats(files []FileDiff, diffs []FileDiff) []FileDiff {
 		return merged
 	}
 
+	// Status lists the old path of a rename as deleted; the diff shows it
+	// under the new path
+	renamedFrom := make(map[string]bool)
+	for _, d := range diffs {
+		if d.ChangeType == Renamed {
+			renamedFrom[d.OldPath] = true
+		}
+	}
+
 	merged := make([]FileDiff, 0, len(files))
 	for _, f := range files {
-		if d, ok := statsByPath[f.Path]; ok {
+		d, ok := statsByPath[f.Path]
+		if !ok && renamedFrom[f.Path] {
+			continue
+		}
+		if ok {
 			f.LinesAdded = d.LinesAdded
 			f.LinesRemoved = d.LinesRemoved
 			f.ChangeType = d.ChangeType
+			f.OldPath = d.OldPath
 		}
 		merged = append(merged, f)
 	}
@@ -1091,6 +1487,7 @@ func aggregateBranchCompareFiles(diffFiles []FileDiff) []FileDiff {
 		if !ok {
 			byPath[f.Path] = FileDiff{
 				Path:         f.Path,
+				OldPath:      f.OldPath,
 				ChangeType:   f.ChangeType,
 				LinesAdded:   f.LinesAdded,
 				LinesRemoved: f.LinesRemoved,
@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"path"
 	"strings"
 
 	"github.com/charmbracelet/lipgloss"
@@ -18,8 +19,16 @@ func (m Model) View() string {
 		return m.renderHelpModal()
 	}
 
+	if m.pendingDiscard != nil {
+		return m.renderConfirmModal()
+	}
+
+	if m.branchPicker != nil {
+		return m.renderBranchPicker()
+	}
+
 	// Calculate dimensions
-	availHeight := contentHeight(m.height, m.searchMode)
+	availHeight := contentHeight(m.height, m.inputBarVisible())
 
 	// Create header
 	header := m.renderHeader()
@@ -50,12 +59,33 @@ func (m Model) renderHeader() string {
 
 	parts = append(parts, viewModeIndicatorStyle.Render("["+m.diffViewModeLabel()+"]"))
 
+	if m.diffAlgorithm != AlgorithmMyers {
+		parts = append(parts, viewModeIndicatorStyle.Render("["+m.diffAlgorithm.Label()+"]"))
+	}
+
+	if m.whitespace != WhitespaceExact {
+		parts = append(parts, viewModeIndicatorStyle.Render("["+m.whitespace.Label()+"]"))
+	}
+	if m.blankLines {
+		parts = append(parts, viewModeIndicatorStyle.Render("[Ignore blank lines]"))
+	}
+	if m.semantic {
+		parts = append(parts, viewModeIndicatorStyle.Render("[Go declarations]"))
+	}
+	if m.structural {
+		parts = append(parts, viewModeIndicatorStyle.Render("[Key paths]"))
+	}
+
 	files, added, removed := m.GetTotalStats()
 	if files > 0 {
 		stats := formatAggregateStats(files, added, removed)
 		parts = append(parts, statsSubtleStyle.Render("("+stats+")"))
 	}
 
+	if !m.pathspec.IsEmpty() {
+		parts = append(parts, searchIndicatorStyle.Render("Paths: "+m.pathspec.String()))
+	}
+
 	// Show search indicator or help hint
 	if m.searchQuery != "" {
 		filtered := len(m.flattenTree())
@@ -73,6 +103,21 @@ func (m Model) renderHeader() string {
 		return lipgloss.JoinVertical(lipgloss.Left, header, separator, searchBar)
 	}
 
+	if m.pathspecMode {
+		separator := headerSeparatorStyle.Render(strings.Repeat("─", m.width))
+		return lipgloss.JoinVertical(lipgloss.Left, header, separator, m.renderPathspecBar())
+	}
+
+	if m.exportMode {
+		separator := headerSeparatorStyle.Render(strings.Repeat("─", m.width))
+		return lipgloss.JoinVertical(lipgloss.Left, header, separator, m.renderExportBar())
+	}
+
+	if m.logRefMode {
+		separator := headerSeparatorStyle.Render(strings.Repeat("─", m.width))
+		return lipgloss.JoinVertical(lipgloss.Left, header, separator, m.renderLogRefBar())
+	}
+
 	separator := headerSeparatorStyle.Render(strings.Repeat("─", m.width))
 
 	return lipgloss.JoinVertical(lipgloss.Left, header, separator)
@@ -89,6 +134,41 @@ func (m Model) renderSearchBar() string {
 	return searchLineStyle.Width(m.width).Render(searchLine)
 }
 
+// renderPathspecBar renders the pathspec input prompt
+func (m Model) renderPathspecBar() string {
+	prompt := searchPromptStyle.Render("Paths: ")
+	input := searchQueryStyle.Render(m.pathspecInput)
+	cursor := searchCursorStyle.Render("█")
+	hint := subtleStyle.Render("  globs, :!exclude  [Enter] apply  [Esc] cancel")
+
+	return searchLineStyle.Width(m.width).Render(prompt + input + cursor + hint)
+}
+
+// renderExportBar renders the patch export prompt
+func (m Model) renderExportBar() string {
+	prompt := searchPromptStyle.Render("Export patch: ")
+	input := searchQueryStyle.Render(m.exportInput)
+	cursor := searchCursorStyle.Render("█")
+	hint := subtleStyle.Render(fmt.Sprintf("  %d context lines  [Enter] write  [Esc] cancel", m.diffContext))
+
+	return searchLineStyle.Width(m.width).Render(prompt + input + cursor + hint)
+}
+
+// renderLogRefBar renders the prompt for the ref the commit log starts from
+func (m Model) renderLogRefBar() string {
+	prompt := searchPromptStyle.Render("Log: ")
+	input := searchQueryStyle.Render(m.logRefInput)
+	cursor := searchCursorStyle.Render("█")
+	hint := subtleStyle.Render("  branch, tag or commit  [Enter] show history  [Esc] cancel")
+
+	return searchLineStyle.Width(m.width).Render(prompt + input + cursor + hint)
+}
+
+// inputBarVisible reports whether a prompt line is shown below the header
+func (m Model) inputBarVisible() bool {
+	return m.searchMode || m.pathspecMode || m.exportMode || m.logRefMode
+}
+
 func (m Model) renderContent(height int) string {
 	// In whole file mode, hide the file tree and show only diff
 	if m.diffViewMode == WholeFile {
@@ -100,6 +180,9 @@ func (m Model) renderContent(height int) string {
 	rightPanelWidth := diffPanelWidth(m.width)
 
 	leftPanel := m.renderFileTree(leftPanelWidth, height)
+	if m.diffMode == CommitLog {
+		leftPanel = m.renderCommits(leftPanelWidth, height)
+	}
 	rightPanel := m.renderDiffPanel(rightPanelWidth, height)
 
 	// Join panels horizontally
@@ -132,7 +215,7 @@ func (m Model) renderFileTree(width, height int) string {
 
 func renderTreeNodeLine(node TreeNode, isSelected, isTreePanelActive bool, selectedStyle lipgloss.Style) string {
 	indicator, lineStyle := treeNodeIndicatorAndStyle(node)
-	line := treeNodePrefix(node) + indicator + " " + node.name
+	line := treeNodePrefix(node) + indicator + " " + treeNodeName(node)
 
 	if !node.isDir && (node.linesAdded > 0 || node.linesRemoved > 0) {
 		line += statsStyle.Render(formatLineStats(node.linesAdded, node.linesRemoved))
@@ -144,6 +227,19 @@ func renderTreeNodeLine(node TreeNode, isSelected, isTreePanelActive bool, selec
 	return lineStyle.Render(line)
 }
 
+// treeNodeName returns the name shown for a node: "old → new" for a renamed
+// or copied file, with the old name alone when the directory is the same
+func treeNodeName(node TreeNode) string {
+	if node.oldPath == "" || node.oldPath == node.path {
+		return node.name
+	}
+	oldName := node.oldPath
+	if path.Dir(node.oldPath) == path.Dir(node.path) {
+		oldName = path.Base(node.oldPath)
+	}
+	return oldName + " → " + node.name
+}
+
 func treeNodePrefix(node TreeNode) string {
 	prefix := strings.Repeat("  ", node.depth)
 	if !node.isDir {
@@ -161,7 +257,7 @@ func treeNodeIndicatorAndStyle(node TreeNode) (string, lipgloss.Style) {
 	}
 
 	switch node.changeType {
-	case Added:
+	case Added, Copied:
 		return "+", addedStyle
 	case Deleted:
 		return "-", deletedStyle
@@ -193,9 +289,7 @@ func (m Model) buildDiffPanelLines() []string {
 	filesToRender := m.getSelectedDiffFiles()
 	lines := make([]string, 0)
 
-	if m.diffMode == BranchCompare {
-		lines = append(lines, diffCommitHeaderStyle.Render("Branch Compare: current working tree vs default branch"), "")
-	}
+	lines = append(lines, m.diffPanelHeaderLines()...)
 
 	if len(filesToRender) == 0 {
 		return append(lines, panelInfoStyle.Render(m.diffPanelEmptyMessage()))
@@ -207,8 +301,46 @@ func (m Model) buildDiffPanelLines() []string {
 	return lines
 }
 
+// diffPanelHeaderLines returns the lines above the files in the commit
+// compare modes, ending with a blank line, and none in the other modes
+func (m Model) diffPanelHeaderLines() []string {
+	if !m.diffMode.loadsAllDiffs() {
+		return nil
+	}
+	if m.diffMode == CommitLog && m.selectedCommit != nil {
+		return commitHeaderLines(*m.selectedCommit)
+	}
+	return []string{diffCommitHeaderStyle.Render(m.compareHeader()), ""}
+}
+
+// compareHeader describes what a commit compare mode is diffing
+func (m Model) compareHeader() string {
+	if m.diffMode == InputDiff && m.input != nil {
+		return m.input.title
+	}
+	if m.diffMode == RefCompare && m.revisions != nil {
+		return "Compare: " + m.revisions.Description()
+	}
+	if m.diffMode == CommitLog {
+		if m.logPath != "" {
+			return "History: " + m.logPath
+		}
+		return "Log: " + defaultRevision(m.logRef)
+	}
+	if m.resolvedBase == nil {
+		return "Branch Compare: current working tree vs default branch"
+	}
+	return fmt.Sprintf("Branch Compare: current working tree vs %s (%s)", m.resolvedBase.Name, m.resolvedBase.Source)
+}
+
 func (m Model) diffPanelEmptyMessage() string {
-	if m.diffMode == BranchCompare {
+	if m.diffMode == CommitLog {
+		if m.selectedCommit != nil {
+			return "No file changes in this commit"
+		}
+		return "Select a commit to view its changes"
+	}
+	if m.diffMode.loadsAllDiffs() {
 		return "Select a file to view unified changes"
 	}
 	return "Select a file to view diff"
@@ -227,66 +359,160 @@ func visiblePaddedLines(allLines []string, scrollOffset, height int) []string {
 	return lines
 }
 
+// noHunksLines explains why a file shows no hunks; binary files get a
+// summary of both versions, and images a preview width columns wide (none
+// when width is 0)
+func noHunksLines(file FileDiff, width int) []string {
+	if file.Binary != nil {
+		var lines []string
+		for _, line := range binarySummaryLines(file.Binary) {
+			lines = append(lines, panelInfoStyle.Render(line))
+		}
+		return append(lines, imagePreviewLines(file.Binary, width)...)
+	}
+
+	message := "No diff content available (no line changes)"
+	switch file.ChangeType {
+	case Renamed:
+		message = "Renamed without changes"
+	case Copied:
+		message = "Copied without changes"
+	}
+	return []string{panelInfoStyle.Render(message)}
+}
+
+// noHunksLineCount returns the number of lines noHunksLines gives, without
+// drawing an image preview
+func noHunksLineCount(file FileDiff, width int) int {
+	if file.Binary != nil {
+		return len(binarySummaryLines(file.Binary)) + imagePreviewHeight(file.Binary, width)
+	}
+	return 1
+}
+
 func (m Model) appendRenderedFileDiffLines(lines []string, file *FileDiff, withSeparator bool) []string {
 	if withSeparator {
 		lines = append(lines, "")
 		lines = append(lines, diffHunkStyle.Render("════════════════════════════════════"))
 	}
 
-	lines = append(lines, diffFileHeaderStyle.Render("📄 "+file.Path))
+	lines = append(lines, diffFileHeaderStyle.Render("📄 "+file.displayPath()))
+	if file.Structure != nil {
+		return append(lines, structuralLines(file.Structure)...)
+	}
 	if len(file.Hunks) == 0 {
-		return append(lines, panelInfoStyle.Render("No diff content available (binary file or no changes)"))
+		return append(lines, noHunksLines(*file, diffContentWidth(m.width, m.diffViewMode))...)
 	}
 
-	for _, hunk := range file.Hunks {
-		lines = append(lines, diffHunkStyle.Render("─"))
-		for _, diffLine := range hunk.Lines {
-			lines = append(lines, m.renderDiffLine(diffLine, file.Path))
-		}
+	lines = append(lines, declSummaryLines(*file)...)
+	for hunkIdx, hunk := range file.Hunks {
+		lines = append(lines, diffHunkStyle.Render(formatHunkHeader(hunk)))
+		lines = append(lines, m.renderHunkRows(hunk, file.Path, m.selectedLinesFor(file.Path, hunkIdx))...)
 	}
 	return lines
 }
 
-func (m Model) renderDiffLine(diffLine DiffLine, filePath string) string {
-	var (
-		prefix       string
-		prefixStyle  lipgloss.Style
-		contentStyle lipgloss.Style
-	)
+// hunkRenderInput bundles a hunk with the per-line data needed to draw it
+type hunkRenderInput struct {
+	hunk     Hunk
+	filePath string
+	emphasis map[int][]textSpan // changed spans of paired lines, keyed by line index
+	selected *lineRange         // visually selected lines, nil when none
+}
 
-	switch diffLine.Type {
-	case LineAdded:
-		prefix = "+"
-		prefixStyle = diffAddedPrefixStyle
-		contentStyle = diffAddedStyle
-	case LineRemoved:
-		prefix = "-"
-		prefixStyle = diffRemovedPrefixStyle
-		contentStyle = diffRemovedStyle
-	default:
-		prefix = " "
-		prefixStyle = diffContextStyle
-		contentStyle = diffContextStyle
+// lineNumStyle returns the line number style for the line at idx
+func (input hunkRenderInput) lineNumStyle(idx int) lipgloss.Style {
+	if input.selected != nil && input.selected.contains(idx) {
+		return diffSelectedLineNumStyle
+	}
+	return diffLineNumStyle
+}
+
+// renderHunkRows renders a hunk in the layout of the current view mode.
+func (m Model) renderHunkRows(hunk Hunk, filePath string, selected *lineRange) []string {
+	input := hunkRenderInput{
+		hunk:     hunk,
+		filePath: filePath,
+		emphasis: computeHunkEmphasis(hunk.Lines),
+		selected: selected,
 	}
+	if m.diffViewMode == SideBySide {
+		return m.renderSplitHunkRows(input)
+	}
+
+	rows := make([]string, 0, len(hunk.Lines))
+	for i, diffLine := range hunk.Lines {
+		rows = append(rows, m.renderDiffLine(diffLine, filePath, input.emphasis[i], input.lineNumStyle(i)))
+	}
+	return rows
+}
+
+func (m Model) renderDiffLine(diffLine DiffLine, filePath string, spans []textSpan, numStyle lipgloss.Style) string {
+	prefix, prefixStyle, _ := diffLinePrefixAndStyles(diffLine)
 
 	// Render line numbers
-	lineNums := renderDiffLineNumbers(diffLine)
+	lineNums := renderDiffLineNumbers(diffLine, numStyle)
+
+	return lineNums + prefixStyle.Render(prefix) + " " + m.renderDiffLineContent(diffLine, filePath, spans)
+}
+
+// diffLinePrefixAndStyles returns the gutter prefix, prefix style and content style for a line
+func diffLinePrefixAndStyles(diffLine DiffLine) (string, lipgloss.Style, lipgloss.Style) {
+	switch {
+	case diffLine.Type == LineAdded && diffLine.MoveID != 0:
+		style := movedLineStyle(diffLine, diffMovedAddedStyle, diffMovedAddedAltStyle)
+		return "+", style, style
+	case diffLine.Type == LineRemoved && diffLine.MoveID != 0:
+		style := movedLineStyle(diffLine, diffMovedRemovedStyle, diffMovedRemovedAltStyle)
+		return "-", style, style
+	case diffLine.Type == LineAdded:
+		return "+", diffAddedPrefixStyle, diffAddedStyle
+	case diffLine.Type == LineRemoved:
+		return "-", diffRemovedPrefixStyle, diffRemovedStyle
+	default:
+		return " ", diffContextStyle, diffContextStyle
+	}
+}
+
+// movedLineStyle alternates between two styles for odd and even moved blocks
+func movedLineStyle(diffLine DiffLine, odd, even lipgloss.Style) lipgloss.Style {
+	if diffLine.MoveID%2 == 1 {
+		return odd
+	}
+	return even
+}
+
+// renderDiffLineContent applies syntax highlighting, intra-line emphasis for the
+// given spans, and the line type style to the content. Moved lines are shown
+// in their move color alone, like git's --color-moved.
+func (m Model) renderDiffLineContent(diffLine DiffLine, filePath string, spans []textSpan) string {
+	_, _, contentStyle := diffLinePrefixAndStyles(diffLine)
 
-	// Apply syntax highlighting to content
 	content := diffLine.Content
-	if m.highlighter != nil {
-		content = m.highlighter.Highlight(diffLine.Content, filePath)
+	switch {
+	case diffLine.MoveID != 0:
+	case m.highlighter != nil:
+		content = m.highlighter.HighlightWithEmphasis(diffLine.Content, filePath, spans, diffEmphasisStyle(diffLine.Type))
+	default:
+		content = emphasizeSpans(diffLine.Content, spans, diffEmphasisStyle(diffLine.Type))
 	}
+	return contentStyle.Render(content)
+}
 
-	return lineNums + prefixStyle.Render(prefix) + " " + contentStyle.Render(content)
+// diffEmphasisStyle returns the style for changed spans within a line of the given type
+func diffEmphasisStyle(lineType LineType) lipgloss.Style {
+	if lineType == LineRemoved {
+		return diffRemovedEmphasisStyle
+	}
+	return diffAddedEmphasisStyle
 }
 
 // renderDiffLineNumbers renders the old and new line numbers for a diff line
-func renderDiffLineNumbers(diffLine DiffLine) string {
+func renderDiffLineNumbers(diffLine DiffLine, numStyle lipgloss.Style) string {
 	oldNum := formatLineNumber(diffLine.OldLineNum)
 	newNum := formatLineNumber(diffLine.NewLineNum)
 
-	return diffLineNumStyle.Render(oldNum+" "+newNum) + " "
+	return numStyle.Render(oldNum+" "+newNum) + " "
 }
 
 // formatLineNumber formats a line number for display
@@ -301,26 +527,57 @@ func (m Model) renderFooter() string {
 	help := m.footerHelpItems()
 	help = m.appendFooterScroll(help)
 	help = m.appendFooterError(help)
+	help = m.appendFooterNotice(help)
 	return footerBaseStyle.Render(strings.Join(help, " • "))
 }
 
 func (m Model) footerHelpItems() []string {
 	help := m.contextualFooterHelp()
-	if m.diffViewMode == DiffOnly {
+	if m.diffViewMode.showsHunks() && m.selection == nil {
 		help = append(help,
 			footerKeyStyle.Render("[o/O]")+" Expand/Reset",
 			footerKeyStyle.Render("[Tab]")+" Switch Panel",
 		)
 	}
+	if m.diffMode != InputDiff {
+		help = append(help, footerKeyStyle.Render("[s]")+" Mode")
+	}
+	if m.diffMode == BranchCompare {
+		help = append(help, footerKeyStyle.Render("[b]")+" Base Branch")
+	}
 	return append(help,
-		footerKeyStyle.Render("[s]")+" Mode",
 		footerKeyStyle.Render("[f]")+" Diff/Whole File",
+		footerKeyStyle.Render("[|]")+" Split",
 		footerKeyStyle.Render("[?]")+" Help",
 		footerKeyStyle.Render("[q]")+" Quit",
 	)
 }
 
 func (m Model) contextualFooterHelp() []string {
+	if m.exportMode {
+		return []string{
+			footerKeyStyle.Render("[type]") + " Patch file",
+			footerKeyStyle.Render("[Enter]") + " Write",
+			footerKeyStyle.Render("[Esc]") + " Cancel",
+		}
+	}
+
+	if m.logRefMode {
+		return []string{
+			footerKeyStyle.Render("[type]") + " Ref",
+			footerKeyStyle.Render("[Enter]") + " Show History",
+			footerKeyStyle.Render("[Esc]") + " Cancel",
+		}
+	}
+
+	if m.pathspecMode {
+		return []string{
+			footerKeyStyle.Render("[type]") + " Pathspec",
+			footerKeyStyle.Render("[Enter]") + " Apply",
+			footerKeyStyle.Render("[Esc]") + " Cancel",
+		}
+	}
+
 	if m.searchMode {
 		return []string{
 			footerKeyStyle.Render("[type]") + " Filter files",
@@ -329,25 +586,82 @@ func (m Model) contextualFooterHelp() []string {
 		}
 	}
 
+	if m.selection != nil {
+		return m.visualFooterHelp()
+	}
+
+	if m.panel == FileTreePanel && m.diffMode == CommitLog {
+		help := []string{
+			footerKeyStyle.Render("[↑↓/j/k]") + " Navigate",
+			footerKeyStyle.Render("[PgUp/PgDn]") + " Page",
+			footerKeyStyle.Render("[Enter]") + " Show Commit",
+			footerKeyStyle.Render("[L]") + " Log Ref",
+		}
+		if m.logPath != "" {
+			return append(help, footerKeyStyle.Render("[Esc]")+" Back")
+		}
+		return append(help, footerKeyStyle.Render("[P]")+" Paths")
+	}
+
 	if m.panel == FileTreePanel {
-		return []string{
+		help := []string{
 			footerKeyStyle.Render("[↑↓/j/k]") + " Navigate",
 			footerKeyStyle.Render("[PgUp/PgDn]") + " Page",
 			footerKeyStyle.Render("[Enter]") + " Select/Expand",
 			footerKeyStyle.Render("[/]") + " Search",
+			footerKeyStyle.Render("[P]") + " Paths",
+		}
+		if m.diffMode != InputDiff {
+			help = append(help, footerKeyStyle.Render("[H]")+" History")
 		}
+		if m.diffMode == Unstaged {
+			help = append(help, footerKeyStyle.Render("[d]")+" Discard File")
+		}
+		return help
 	}
 
 	diffNavigationLabel := "Scroll"
-	if m.diffViewMode == DiffOnly {
+	if m.diffViewMode.showsHunks() {
 		diffNavigationLabel = "Hunk Jump"
 	}
-	return []string{
+	help := []string{
 		footerKeyStyle.Render("[↑↓]") + " Scroll",
 		footerKeyStyle.Render("[PgUp/PgDn]") + " Page",
 		footerKeyStyle.Render("[j/k]") + " " + diffNavigationLabel,
 		footerKeyStyle.Render("[gg/G]") + " Top/Bottom",
 	}
+	return m.appendFooterStaging(help)
+}
+
+func (m Model) appendFooterStaging(help []string) []string {
+	if !m.diffViewMode.showsHunks() {
+		return help
+	}
+	switch m.diffMode {
+	case Unstaged:
+		return append(help,
+			footerKeyStyle.Render("[a]")+" Stage Hunk",
+			footerKeyStyle.Render("[v/V]")+" Select Lines",
+			footerKeyStyle.Render("[d]")+" Discard Hunk",
+		)
+	case Staged:
+		return append(help, footerKeyStyle.Render("[u]")+" Unstage Hunk", footerKeyStyle.Render("[v/V]")+" Select Lines")
+	default:
+		return help
+	}
+}
+
+func (m Model) visualFooterHelp() []string {
+	action := footerKeyStyle.Render("[a]") + " Stage Lines"
+	if m.diffMode == Staged {
+		action = footerKeyStyle.Render("[u]") + " Unstage Lines"
+	}
+	return []string{
+		footerKeyStyle.Render("[↑↓/j/k]") + " Extend",
+		footerKeyStyle.Render("[o]") + " Other End",
+		action,
+		footerKeyStyle.Render("[Esc]") + " Cancel",
+	}
 }
 
 func (m Model) appendFooterScroll(help []string) []string {
@@ -370,6 +684,13 @@ func (m Model) appendFooterError(help []string) []string {
 	return append(help, errorStyle.Render("Error: "+m.err.Error()))
 }
 
+func (m Model) appendFooterNotice(help []string) []string {
+	if m.notice == "" || m.err != nil {
+		return help
+	}
+	return append(help, addedStyle.Render(m.notice))
+}
+
 func computeScrollPercent(scrollPos, totalLines, visibleHeight int) int {
 	maxScroll := max(0, totalLines-visibleHeight)
 	if maxScroll == 0 {
@@ -385,16 +706,35 @@ func (m Model) diffModeLabel() string {
 		return "Staged"
 	case BranchCompare:
 		return "Branch Compare"
+	case RefCompare:
+		if m.revisions != nil {
+			return "Compare " + m.revisions.String()
+		}
+		return "Compare"
+	case InputDiff:
+		if m.input != nil {
+			return m.input.label
+		}
+		return "Input"
+	case CommitLog:
+		if m.logPath != "" {
+			return "History " + m.logPath
+		}
+		return "Log " + defaultRevision(m.logRef)
 	default:
 		return "Unstaged"
 	}
 }
 
 func (m Model) diffViewModeLabel() string {
-	if m.diffViewMode == WholeFile {
+	switch m.diffViewMode {
+	case WholeFile:
 		return "Whole File"
+	case SideBySide:
+		return "Side by Side"
+	default:
+		return "Diff Only"
 	}
-	return "Diff Only"
 }
 
 func formatAggregateStats(fileCount, linesAdded, linesRemoved int) string {
@@ -426,26 +766,27 @@ func (m Model) renderHelpModal() string {
 	return m.renderHelp()
 }
 
-// renderCommits renders the list of commits for branch comparison
+// renderCommits renders the commit log, one commit per row
 func (m Model) renderCommits(width, height int) string {
 	selectedStyle := fileTreeSelectedLineStyle.Width(max(0, width-2))
 	internalHeight := panelContentHeight(height)
 
-	// If no commits ahead, show changes summary
 	if len(m.commits) == 0 {
 		return m.renderPanel(m.renderEmptyCommitList(), width, height, m.panel == FileTreePanel)
 	}
 
 	// Get visible commits.
 	start, end := visibleRange(m.scrollOffset, internalHeight, len(m.commits))
-	visibleCommits := m.commits[start:end]
 
 	// Render each commit
 	var lines []string
-	for i, commit := range visibleCommits {
-		globalIndex := start + i
-		isSelected := globalIndex == m.selectedIndex
-		lines = append(lines, renderCommitLines(commit, isSelected, m.panel == FileTreePanel, selectedStyle)...)
+	for i := start; i < end; i++ {
+		commit := m.commits[i]
+		isShown := m.selectedCommit != nil && m.selectedCommit.Hash == commit.Hash
+		lines = append(lines, renderCommitLine(commit, max(0, width-2), i == m.selectedIndex, isShown, m.panel == FileTreePanel, selectedStyle))
+	}
+	if end == len(m.commits) && m.logHasMore && len(lines) < internalHeight {
+		lines = append(lines, panelInfoStyle.Render("  more commits below…"))
 	}
 
 	// Build content as a single string
@@ -456,24 +797,44 @@ func (m Model) renderCommits(width, height int) string {
 }
 
 func (m Model) renderEmptyCommitList() string {
-	lines := []string{panelInfoStyle.Render("No commits ahead of main")}
-	if len(m.diffFiles) > 0 {
-		lines = append(lines, "", panelInfoStyle.Render(fmt.Sprintf("Changes: %d files", len(m.diffFiles))))
+	switch {
+	case m.commits == nil && m.err == nil:
+		return panelInfoStyle.Render("Loading commits…")
+	case m.logPath != "":
+		return panelInfoStyle.Render("No commits change " + m.logPath)
+	default:
+		return panelInfoStyle.Render("No commits")
 	}
-	return strings.Join(lines, "\n")
 }
 
-func renderCommitLines(commit Commit, isSelected, isTreePanelActive bool, selectedStyle lipgloss.Style) []string {
+// renderCommitLine renders a commit as its short hash and subject, cut to
+// width columns. The commit whose diff is shown is marked with a dot.
+func renderCommitLine(commit Commit, width int, isSelected, isShown, isTreePanelActive bool, selectedStyle lipgloss.Style) string {
+	marker := "  "
+	if isShown {
+		marker = "● "
+	}
 	if isSelected && isTreePanelActive {
-		header := selectedStyle.Render(commit.ShortHash + " " + commit.Author + " " + commit.Date)
-		return []string{header, "    " + commitMessageStyle.Render(commit.Message)}
+		return selectedStyle.Render(truncateToWidth(marker+commit.ShortHash+" "+commit.Message, width))
 	}
 
-	line := commitHashStyle.Render(commit.ShortHash) + " " +
-		commitAuthorStyle.Render(commit.Author) + " " +
-		commitDateStyle.Render(commit.Date) + "\n" +
-		"    " + commitMessageStyle.Render(commit.Message)
-	return []string{line}
+	message := truncateToWidth(commit.Message, width-2-len(commit.ShortHash)-1) // after the marker and hash
+	return marker + commitHashStyle.Render(commit.ShortHash) + " " + commitMessageStyle.Render(message)
+}
+
+// commitHeaderLines describes the commit shown in the commit log the way git
+// log does: hash, author, date and the full message, then a blank line
+func commitHeaderLines(commit Commit) []string {
+	lines := []string{
+		diffCommitHeaderStyle.Render("commit " + commit.Hash),
+		commitAuthorStyle.Render(fmt.Sprintf("Author: %s <%s>", commit.Author, commit.Email)),
+		commitDateStyle.Render("Date:   " + commit.Date),
+		"",
+	}
+	for _, line := range splitLines(commit.FullMessage) {
+		lines = append(lines, commitMessageStyle.Render("    "+line))
+	}
+	return append(lines, "")
 }
 
 func (m Model) renderPanel(content string, width, height int, active bool) string {
@@ -151,6 +151,7 @@ func TestGetChangeTypeSymbol(t *testing.T) {
 		{"Added", Added, "A"},
 		{"Deleted", Deleted, "D"},
 		{"Renamed", Renamed, "R"},
+		{"Copied", Copied, "C"},
 	}
 
 	for _, tt := range tests {
@@ -703,3 +704,74 @@ func TestVisibleRange(t *testing.T) {
 		})
 	}
 }
+
+func TestPairHunkRows(t *testing.T) {
+	lines := []DiffLine{
+		{Type: LineContext, Content: "ctx"},
+		{Type: LineRemoved, Content: "old1"},
+		{Type: LineRemoved, Content: "old2"},
+		{Type: LineAdded, Content: "new1"},
+		{Type: LineContext, Content: "ctx2"},
+		{Type: LineAdded, Content: "new2"},
+	}
+
+	got := pairHunkRows(lines)
+	want := []splitRow{
+		{oldIdx: 0, newIdx: 0},
+		{oldIdx: 1, newIdx: 3},
+		{oldIdx: 2, newIdx: -1},
+		{oldIdx: 4, newIdx: 4},
+		{oldIdx: -1, newIdx: 5},
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("pairHunkRows() returned %d rows, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("pairHunkRows()[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestViewSideBySideRendersColumns(t *testing.T) {
+	model := setupModelForView(t)
+	model.width = 120
+	model.height = 24
+	model.diffViewMode = SideBySide
+	model.panel = DiffPanel
+
+	model.files = []FileDiff{{Path: "test.txt", ChangeType: Modified}}
+	model.diffFiles = []FileDiff{
+		{
+			Path: "test.txt",
+			Hunks: []Hunk{
+				{
+					OldStart: 1,
+					OldCount: 2,
+					NewStart: 1,
+					NewCount: 2,
+					Lines: []DiffLine{
+						{Type: LineContext, Content: "same", OldLineNum: 1, NewLineNum: 1},
+						{Type: LineRemoved, Content: "before", OldLineNum: 2},
+						{Type: LineAdded, Content: "after", NewLineNum: 2},
+					},
+				},
+			},
+		},
+	}
+	model.buildFileTree()
+
+	lines := model.buildDiffPanelLines()
+	if got, want := len(lines), model.getDiffLineCount(); got != want {
+		t.Fatalf("rendered %d lines, layout expects %d", got, want)
+	}
+
+	changedRow := stripAnsi(lines[len(lines)-1])
+	if !strings.Contains(changedRow, "before") || !strings.Contains(changedRow, "after") {
+		t.Fatalf("expected paired row to contain both sides, got %q", changedRow)
+	}
+	if strings.Index(changedRow, "before") > strings.Index(changedRow, "after") {
+		t.Errorf("expected old content left of new content, got %q", changedRow)
+	}
+}
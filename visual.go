@@ -0,0 +1,170 @@
+package main
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// lineSelection is a visual line selection inside one hunk of the diff panel.
+// anchor stays where the selection started and cursor moves with j/k; both are
+// indices into Hunk.Lines.
+type lineSelection struct {
+	path    string
+	hunkIdx int
+	anchor  int
+	cursor  int
+}
+
+func (s lineSelection) lines() lineRange {
+	return lineRange{first: min(s.anchor, s.cursor), last: max(s.anchor, s.cursor)}
+}
+
+// selectedLinesFor returns the selected line range of a rendered hunk, if any
+func (m Model) selectedLinesFor(path string, hunkIdx int) *lineRange {
+	if m.selection == nil || m.selection.path != path || m.selection.hunkIdx != hunkIdx {
+		return nil
+	}
+	lines := m.selection.lines()
+	return &lines
+}
+
+// canSelectLines reports whether line-level staging is available in the current state
+func (m Model) canSelectLines() bool {
+	return m.panel == DiffPanel && m.diffViewMode.showsHunks() &&
+		(m.diffMode == Unstaged || m.diffMode == Staged)
+}
+
+// enterVisualMode starts a selection in the hunk under the cursor. With wholeHunk
+// the selection spans every changed line, otherwise it starts on the first one.
+func (m *Model) enterVisualMode(wholeHunk bool) {
+	if !m.canSelectLines() || m.hunkEditsBlocked() {
+		return
+	}
+	loc, ok := m.currentHunk()
+	if !ok {
+		return
+	}
+
+	first, last := changedLineBounds(loc.hunk().Lines)
+	selection := lineSelection{path: loc.file.Path, hunkIdx: loc.hunkIdx, anchor: first, cursor: first}
+	if wholeHunk {
+		selection.cursor = last
+	}
+	m.selection = &selection
+	m.scrollToSelectionCursor()
+}
+
+// changedLineBounds returns the indices of the first and last added/removed lines
+func changedLineBounds(lines []DiffLine) (int, int) {
+	first, last := 0, len(lines)-1
+	for first < last && lines[first].Type == LineContext {
+		first++
+	}
+	for last > first && lines[last].Type == LineContext {
+		last--
+	}
+	return first, last
+}
+
+func (m *Model) exitVisualMode() {
+	m.selection = nil
+}
+
+// handleVisualKey handles keys while a selection is active. Keys that would
+// change the loaded diff are swallowed so the selection stays valid.
+func (m *Model) handleVisualKey(key string) (tea.Cmd, bool) {
+	switch key {
+	case "q", "ctrl+c", "?":
+		return nil, false
+	case "up", "k":
+		m.moveSelectionCursor(-1)
+	case "down", "j":
+		m.moveSelectionCursor(1)
+	case "o":
+		m.selection.anchor, m.selection.cursor = m.selection.cursor, m.selection.anchor
+		m.scrollToSelectionCursor()
+	case "esc", "v", "V":
+		m.exitVisualMode()
+	case "a":
+		return m.applySelection(Unstaged, m.StageHunkLines), true
+	case "u":
+		return m.applySelection(Staged, m.UnstageHunkLines), true
+	}
+	return nil, true
+}
+
+func (m *Model) moveSelectionCursor(delta int) {
+	hunk, ok := m.selectedHunk()
+	if !ok {
+		m.exitVisualMode()
+		return
+	}
+	m.selection.cursor = clamp(m.selection.cursor+delta, 0, len(hunk.Lines)-1)
+	m.scrollToSelectionCursor()
+}
+
+// applySelection runs action on the selected lines and ends visual mode. It is a
+// no-op when the key does not apply to the current diff mode.
+func (m *Model) applySelection(mode DiffMode, action func(path string, hunk Hunk, lines lineRange) tea.Cmd) tea.Cmd {
+	if m.diffMode != mode {
+		return nil
+	}
+	hunk, ok := m.selectedHunk()
+	if !ok {
+		m.exitVisualMode()
+		return nil
+	}
+	selection := *m.selection
+	m.exitVisualMode()
+	return action(selection.path, hunk, selection.lines())
+}
+
+// selectedHunk returns the hunk the selection refers to, if it is still loaded
+func (m Model) selectedHunk() (Hunk, bool) {
+	if m.selection == nil {
+		return Hunk{}, false
+	}
+	file, found := findFileDiffByPath(m.diffFiles, m.selection.path)
+	if !found || m.selection.hunkIdx >= len(file.Hunks) {
+		return Hunk{}, false
+	}
+	return file.Hunks[m.selection.hunkIdx], true
+}
+
+// scrollToSelectionCursor scrolls the diff panel so the cursor row is visible
+func (m *Model) scrollToSelectionCursor() {
+	row, ok := m.selectionCursorRow()
+	if !ok {
+		return
+	}
+	visibleHeight := m.visibleContentRows()
+	if row < m.diffScroll {
+		m.diffScroll = row
+	} else if row >= m.diffScroll+visibleHeight {
+		m.diffScroll = row - visibleHeight + 1
+	}
+}
+
+// selectionCursorRow returns the panel row showing the selection cursor
+func (m Model) selectionCursorRow() (int, bool) {
+	layout := m.computeDiffLayout(m.getSelectedDiffFiles())
+	for i, loc := range layout.hunks {
+		if loc.file.Path != m.selection.path || loc.hunkIdx != m.selection.hunkIdx {
+			continue
+		}
+		return layout.hunkStarts[i] + 1 + m.hunkRowOfLine(loc.hunk(), m.selection.cursor), true
+	}
+	return 0, false
+}
+
+// hunkRowOfLine maps a line index to its row within the rendered hunk
+func (m Model) hunkRowOfLine(hunk Hunk, idx int) int {
+	if m.diffViewMode != SideBySide {
+		return idx
+	}
+	for row, pair := range pairHunkRows(hunk.Lines) {
+		if pair.oldIdx == idx || pair.newIdx == idx {
+			return row
+		}
+	}
+	return 0
+}